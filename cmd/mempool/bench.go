@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// Admission modes measured by bench.
+const (
+	benchModeBatch = "batch" // AddTxs in batches, as run ingests files
+	benchModeAsync = "async" // AddTx through the processors
+)
+
+// runBench implements "mempool bench": it measures how fast a fresh pool admits the transactions of
+// a file.
+func runBench(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("bench", "Measure how fast a fresh pool admits the transactions of a file. The file is read into\nmemory first, so only admission is timed.")
+	var in inputFlags
+	in.register(fs)
+	maxSize := fs.Uint("max-size", 0, "pool capacity, 0 for room for every transaction")
+	fs.fromEnv("max-size", constants.ENV_MAX_MEMPOOL_SIZE)
+	shards := fs.Int("shards", 0, "number of shards, 0 for a single-lock pool")
+	fs.fromEnv("shards", constants.ENV_MEMPOOL_SHARDS)
+	mode := fs.String("mode", benchModeBatch, "admission path: batch (AddTxs) or async (AddTx through processors)")
+	batchSize := fs.Int("batch", ingestBatchSize, "transactions per AddTxs call in batch mode")
+	processors := fs.Uint("processors", uint(numProcessors()), "processors started in async mode")
+	rounds := fs.Int("rounds", 3, "number of timed rounds, each on a fresh pool")
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	switch {
+	case *mode != benchModeBatch && *mode != benchModeAsync:
+		return usageError{errors.Errorf("unknown -mode %q", *mode)}
+	case *batchSize < 1:
+		return usageError{errors.Errorf("-batch %d must be positive", *batchSize)}
+	case *processors < 1 || *processors > 255:
+		return usageError{errors.Errorf("-processors %d must be between 1 and 255", *processors)}
+	case *rounds < 1:
+		return usageError{errors.Errorf("-rounds %d must be positive", *rounds)}
+	case *shards < 0:
+		return usageError{errors.Errorf("-shards %d is negative", *shards)}
+	case *maxSize > 1<<32-1:
+		return usageError{errors.Errorf("-max-size %d is too large", *maxSize)}
+	}
+	if err := in.required(); err != nil {
+		return err
+	}
+	txs, err := in.readTxs(logger)
+	if err != nil {
+		return err
+	}
+	if len(txs) == 0 {
+		return errors.Errorf("%s holds no valid transactions", in.path)
+	}
+	capacity := uint32(*maxSize)
+	if capacity == 0 {
+		capacity = uint32(len(txs))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "round\tduration\ttx/s\tpooled\t\n")
+	var best, total float64
+	for round := 1; round <= *rounds; round++ {
+		var mempool types.Mempool
+		if *shards > 0 {
+			mempool, err = types.NewShardedMempool(capacity, *shards, logger)
+		} else {
+			mempool, err = types.NewMempool(capacity, logger)
+		}
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		if *mode == benchModeAsync {
+			waitGroup := &sync.WaitGroup{}
+			mempool.StartProcessors(waitGroup, uint8(*processors))
+			for _, tx := range txs {
+				mempool.AddTx(tx, waitGroup)
+			}
+			waitGroup.Wait()
+		} else {
+			for start := 0; start < len(txs); start += *batchSize {
+				mempool.AddTxs(txs[start:min(start+*batchSize, len(txs))])
+			}
+		}
+		elapsed := time.Since(start)
+		mempool.CloseTxInsertChan()
+		rate := float64(len(txs)) / elapsed.Seconds()
+		best, total = max(best, rate), total+rate
+		fmt.Fprintf(w, "%d\t%v\t%.0f\t%d\t\n", round, elapsed.Round(time.Microsecond), rate, mempool.MempoolLen())
+	}
+	fmt.Fprintf(w, "\nbest\t\t%.0f\t\t\nmean\t\t%.0f\t\t\n", best, total/float64(*rounds))
+	return errors.Wrap(w.Flush(), "failed to write results")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/diff"
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+const exportSniffLen = 64 // Bytes of an export used to detect its format
+
+// runDiff implements "mempool diff": it compares two exports and reports the transactions that
+// entered, left or moved within the exported set.
+func runDiff(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("diff", "Compare two exports, e.g. before and after a change of priority rules or capacity, and\nlist the transactions added, removed or moved, with fee deltas and summary statistics.\nRanks are positions in the export, 1 being the highest priority.")
+	fs.expect("OLD", "NEW")
+	format := fs.String("export-format", "", "format of both exports: text, jsonl, csv or binary (default detected from each file)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a report")
+	maxLines := fs.Int("max-lines", 20, "transactions listed per section, 0 lists every one")
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	if *format != "" {
+		if _, err := types.LookupExportFormat(*format); err != nil {
+			return usageError{err}
+		}
+	}
+
+	oldPath, newPath := fs.Arg(0), fs.Arg(1)
+	oldTxs, err := readExport(oldPath, *format)
+	if err != nil {
+		return err
+	}
+	newTxs, err := readExport(newPath, *format)
+	if err != nil {
+		return err
+	}
+	result, err := diff.Compare(oldTxs, newTxs)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return errors.Wrap(enc.Encode(result), "failed to write diff")
+	}
+	return errors.Wrap(printDiff(os.Stdout, oldPath, newPath, result, *maxLines), "failed to write diff")
+}
+
+// readExport reads the export at path, decompressing it if needed. An empty format is detected from
+// the content.
+func readExport(path, format string) ([]*types.Tx, error) {
+	input, err := ingest.OpenInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	br := bufio.NewReader(input)
+	if format == "" {
+		prefix, _ := br.Peek(exportSniffLen) // A short read just means a short export
+		format = types.DetectExportFormat(prefix)
+	}
+	exportFormat, err := types.LookupExportFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := diff.Read(br, exportFormat)
+	return txs, errors.Wrapf(err, "failed to read %s export %s", exportFormat.Name(), path)
+}
+
+func printDiff(out io.Writer, oldPath, newPath string, result diff.Result, maxLines int) error {
+	s := result.Summary
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "\ttransactions\ttotal fee\tlowest fee\t\n")
+	fmt.Fprintf(w, "old %s\t%d\t%s\t%s\t\n", oldPath, s.Old, formatFloat(s.OldTotalFee), formatFloat(s.OldMinFee))
+	fmt.Fprintf(w, "new %s\t%d\t%s\t%s\t\n", newPath, s.New, formatFloat(s.NewTotalFee), formatFloat(s.NewMinFee))
+	fmt.Fprintf(w, "change\t%+d\t%+.6g\t%+.6g\t\n\n", s.New-s.Old, s.NewTotalFee-s.OldTotalFee, s.NewMinFee-s.OldMinFee)
+	fmt.Fprintf(w, "%d added, %d removed, %d moved, %d with a different fee; ranks of common transactions shifted by %.2f on average, %d at most\n",
+		s.Added, s.Removed, s.Moved, s.FeeChanged, s.MeanRankShift, s.MaxRankShift)
+
+	if len(result.Added) > 0 {
+		fmt.Fprint(w, "\nadded\trank\ttotal fee\t\n")
+		printEntries(w, result.Added, maxLines, func(e diff.Entry) string {
+			return fmt.Sprintf("+ %s\t%d\t%s\t", e.TxHash, e.NewRank, formatFloat(e.NewTotalFee))
+		})
+	}
+	if len(result.Removed) > 0 {
+		fmt.Fprint(w, "\nremoved\trank\ttotal fee\t\n")
+		printEntries(w, result.Removed, maxLines, func(e diff.Entry) string {
+			return fmt.Sprintf("- %s\t%d\t%s\t", e.TxHash, e.OldRank, formatFloat(e.OldTotalFee))
+		})
+	}
+	if len(result.Changed) > 0 {
+		fmt.Fprint(w, "\nchanged\trank\tmoved\ttotal fee\tfee delta\t\n")
+		printEntries(w, result.Changed, maxLines, func(e diff.Entry) string {
+			return fmt.Sprintf("~ %s\t%d -> %d\t%+d\t%s -> %s\t%+.6g\t", e.TxHash, e.OldRank, e.NewRank, e.RankDelta(),
+				formatFloat(e.OldTotalFee), formatFloat(e.NewTotalFee), e.FeeDelta())
+		})
+	}
+	return w.Flush()
+}
+
+func printEntries(w io.Writer, entries []diff.Entry, maxLines int, format func(diff.Entry) string) {
+	for i, e := range entries {
+		if maxLines > 0 && i == maxLines {
+			fmt.Fprintf(w, "... and %d more\n", len(entries)-i)
+			return
+		}
+		fmt.Fprintln(w, format(e))
+	}
+}
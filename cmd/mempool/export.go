@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// runExport implements "mempool export": it loads a transactions file or a snapshot into a pool and
+// exports the pool in priority order.
+func runExport(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("export", "Load a transactions file or a snapshot into a pool and export it in priority order,\nwithout starting processors, a WAL or the admin server.")
+	var source sourceFlags
+	source.register(fs)
+	output := fs.String("output", "", outputUsage)
+	fs.fromEnv("output", constants.PRIORITIZED_TX_FILE_PATH)
+	exportFormat := fs.String("export-format", "", exportFormatUsage)
+	fs.fromEnv("export-format", constants.ENV_EXPORT_FORMAT)
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	if *exportFormat != "" {
+		if _, err := types.LookupExportFormat(*exportFormat); err != nil {
+			return usageError{err}
+		}
+	}
+
+	mempool, err := source.load(logger)
+	if err != nil {
+		return err
+	}
+	result, err := types.ExportToPath(mempool, *output, *exportFormat, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to export prioritized transactions")
+	}
+	logger.Info("export complete", zap.String("path", result.Path), zap.Int("records", result.Records), zap.String("sha256", result.Checksum))
+	return nil
+}
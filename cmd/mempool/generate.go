@@ -0,0 +1,95 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/generator"
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// runGenerate implements "mempool generate": it writes a reproducible synthetic transactions file for
+// load testing.
+func runGenerate(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("generate", "Write a reproducible synthetic transactions file for load testing. The same -seed and\nflags always produce the same file.")
+	cfg := generator.DefaultConfig(10000)
+	fs.IntVar(&cfg.Count, "count", cfg.Count, "records to write, including duplicates and malformed records")
+	fs.Int64Var(&cfg.Seed, "seed", cfg.Seed, "random seed")
+	output := fs.String("output", ingest.StdinPath, "destination, - for standard output; .gz compresses")
+	format := fs.String("format", "", "output format: kv, jsonl or csv (default guessed from the -output extension)")
+	fs.StringVar(&cfg.FeeDistribution, "fee-distribution", cfg.FeeDistribution, "FeePerGas distribution: uniform, lognormal or powerlaw")
+	fs.Float64Var(&cfg.FeeMin, "fee-min", cfg.FeeMin, "lower bound of uniform fees, scale of power-law fees")
+	fs.Float64Var(&cfg.FeeMax, "fee-max", cfg.FeeMax, "upper bound of uniform fees")
+	fs.Float64Var(&cfg.FeeMu, "fee-mu", cfg.FeeMu, "mean of ln(FeePerGas) for log-normal fees")
+	fs.Float64Var(&cfg.FeeSigma, "fee-sigma", cfg.FeeSigma, "standard deviation of ln(FeePerGas) for log-normal fees")
+	fs.Float64Var(&cfg.FeeAlpha, "fee-alpha", cfg.FeeAlpha, "shape of power-law fees; smaller is heavier tailed")
+	fs.Float64Var(&cfg.GasMin, "gas-min", cfg.GasMin, "smallest Gas")
+	fs.Float64Var(&cfg.GasMax, "gas-max", cfg.GasMax, "largest Gas")
+	fs.Float64Var(&cfg.DuplicateRatio, "duplicate-ratio", cfg.DuplicateRatio, "fraction of records repeating a recent transaction")
+	fs.Float64Var(&cfg.MalformedRatio, "malformed-ratio", cfg.MalformedRatio, "fraction of records that fail to parse")
+	fs.IntVar(&cfg.Senders, "senders", cfg.Senders, "distinct senders, 0 leaves Sender out")
+	fs.StringVar(&cfg.SenderPattern, "sender-pattern", cfg.SenderPattern, "how senders are picked: uniform, zipf or round-robin")
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return usageError{err}
+	}
+	if *format == "" {
+		*format = ingest.FormatFromPath(*output)
+	}
+	switch *format {
+	case ingest.FormatKeyValue, ingest.FormatJSONL, ingest.FormatCSV:
+	default:
+		return usageError{errors.Errorf("unknown -format %q", *format)}
+	}
+
+	w, closeOutput, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	summary, err := generator.Write(w, *format, cfg)
+	if closeErr := closeOutput(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("generated transactions", zap.String("path", *output), zap.String("format", *format),
+		zap.Int("records", summary.Records), zap.Int("unique", summary.Unique),
+		zap.Int("duplicate", summary.Duplicate), zap.Int("malformed", summary.Malformed))
+	return nil
+}
+
+// createOutput opens path for writing, or standard output when path is "-", gzip compressing when
+// the name ends in .gz. Other compression extensions are refused, as exports refuse them.
+func createOutput(path string) (io.Writer, func() error, error) {
+	if path == ingest.StdinPath {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	if err := types.CheckCompression(path); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create %s", path)
+	}
+	if ext := filepath.Ext(path); ext != ".gz" && ext != ".gzip" {
+		return file, func() error { return errors.Wrapf(file.Close(), "failed to close %s", path) }, nil
+	}
+	gz := gzip.NewWriter(file)
+	return gz, func() error {
+		err := gz.Close()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return errors.Wrapf(err, "failed to close %s", path)
+	}, nil
+}
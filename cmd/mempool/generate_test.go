@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/types"
+)
+
+func TestCreateOutput_Compression(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		wantErr error
+	}{
+		{name: "transactions.txt"},
+		{name: "transactions.jsonl.gz"},
+		{name: "transactions.jsonl.zst", wantErr: types.ErrUnsupportedCompression},
+		{name: "transactions.txt.BZ2", wantErr: types.ErrUnsupportedCompression},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.name)
+			_, closeOutput, err := createOutput(path)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.NoFileExists(t, path, "nothing is written under a misleading name")
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, closeOutput())
+			assert.FileExists(t, path)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// inputFlags select a transactions file and its format.
+type inputFlags struct {
+	path   string
+	format string
+}
+
+const (
+	inputUsage        = "transactions file, - for standard input; gzip and bzip2 input is decompressed"
+	formatUsage       = "input format: kv, jsonl or csv (default guessed from the file extension)"
+	outputUsage       = "export destination; .gz compresses and - writes to standard output (default ./prioritized-transactions.txt)"
+	exportFormatUsage = "export format: text, jsonl, csv or binary (default text)"
+)
+
+func (in *inputFlags) register(fs *flagSet) {
+	fs.StringVar(&in.path, "input", "", inputUsage)
+	fs.fromEnv("input", constants.ENV_TRANSACTIONS_FILE_PATH)
+	fs.StringVar(&in.format, "format", "", formatUsage)
+	fs.fromEnv("format", constants.ENV_INPUT_FORMAT)
+}
+
+func (in *inputFlags) required() error {
+	if in.path == "" {
+		return usageError{errors.Errorf("-input or %s is required", constants.ENV_TRANSACTIONS_FILE_PATH)}
+	}
+	return nil
+}
+
+// open opens the input and returns a reader over it and a function that closes both. Line oriented
+// formats are parsed in parallel chunks; CSV falls back to a sequential reader.
+func (in *inputFlags) open(logger logging.LoggingSystem) (ingest.Reader, func(), error) {
+	input, err := ingest.OpenInput(in.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Info("reading transactions", zap.String("path", in.path), zap.String("compression", input.Compression))
+	format := in.format
+	if format == "" {
+		format = ingest.FormatFromPath(in.path)
+	}
+	if parallelReader, err := ingest.NewParallelReader(input, format, logger, ingest.ParallelOptions{Workers: int(numProcessors())}); err == nil {
+		return parallelReader, func() { parallelReader.Close(); input.Close() }, nil
+	}
+	reader, err := ingest.NewReader(format, input, logger)
+	if err != nil {
+		input.Close()
+		return nil, nil, usageError{err}
+	}
+	return reader, func() { input.Close() }, nil
+}
+
+// readTxs reads every well-formed transaction of the input, skipping malformed records as the pool
+// does when it is run.
+func (in *inputFlags) readTxs(logger logging.LoggingSystem) ([]*types.Tx, error) {
+	reader, closeInput, err := in.open(logger)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+	var txs []*types.Tx
+	for {
+		tx, err := reader.Next()
+		if err == io.EOF {
+			return txs, nil
+		}
+		var lineErr *ingest.LineError
+		if errors.As(err, &lineErr) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", in.path)
+		}
+		txs = append(txs, tx)
+	}
+}
+
+// loadInput admits every transaction of the input into a new pool of maxSize, or large enough for
+// all of them when maxSize is 0.
+func loadInput(in *inputFlags, maxSize uint32, logger logging.LoggingSystem) (types.Mempool, error) {
+	txs, err := in.readTxs(logger)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize == 0 {
+		maxSize = uint32(max(len(txs), 1))
+	}
+	mempool, err := types.NewMempool(maxSize, logger)
+	if err != nil {
+		return nil, err
+	}
+	for start := 0; start < len(txs); start += ingestBatchSize {
+		mempool.AddTxs(txs[start:min(start+ingestBatchSize, len(txs))])
+	}
+	return mempool, nil
+}
+
+// loadSnapshot loads the snapshot at path into a new pool of maxSize, or of the capacity recorded in
+// the snapshot when maxSize is 0.
+func loadSnapshot(path string, maxSize uint32, logger logging.LoggingSystem) (types.Mempool, error) {
+	if maxSize == 0 {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open snapshot %s", path)
+		}
+		meta, _, err := types.ReadSnapshot(file)
+		file.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read snapshot %s", path)
+		}
+		maxSize = meta.MaxMemPoolSize
+	}
+	mempool, err := types.NewMempool(maxSize, logger)
+	if err != nil {
+		return nil, err
+	}
+	return mempool, mempool.LoadSnapshot(path)
+}
+
+// sourceFlags select a pool to inspect: a snapshot, or else a transactions file.
+type sourceFlags struct {
+	inputFlags
+	snapshotPath string
+	maxSize      uint
+}
+
+func (sf *sourceFlags) register(fs *flagSet) {
+	sf.inputFlags.register(fs)
+	fs.StringVar(&sf.snapshotPath, "snapshot", "", "snapshot file to load instead of -input")
+	fs.UintVar(&sf.maxSize, "max-size", 0, "pool capacity; 0 keeps every valid input transaction, or uses the snapshot's capacity")
+}
+
+// load builds the selected pool.
+func (sf *sourceFlags) load(logger logging.LoggingSystem) (types.Mempool, error) {
+	if sf.maxSize > 1<<32-1 {
+		return nil, usageError{errors.Errorf("-max-size %d is too large", sf.maxSize)}
+	}
+	if sf.snapshotPath != "" {
+		return loadSnapshot(sf.snapshotPath, uint32(sf.maxSize), logger)
+	}
+	if err := sf.required(); err != nil {
+		return nil, err
+	}
+	return loadInput(&sf.inputFlags, uint32(sf.maxSize), logger)
+}
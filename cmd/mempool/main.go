@@ -1,88 +1,224 @@
 package main
 
 import (
-	"bufio"
+	"flag"
+	"fmt"
+	"io"
 	"os"
-	"runtime"
-	"strconv"
+	"sort"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/joho/godotenv"
-	"go.uber.org/zap"
+	"github.com/pkg/errors"
 
+	"mempool/pkg/config"
 	"mempool/pkg/constants"
 	"mempool/pkg/logging"
-	"mempool/pkg/types"
 )
 
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1 // The command ran and failed
+	exitUsage   = 2 // The command line could not be parsed
+)
+
+// command is a mempool subcommand. run receives the arguments after the command name.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, logger logging.LoggingSystem) error
+}
+
+var commands = []command{
+	{name: "run", summary: "ingest a transactions file, then export the prioritized pool (the default)", run: runRun},
+	{name: "serve", summary: "keep a pool running behind the admin HTTP server until interrupted", run: runServe},
+	{name: "status", summary: "ask a running serve what happened to a transaction hash", run: runStatus},
+	{name: "stats", summary: "print fee and sender statistics for a transactions file or snapshot", run: runStats},
+	{name: "export", summary: "export a transactions file or snapshot in priority order", run: runExport},
+	{name: "validate", summary: "check that every record of a transactions file would be admitted", run: runValidate},
+	{name: "bench", summary: "measure admission throughput for a transactions file", run: runBench},
+	{name: "generate", summary: "write a synthetic transactions file for load testing", run: runGenerate},
+	{name: "diff", summary: "compare two exports: transactions added, removed and moved", run: runDiff},
+}
+
+// usageError marks errors in the command line, which exit with exitUsage.
+type usageError struct {
+	error
+}
+
 func main() {
 	godotenv.Load(".env")
-	maxMempoolSize := os.Getenv(constants.ENV_MAX_MEMPOOL_SIZE)
 	logger, err := logging.Logger()
 	if err != nil {
 		panic(err)
 	}
-	defer logger.Sync()
-	logger.Info("initializing mempool", zap.String("maxMempoolSize", maxMempoolSize))
-	if maxPoolSize, err := strconv.Atoi(maxMempoolSize); err != nil {
-		logger.Fatal("environment variable not set", zap.String("variable", constants.ENV_MAX_MEMPOOL_SIZE))
-	} else {
-		numOfCores := uint8(runtime.NumCPU())
-		mempool, err := types.NewMempool(uint32(maxPoolSize), logger)
-		if err != nil {
-			logger.Fatal("error initializing mempool", zap.Error(err))
+	code := dispatch(os.Args[1:], logger)
+	logger.Sync()
+	os.Exit(code)
+}
+
+// dispatch runs the command named by args[0] and returns the process exit code. Without a command,
+// or when args start with a flag, it runs "run" so existing invocations keep working.
+func dispatch(args []string, logger logging.LoggingSystem) int {
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	} else if len(args) > 0 && isHelpFlag(args[0]) {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	if name == "help" {
+		if len(args) == 0 {
+			printUsage(os.Stdout)
+			return exitOK
+		}
+		name, args = args[0], []string{"-help"}
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args, logger)
+		var usageErr usageError
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.As(err, &usageErr):
+			fmt.Fprintf(os.Stderr, "mempool %s: %v\n", name, err)
+			return exitUsage
+		default:
+			fmt.Fprintf(os.Stderr, "mempool %s: %v\n", name, err)
+			return exitFailure
+		}
+	}
+	fmt.Fprintf(os.Stderr, "mempool: unknown command %q\n\n", name)
+	printUsage(os.Stderr)
+	return exitUsage
+}
+
+func isHelpFlag(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, "Usage: mempool <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprint(w, "\nRun \"mempool <command> -help\" for the flags of a command. Flags override the environment\nvariables named in their help, which may also be set in a .env file.\n")
+}
+
+// flagSet is a flag.FlagSet whose flags may take their values from environment variables and, for
+// flags bound to a config.Config, from a configuration file. A flag given on the command line always
+// wins over its variable, which wins over the file.
+type flagSet struct {
+	*flag.FlagSet
+	env      map[string]string // Flag name -> environment variable
+	operands []string          // Names of the required arguments after the flags
+	cfg      *config.Config    // Loaded from the -config file before variables are applied, nil without -config
+}
+
+func newFlagSet(name, synopsis string) *flagSet {
+	fs := &flagSet{FlagSet: flag.NewFlagSet(name, flag.ContinueOnError), env: make(map[string]string)}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: mempool %s\n\n%s\n\nFlags:\n", strings.Join(append([]string{name, "[flags]"}, fs.operands...), " "), synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// fromEnv makes the flag called name default to the environment variable.
+func (fs *flagSet) fromEnv(name, variable string) {
+	fs.Lookup(name).Usage += fmt.Sprintf(" (env %s)", variable)
+	fs.env[name] = variable
+}
+
+// withConfig adds a -config flag whose file is read into cfg. Flags that store into cfg should be
+// registered with bind so their help names the file key.
+func (fs *flagSet) withConfig(cfg *config.Config) {
+	fs.cfg = cfg
+	fs.String("config", "", "YAML or JSON configuration file; its settings are overridden by environment variables and flags")
+	fs.fromEnv("config", constants.ENV_CONFIG_FILE)
+}
+
+// bind makes the flag called name default to the environment variable, and documents the
+// configuration file key that sets the same field.
+func (fs *flagSet) bind(name, variable, key string) {
+	fs.Lookup(name).Usage += fmt.Sprintf(" (env %s, config %s)", variable, key)
+	fs.env[name] = variable
+}
+
+// expect makes the command require one argument after the flags for each name.
+func (fs *flagSet) expect(names ...string) {
+	fs.operands = names
+}
+
+// parse parses args, then sets every flag not given on the command line from its environment
+// variable. Values from either source go through the same parser, so they are validated alike.
+func (fs *flagSet) parse(args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err}
+	}
+	switch {
+	case fs.NArg() > len(fs.operands):
+		return usageError{errors.Errorf("unexpected argument %q", fs.Arg(len(fs.operands)))}
+	case fs.NArg() < len(fs.operands):
+		return usageError{errors.Errorf("missing %s", strings.Join(fs.operands[fs.NArg():], " and "))}
+	}
+	given := make(map[string]string) // Flag name -> value from the command line
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = f.Value.String() })
+	if err := fs.loadConfig(given); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(fs.env))
+	for name := range fs.env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := os.Getenv(fs.env[name])
+		if _, ok := given[name]; ok || value == "" {
+			continue
 		}
-		waitGroup := &sync.WaitGroup{}
-		mempool.StartProcessors(waitGroup, numOfCores) // Start processors equal to the number of CPU cores for CPU-bound tasks
-		logger.Info("workers started", zap.Uint8("count", numOfCores))
-		// start timer to test performance
-		start := time.Now()
-		defer func() {
-			elapsed := time.Since(start)
-			logger.Info("Total time taken to process transactions", zap.Duration("duration", elapsed))
-		}()
-		// Process transactions.txt and insert into mempool
-		logger.Info("retrieving transactions and inserting into mempool")
-		if transactionFile, err := os.Open(os.Getenv(constants.ENV_TRANSACTIONS_FILE_PATH)); err != nil {
-			logger.Error("error opening transactions.txt. ensure environment variable is set", zap.String("variable", constants.ENV_TRANSACTIONS_FILE_PATH))
-		} else {
-			defer transactionFile.Close()
-			scanner := bufio.NewScanner(transactionFile)
-			scanner.Split(bufio.ScanLines)
-			var currentLine uint32
-			for scanner.Scan() {
-				currentLine++
-				rawTransaction := strings.Fields(scanner.Text())
-				if len(rawTransaction) != 4 {
-					logger.Error("transaction file is misformatted", zap.String("path", constants.ENV_TRANSACTIONS_FILE_PATH), zap.Uint32("line", currentLine))
-					continue
-				}
-				txHash := strings.TrimPrefix(rawTransaction[0], "TxHash=")
-				gas, err := strconv.ParseFloat(strings.TrimPrefix(rawTransaction[1], "Gas="), 64)
-				if err != nil {
-					logger.Error("gas conversion error", zap.String("txHash", txHash), zap.Uint32("line", currentLine))
-					continue
-				}
-				feePerGas, err := strconv.ParseFloat(strings.TrimPrefix(rawTransaction[2], "FeePerGas="), 64)
-				if err != nil {
-					logger.Error("feePerGas conversion error", zap.String("txHash", txHash), zap.Uint32("line", currentLine))
-					continue
-				}
-				signature := strings.TrimPrefix(rawTransaction[3], "Signature=")
-				err = mempool.AddTx(types.NewTx(logger, txHash, signature, gas, feePerGas), waitGroup)
-				if err != nil {
-					logger.Error("error inserting transaction", zap.String("txHash", txHash), zap.Error(err))
-					continue
-				}
-			}
-			waitGroup.Wait()
-			mempool.CloseTxInsertChan()
-			if err = mempool.ExportToFile(); err != nil {
-				logger.Error("error creating prioritized-transactions.txt", zap.Error(err))
-			}
+		if err := fs.Set(name, value); err != nil {
+			return usageError{errors.Errorf("invalid value %q for %s: %v", value, fs.env[name], err)}
+		}
+	}
+	return nil
+}
+
+// loadConfig reads the -config file, or the file named by its variable, into fs.cfg, then restores
+// the flags given on the command line, which the file may have overwritten.
+func (fs *flagSet) loadConfig(given map[string]string) error {
+	if fs.cfg == nil {
+		return nil
+	}
+	path, ok := given["config"]
+	if !ok {
+		path = os.Getenv(constants.ENV_CONFIG_FILE)
+	}
+	if path == "" {
+		return nil
+	}
+	if err := config.LoadFile(path, fs.cfg); err != nil {
+		if errors.Is(err, config.ErrInvalidConfig) {
+			return usageError{err}
+		}
+		return err
+	}
+	for name, value := range given {
+		if err := fs.Set(name, value); err != nil {
+			return usageError{err}
 		}
 	}
-	logger.Named("main").Info("Done...")
+	return nil
 }
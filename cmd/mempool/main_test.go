@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/config"
+	"mempool/pkg/constants"
+	"mempool/pkg/logging"
+)
+
+func TestDispatch(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	dir := t.TempDir()
+	input := filepath.Join(dir, "transactions.txt")
+	require.NoError(t, os.WriteFile(input, []byte("TxHash=0xa Gas=1 FeePerGas=2 Signature=0xs\n"), 0o644))
+	for _, tc := range []struct {
+		name string
+		args []string
+		env  map[string]string
+		want int
+	}{
+		{name: "help_flag", args: []string{"-help"}, want: exitOK},
+		{name: "help_command", args: []string{"help"}, want: exitOK},
+		{name: "help_for_command", args: []string{"help", "stats"}, want: exitOK},
+		{name: "command_help_flag", args: []string{"run", "-h"}, want: exitOK},
+		{name: "unknown_command", args: []string{"frobnicate"}, want: exitUsage},
+		{name: "unknown_flag", args: []string{"run", "-frobnicate"}, want: exitUsage},
+		{name: "unexpected_argument", args: []string{"stats", "-input", input, "extra"}, want: exitUsage},
+		{name: "missing_operand", args: []string{"diff", "old.txt"}, want: exitUsage},
+		{name: "invalid_env", args: []string{"run", "-input", input}, env: map[string]string{constants.ENV_MAX_MEMPOOL_SIZE: "lots"}, want: exitUsage},
+		{name: "missing_required", args: []string{"run", "-input", input}, want: exitUsage},
+		{name: "missing_input_file", args: []string{"run", "-max-size", "10", "-input", filepath.Join(dir, "missing.txt")}, want: exitFailure},
+		{name: "run_default_command", args: []string{"-max-size", "10", "-input", input, "-output", filepath.Join(dir, "out.txt")}, want: exitOK},
+		{name: "env_supplies_flag", args: []string{"validate"}, env: map[string]string{constants.ENV_TRANSACTIONS_FILE_PATH: input}, want: exitOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, variable := range []string{constants.ENV_MAX_MEMPOOL_SIZE, constants.ENV_TRANSACTIONS_FILE_PATH, constants.ENV_CONFIG_FILE} {
+				t.Setenv(variable, "")
+			}
+			for variable, value := range tc.env {
+				t.Setenv(variable, value)
+			}
+			assert.Equal(t, tc.want, dispatch(tc.args, logger))
+		})
+	}
+}
+
+func TestFlagSet_Parse(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		args      []string
+		env       string
+		wantSize  int
+		wantDelay time.Duration
+		wantUsage bool
+	}{
+		{name: "default", wantSize: 1, wantDelay: time.Second},
+		{name: "env", env: "5", wantSize: 5, wantDelay: time.Second},
+		{name: "flag_over_env", args: []string{"-size", "7"}, env: "5", wantSize: 7, wantDelay: time.Second},
+		{name: "flag_equal_to_default_over_env", args: []string{"-size", "1"}, env: "5", wantSize: 1, wantDelay: time.Second},
+		{name: "invalid_env", env: "lots", wantUsage: true},
+		{name: "invalid_env_ignored_when_flag_given", args: []string{"-size", "3"}, env: "lots", wantSize: 3, wantDelay: time.Second},
+		{name: "invalid_flag", args: []string{"-size", "lots"}, wantUsage: true},
+		{name: "other_flag", args: []string{"-delay", "2s"}, env: "5", wantSize: 5, wantDelay: 2 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("TEST_SIZE", tc.env)
+			fs := newFlagSet("test", "")
+			fs.SetOutput(io.Discard)
+			size := fs.Int("size", 1, "size")
+			fs.fromEnv("size", "TEST_SIZE")
+			delay := fs.Duration("delay", time.Second, "delay")
+
+			err := fs.parse(tc.args)
+			if tc.wantUsage {
+				assert.ErrorAs(t, err, &usageError{})
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSize, *size)
+			assert.Equal(t, tc.wantDelay, *delay)
+		})
+	}
+}
+
+func TestFlagSet_ConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mempool.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("maxMempoolSize: 10\nshards: 4\nminFeePerGas: 0.25\nstatusIndexTTL: 1h\nlogLevel: warn\n"), 0o644))
+	fromFile := config.Default()
+	fromFile.MaxMempoolSize, fromFile.Shards, fromFile.MinFeePerGas, fromFile.StatusIndexTTL, fromFile.LogLevel = 10, 4, 0.25, time.Hour, "warn"
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		modify func(*config.Config) // Applied to fromFile to get the wanted configuration
+	}{
+		{name: "file", args: []string{"-config", path}, modify: func(*config.Config) {}},
+		{name: "file_from_env", env: map[string]string{constants.ENV_CONFIG_FILE: path}, modify: func(*config.Config) {}},
+		{
+			name:   "env_over_file",
+			args:   []string{"-config", path},
+			env:    map[string]string{constants.ENV_MAX_MEMPOOL_SIZE: "20", constants.ENV_STATUS_INDEX_TTL: "2m"},
+			modify: func(c *config.Config) { c.MaxMempoolSize, c.StatusIndexTTL = 20, 2*time.Minute },
+		},
+		{
+			name: "flag_over_env_and_file",
+			args: []string{"-config", path, "-max-size", "30", "-status-index-ttl", "90s", "-min-fee-per-gas", "0.5", "-log-level", "debug"},
+			env:  map[string]string{constants.ENV_MAX_MEMPOOL_SIZE: "20", constants.ENV_MIN_FEE_PER_GAS: "1"},
+			modify: func(c *config.Config) {
+				c.MaxMempoolSize, c.StatusIndexTTL, c.MinFeePerGas, c.LogLevel = 30, 90*time.Second, 0.5, "debug"
+			},
+		},
+		{
+			// A flag given with its default value still overrides the file and the environment.
+			name:   "flag_at_default_over_file",
+			args:   []string{"-config", path, "-shards", "0", "-log-level", ""},
+			env:    map[string]string{constants.ENV_MEMPOOL_SHARDS: "8"},
+			modify: func(c *config.Config) { c.Shards, c.LogLevel = 0, "" },
+		},
+		{
+			// A flag given before -config is not overwritten by the file read afterwards.
+			name:   "flag_before_config",
+			args:   []string{"-max-size", "40", "-config", path},
+			modify: func(c *config.Config) { c.MaxMempoolSize = 40 },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, variable := range []string{constants.ENV_CONFIG_FILE, constants.ENV_MAX_MEMPOOL_SIZE, constants.ENV_MEMPOOL_SHARDS,
+				constants.ENV_MIN_FEE_PER_GAS, constants.ENV_STATUS_INDEX_TTL, constants.ENV_LOG_LEVEL} {
+				t.Setenv(variable, "")
+			}
+			for variable, value := range tc.env {
+				t.Setenv(variable, value)
+			}
+			fs := newFlagSet("test", "")
+			fs.SetOutput(io.Discard)
+			var pool poolFlags
+			pool.register(fs)
+			require.NoError(t, fs.parse(tc.args))
+
+			want := fromFile
+			tc.modify(&want)
+			assert.Equal(t, want, pool.Config)
+		})
+	}
+}
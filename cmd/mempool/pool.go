@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/config"
+	"mempool/pkg/constants"
+	"mempool/pkg/logging"
+	"mempool/pkg/server"
+	"mempool/pkg/types"
+)
+
+const (
+	ingestBatchSize       = 1024            // Transactions passed to each AddTxs call
+	adminShutdownDeadline = 5 * time.Second // How long in-flight admin requests may take on exit
+)
+
+// poolFlags bind the configuration of a long-lived pool to flags, environment variables and the
+// -config file.
+type poolFlags struct {
+	config.Config
+	wal *types.WAL // Opened by openPool, nil without WALDir
+}
+
+func (pf *poolFlags) register(fs *flagSet) {
+	pf.Config = config.Default()
+	fs.withConfig(&pf.Config)
+	fs.Var((*uint32Value)(&pf.MaxMempoolSize), "max-size", "maximum `number` of pooled transactions (required)")
+	fs.bind("max-size", constants.ENV_MAX_MEMPOOL_SIZE, "maxMempoolSize")
+	fs.IntVar(&pf.Shards, "shards", pf.Shards, "number of shards, 0 for a single-lock pool")
+	fs.bind("shards", constants.ENV_MEMPOOL_SHARDS, "shards")
+	fs.Float64Var(&pf.MinFeePerGas, "min-fee-per-gas", pf.MinFeePerGas, "minimum FeePerGas admitted")
+	fs.bind("min-fee-per-gas", constants.ENV_MIN_FEE_PER_GAS, "minFeePerGas")
+	fs.IntVar(&pf.StatusIndexSize, "status-index-size", pf.StatusIndexSize, "discarded transactions whose status is remembered, 0 disables the index")
+	fs.bind("status-index-size", constants.ENV_STATUS_INDEX_SIZE, "statusIndexSize")
+	fs.DurationVar(&pf.StatusIndexTTL, "status-index-ttl", pf.StatusIndexTTL, "how long a discarded transaction's status is remembered, 0 until the index is full")
+	fs.bind("status-index-ttl", constants.ENV_STATUS_INDEX_TTL, "statusIndexTTL")
+	fs.StringVar(&pf.WALDir, "wal-dir", pf.WALDir, "write-ahead log directory, empty disables persistence")
+	fs.bind("wal-dir", constants.ENV_WAL_DIR, "walDir")
+	fs.StringVar(&pf.WALSyncPolicy, "wal-sync", pf.WALSyncPolicy, "when WAL records are fsynced: always, interval or never (default always)")
+	fs.bind("wal-sync", constants.ENV_WAL_SYNC_POLICY, "walSyncPolicy")
+	fs.StringVar(&pf.SnapshotPath, "snapshot", pf.SnapshotPath, "snapshot loaded on startup if present and saved on exit")
+	fs.bind("snapshot", constants.ENV_SNAPSHOT_PATH, "snapshotPath")
+	fs.DurationVar(&pf.SnapshotInterval, "snapshot-interval", pf.SnapshotInterval, "also save the snapshot on this interval, 0 disables")
+	fs.bind("snapshot-interval", constants.ENV_SNAPSHOT_INTERVAL, "snapshotInterval")
+	fs.StringVar(&pf.AdminAddr, "admin-addr", pf.AdminAddr, "listen address of the admin HTTP server, empty disables it")
+	fs.bind("admin-addr", constants.ENV_ADMIN_ADDR, "adminAddr")
+	fs.StringVar(&pf.InputPath, "input", pf.InputPath, inputUsage)
+	fs.bind("input", constants.ENV_TRANSACTIONS_FILE_PATH, "inputPath")
+	fs.StringVar(&pf.InputFormat, "format", pf.InputFormat, formatUsage)
+	fs.bind("format", constants.ENV_INPUT_FORMAT, "inputFormat")
+	fs.StringVar(&pf.LogLevel, "log-level", pf.LogLevel, "minimum level logged: debug, info, warn or error (default info, debug when DEBUG is true)")
+	fs.bind("log-level", constants.ENV_LOG_LEVEL, "logLevel")
+}
+
+// registerExport adds the flags of the pool's export destination, used by commands that export.
+func (pf *poolFlags) registerExport(fs *flagSet) {
+	fs.StringVar(&pf.RejectsPath, "rejects", pf.RejectsPath, "JSON Lines file listing every rejected or discarded input transaction")
+	fs.bind("rejects", constants.ENV_REJECTS_FILE_PATH, "rejectsPath")
+	fs.StringVar(&pf.ExportPath, "output", pf.ExportPath, outputUsage)
+	fs.bind("output", constants.PRIORITIZED_TX_FILE_PATH, "exportPath")
+	fs.StringVar(&pf.ExportFormat, "export-format", pf.ExportFormat, exportFormatUsage)
+	fs.bind("export-format", constants.ENV_EXPORT_FORMAT, "exportFormat")
+}
+
+func (pf *poolFlags) validate() error {
+	if err := pf.Validate(); err != nil {
+		return usageError{err}
+	}
+	return nil
+}
+
+// input returns the configured transactions file.
+func (pf *poolFlags) input() *inputFlags {
+	return &inputFlags{path: pf.InputPath, format: pf.InputFormat}
+}
+
+// uint32Value is a flag.Value for a uint32, so out of range values are rejected when parsed.
+type uint32Value uint32
+
+func (v *uint32Value) Set(s string) error {
+	n, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return errors.New("must be a whole number between 0 and 4294967295")
+	}
+	*v = uint32Value(n)
+	return nil
+}
+
+func (v *uint32Value) String() string { return strconv.FormatUint(uint64(*v), 10) }
+
+// openPool builds the pool described by pf, restores it from the WAL and the snapshot, and starts the
+// admin server and scheduled snapshots. The returned close function stops everything openPool
+// started, in reverse order; it must be called even on error, and later calls do nothing.
+func (pf *poolFlags) openPool(logger logging.LoggingSystem, opts ...types.MempoolOption) (types.Mempool, func(), error) {
+	var closers []func()
+	var once sync.Once
+	closeAll := func() {
+		once.Do(func() {
+			for i := len(closers) - 1; i >= 0; i-- {
+				closers[i]()
+			}
+		})
+	}
+	level, _ := pf.Level() // Checked by validate
+	logging.SetLevel(level)
+	logger.Info("initializing mempool", zap.Uint32("maxMempoolSize", pf.MaxMempoolSize))
+
+	opts = append(pf.MempoolOptions(), opts...)
+	if pf.WALDir != "" {
+		syncPolicy, _ := types.ParseWALSyncPolicy(pf.WALSyncPolicy) // Checked by validate
+		wal, err := types.OpenWAL(pf.WALDir, types.WALOptions{Sync: syncPolicy, CompactEvery: pf.MaxMempoolSize})
+		if err != nil {
+			return nil, closeAll, errors.Wrapf(err, "failed to open write-ahead log in %s", pf.WALDir)
+		}
+		closers = append(closers, func() { wal.Close() })
+		logger.Info("write-ahead log opened", zap.String("dir", pf.WALDir), zap.Int("recovered", len(wal.Recovered())))
+		opts = append(opts, types.WithWAL(wal))
+		pf.wal = wal
+	}
+
+	var mempool types.Mempool
+	var err error
+	if pf.Shards > 0 {
+		logger.Info("using sharded mempool", zap.Int("shards", pf.Shards))
+		mempool, err = types.NewShardedMempool(pf.MaxMempoolSize, pf.Shards, logger, opts...)
+	} else {
+		mempool, err = types.NewMempool(pf.MaxMempoolSize, logger, opts...)
+	}
+	if err != nil {
+		return nil, closeAll, errors.Wrap(err, "failed to initialize mempool")
+	}
+
+	if pf.AdminAddr != "" {
+		adminServer := server.New(pf.AdminAddr, mempool, logger)
+		if _, err := adminServer.Start(); err != nil {
+			return nil, closeAll, errors.Wrap(err, "failed to start admin server")
+		}
+		closers = append(closers, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), adminShutdownDeadline)
+			defer cancel()
+			if err := adminServer.Shutdown(ctx); err != nil {
+				logger.Error("error stopping admin server", zap.Error(err))
+			}
+		})
+	}
+
+	if pf.SnapshotPath != "" {
+		if _, err := os.Stat(pf.SnapshotPath); err == nil {
+			if err = mempool.LoadSnapshot(pf.SnapshotPath); err != nil {
+				return nil, closeAll, errors.Wrapf(err, "failed to load snapshot %s", pf.SnapshotPath)
+			}
+		}
+		if pf.SnapshotInterval > 0 {
+			closers = append(closers, scheduleSnapshots(mempool, pf.SnapshotPath, pf.SnapshotInterval, logger))
+		}
+	}
+	return mempool, closeAll, nil
+}
+
+// saveSnapshot saves mempool to the configured snapshot path, if any.
+func (pf *poolFlags) saveSnapshot(mempool types.Mempool, logger logging.LoggingSystem) {
+	if pf.SnapshotPath == "" {
+		return
+	}
+	if err := mempool.SaveSnapshot(pf.SnapshotPath); err != nil {
+		logger.Error("error saving snapshot", zap.String("path", pf.SnapshotPath), zap.Error(err))
+	}
+}
+
+// numProcessors is the number of processors started for a pool: one per CPU core, because
+// processing is CPU bound.
+func numProcessors() uint8 {
+	return uint8(min(runtime.NumCPU(), 255))
+}
+
+// scheduleSnapshots saves a snapshot of mempool to path every interval until the returned stop function is called.
+func scheduleSnapshots(mempool types.Mempool, path string, interval time.Duration, logger logging.LoggingSystem) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := mempool.SaveSnapshot(path); err != nil {
+					logger.Error("error saving scheduled snapshot", zap.String("path", path), zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
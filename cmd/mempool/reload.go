@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"mempool/pkg/config"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// reloader re-reads the configuration of a running "serve" and applies the settings that can change
+// without a restart. Other changes are refused and logged; they keep their running value until the
+// process is restarted.
+type reloader struct {
+	args    []string      // Command line of serve, parsed again on each reload
+	path    string        // Configuration file, empty without -config
+	running config.Config // Configuration in effect, except MaxMempoolSize, which is read from mempool
+	mempool types.Mempool
+	wal     *types.WAL // Compacted every maxMempoolSize records, nil without a write-ahead log
+	logger  *zap.Logger
+
+	modTime time.Time // Of the configuration file when last seen by changed
+	size    int64
+}
+
+func newReloader(args []string, path string, running config.Config, mempool types.Mempool, wal *types.WAL, logger logging.LoggingSystem) *reloader {
+	r := &reloader{args: args, path: path, running: running, mempool: mempool, wal: wal, logger: logger.Named("main/reload")}
+	r.changed()
+	return r
+}
+
+// changed reports whether the configuration file was modified since the last call.
+func (r *reloader) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false // Reported by reload if the file is still missing when it is next modified
+	}
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false
+	}
+	r.modTime, r.size = info.ModTime(), info.Size()
+	return true
+}
+
+// reload reads the configuration the way serve did on startup, from the file, the environment and the
+// original flags, and applies what changed. A configuration that does not load or validate is
+// rejected whole and the running one is kept.
+func (r *reloader) reload() {
+	next, err := r.load()
+	if err != nil {
+		r.logger.Error("configuration not reloaded, keeping the running configuration", zap.String("path", r.path), zap.Error(err))
+		return
+	}
+	r.applyConfig(next)
+}
+
+// load parses the original command line again, reading the file and the environment anew.
+func (r *reloader) load() (config.Config, error) {
+	fs, next, _ := serveFlags()
+	fs.SetOutput(io.Discard)
+	if err := fs.parse(r.args); err != nil {
+		return config.Config{}, err
+	}
+	return next.Config, next.Validate()
+}
+
+// applyConfig applies the reloadable settings of next that differ from the running configuration,
+// and logs every other difference as refused.
+func (r *reloader) applyConfig(next config.Config) {
+	// PUT /admin/capacity also resizes the pool, so the pool holds the capacity in effect.
+	r.running.MaxMempoolSize = r.mempool.MaxMemPoolSize()
+	changes := config.Diff(r.running, next)
+	if len(changes) == 0 {
+		r.logger.Info("configuration reloaded, nothing changed", zap.String("path", r.path))
+		return
+	}
+	for _, change := range changes {
+		if reason := r.refusal(change); reason != "" {
+			r.logger.Warn("configuration change not applied", zap.String("key", change.Key), zap.String("reason", reason),
+				zap.Any("running", change.Old), zap.Any("requested", change.New))
+			continue
+		}
+		if err := r.apply(next, change.Key); err != nil {
+			r.logger.Error("error applying configuration change", zap.String("key", change.Key), zap.Error(err))
+			continue
+		}
+		r.logger.Info("applied configuration change", zap.String("key", change.Key), zap.Any("previous", change.Old), zap.Any("value", change.New))
+	}
+}
+
+// refusal returns why change cannot be applied to the running pool, or "" if it can.
+func (r *reloader) refusal(change config.Change) string {
+	switch {
+	case !change.Reloadable:
+		return "restart to apply"
+	case change.Key == "statusIndexTTL" && r.running.StatusIndexSize == 0:
+		return "the status index is disabled; enabling it with statusIndexSize requires a restart"
+	}
+	return ""
+}
+
+// toggleDebug switches every logger between debug and the configured level, so a running pool can
+// be inspected briefly without editing its configuration. Per-logger overrides are left alone.
+func (r *reloader) toggleDebug() {
+	level, _ := r.running.Level() // Checked by Validate
+	if logging.Level() != zapcore.DebugLevel {
+		level = zapcore.DebugLevel
+	}
+	logging.SetLevel(level)
+	r.logger.Info("switched log level", zap.Stringer("level", level))
+}
+
+// apply sets the reloadable setting called key from next, and records it as running.
+func (r *reloader) apply(next config.Config, key string) error {
+	switch key {
+	case "maxMempoolSize":
+		if _, err := r.mempool.SetMaxMemPoolSize(next.MaxMempoolSize); err != nil {
+			return err
+		}
+		if r.wal != nil {
+			r.wal.SetCompactEvery(next.MaxMempoolSize) // As openPool set it for the startup capacity
+		}
+		r.running.MaxMempoolSize = next.MaxMempoolSize
+	case "minFeePerGas":
+		r.mempool.SetMinFeePerGas(next.MinFeePerGas)
+		r.running.MinFeePerGas = next.MinFeePerGas
+	case "statusIndexTTL":
+		r.mempool.SetStatusIndexTTL(next.StatusIndexTTL)
+		r.running.StatusIndexTTL = next.StatusIndexTTL
+	case "logLevel":
+		level, _ := next.Level() // Checked by Validate
+		logging.SetLevel(level)
+		r.running.LogLevel = next.LogLevel
+	}
+	return nil
+}
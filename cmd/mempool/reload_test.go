@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"mempool/pkg/config"
+	"mempool/pkg/constants"
+	"mempool/pkg/logging"
+	"mempool/pkg/server"
+	"mempool/pkg/types"
+)
+
+// fakeMempool records the runtime setters called by the reloader. Any other method panics.
+type fakeMempool struct {
+	types.Mempool
+	size         uint32
+	maxSize      []uint32
+	minFeePerGas []float64
+	ttl          []time.Duration
+}
+
+func (f *fakeMempool) MaxMemPoolSize() uint32 { return f.size }
+
+func (f *fakeMempool) SetMaxMemPoolSize(n uint32) (int, error) {
+	f.size = n
+	f.maxSize = append(f.maxSize, n)
+	return 0, nil
+}
+
+func (f *fakeMempool) SetMinFeePerGas(min float64) { f.minFeePerGas = append(f.minFeePerGas, min) }
+
+func (f *fakeMempool) SetStatusIndexTTL(ttl time.Duration) { f.ttl = append(f.ttl, ttl) }
+
+// newTestReloader starts a reloader for "serve -config path" with the configuration in path.
+func newTestReloader(t *testing.T, path string, wal *types.WAL) (*reloader, *fakeMempool, *observer.ObservedLogs) {
+	for _, variable := range []string{constants.ENV_CONFIG_FILE, constants.ENV_MAX_MEMPOOL_SIZE, constants.ENV_MEMPOOL_SHARDS, constants.ENV_MIN_FEE_PER_GAS,
+		constants.ENV_STATUS_INDEX_SIZE, constants.ENV_STATUS_INDEX_TTL, constants.ENV_LOG_LEVEL, constants.ENV_WAL_DIR, constants.ENV_ADMIN_ADDR} {
+		t.Setenv(variable, "")
+	}
+	previous := logging.Level()
+	t.Cleanup(func() { logging.SetLevel(previous) })
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	fake := &fakeMempool{}
+	r := newReloader([]string{"-config", path}, path, config.Config{}, fake, wal, zap.New(core))
+	running, err := r.load()
+	require.NoError(t, err)
+	r.running = running
+	fake.size = running.MaxMempoolSize
+	return r, fake, logs
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestReloader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mempool.yaml")
+	writeConfig(t, path, "maxMempoolSize: 10\nstatusIndexSize: 5\nadminAddr: localhost:0\n")
+	r, fake, logs := newTestReloader(t, path, nil)
+
+	writeConfig(t, path, "maxMempoolSize: 5\nstatusIndexSize: 5\nstatusIndexTTL: 1m\nminFeePerGas: 2\nlogLevel: warn\nshards: 4\nwalDir: wal\nadminAddr: localhost:0\n")
+	r.reload()
+	assert.Equal(t, []uint32{5}, fake.maxSize)
+	assert.Equal(t, []float64{2}, fake.minFeePerGas)
+	assert.Equal(t, []time.Duration{time.Minute}, fake.ttl)
+	assert.Equal(t, zapcore.WarnLevel, logging.Level())
+
+	want := config.Default()
+	want.MaxMempoolSize, want.StatusIndexSize, want.StatusIndexTTL, want.MinFeePerGas, want.LogLevel, want.AdminAddr = 5, 5, time.Minute, 2, "warn", "localhost:0"
+	assert.Equal(t, want, r.running, "only reloadable settings change")
+	refused := logs.FilterMessage("configuration change not applied")
+	require.Equal(t, 2, refused.Len())
+	for i, key := range []string{"shards", "walDir"} {
+		assert.Equal(t, key, refused.All()[i].ContextMap()["key"])
+		assert.Equal(t, "restart to apply", refused.All()[i].ContextMap()["reason"])
+	}
+	assert.Equal(t, 4, logs.FilterMessage("applied configuration change").Len())
+
+	// Reloading the same file again applies nothing new, but still refuses the restart-only changes.
+	r.reload()
+	assert.Len(t, fake.maxSize, 1)
+	assert.Equal(t, 4, logs.FilterMessage("configuration change not applied").Len())
+	assert.Equal(t, 4, logs.FilterMessage("applied configuration change").Len())
+}
+
+func TestReloader_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mempool.yaml")
+	writeConfig(t, path, "maxMempoolSize: 10\nadminAddr: localhost:0\n")
+	r, fake, logs := newTestReloader(t, path, nil)
+	running := r.running
+
+	for _, content := range []string{"maxMempoolSize: lots\n", "maxMempoolSize: 0\n", "maxMempoolSize: 5\nminFeePerGas: -1\n"} {
+		writeConfig(t, path, content)
+		r.reload()
+	}
+	require.NoError(t, os.Remove(path))
+	r.reload()
+
+	assert.Empty(t, fake.maxSize)
+	assert.Empty(t, fake.minFeePerGas)
+	assert.Equal(t, running, r.running)
+	assert.Equal(t, 4, logs.FilterMessage("configuration not reloaded, keeping the running configuration").Len())
+}
+
+func TestReloader_StatusIndexDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mempool.yaml")
+	writeConfig(t, path, "maxMempoolSize: 10\n")
+	r, fake, logs := newTestReloader(t, path, nil)
+
+	writeConfig(t, path, "maxMempoolSize: 10\nstatusIndexTTL: 5m\n")
+	r.reload()
+	assert.Empty(t, fake.ttl)
+	assert.Equal(t, config.DefaultStatusIndexTTL, r.running.StatusIndexTTL)
+	refused := logs.FilterMessage("configuration change not applied").All()
+	require.Len(t, refused, 1)
+	assert.Equal(t, "statusIndexTTL", refused[0].ContextMap()["key"])
+	assert.Contains(t, refused[0].ContextMap()["reason"], "status index is disabled")
+}
+
+func TestReloader_WALCompactionFollowsCapacity(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	wal, err := types.OpenWAL(t.TempDir(), types.WALOptions{Sync: types.WALSyncNever, CompactEvery: 10})
+	require.NoError(t, err)
+	defer wal.Close()
+	path := filepath.Join(t.TempDir(), "mempool.yaml")
+	writeConfig(t, path, "maxMempoolSize: 10\n")
+	r, _, _ := newTestReloader(t, path, wal)
+
+	writeConfig(t, path, "maxMempoolSize: 2\n")
+	r.reload()
+	require.NoError(t, wal.AppendAdd(types.NewTx(logger, "0xa", "0xs", 1, 1)))
+	assert.False(t, wal.ShouldCompact())
+	require.NoError(t, wal.AppendAdd(types.NewTx(logger, "0xb", "0xs", 1, 1)))
+	assert.True(t, wal.ShouldCompact(), "compaction follows the reloaded capacity")
+}
+
+// TestReloader_AdminCapacity checks that a reload diffs against the capacity set through
+// PUT /admin/capacity rather than the one last loaded from the configuration.
+func TestReloader_AdminCapacity(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	memPool, err := types.NewMempool(10, logger)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mempool.yaml")
+	writeConfig(t, path, "maxMempoolSize: 10\n")
+	r, _, logs := newTestReloader(t, path, nil)
+	r.mempool = memPool
+
+	putCapacity := func(n uint32) {
+		rec := httptest.NewRecorder()
+		server.New("", memPool, logger).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/capacity", strings.NewReader(fmt.Sprintf(`{"maxMemPoolSize": %d}`, n))))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	putCapacity(5)
+	r.reload()
+	assert.Equal(t, uint32(10), memPool.MaxMemPoolSize(), "reloading the unchanged file restores its capacity")
+	applied := logs.FilterMessage("applied configuration change").All()
+	require.Len(t, applied, 1)
+	assert.Equal(t, uint32(5), applied[0].ContextMap()["previous"])
+
+	putCapacity(20)
+	writeConfig(t, path, "maxMempoolSize: 10\nminFeePerGas: 1\n")
+	r.reload()
+	assert.Equal(t, uint32(10), memPool.MaxMemPoolSize())
+	assert.Equal(t, 1.0, memPool.MinFeePerGas())
+	assert.Equal(t, 3, logs.FilterMessage("applied configuration change").Len())
+}
+
+func TestReloader_Changed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mempool.yaml")
+	writeConfig(t, path, "maxMempoolSize: 10\n")
+	r, _, _ := newTestReloader(t, path, nil)
+	assert.False(t, r.changed())
+
+	writeConfig(t, path, "maxMempoolSize: 100\n") // A different size is seen even within the mtime resolution
+	assert.True(t, r.changed())
+	assert.False(t, r.changed())
+	require.NoError(t, os.Remove(path))
+	assert.False(t, r.changed(), "a missing file is not a change")
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// runRun implements "mempool run": it ingests a transactions file into a new pool, then exports the
+// prioritized pool.
+func runRun(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("run", "Ingest a transactions file into a new pool, then export the pool in priority order.")
+	var pool poolFlags
+	pool.register(fs)
+	pool.registerExport(fs)
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	if err := pool.validate(); err != nil {
+		return err
+	}
+	in := pool.input()
+	if in.path == "" {
+		return usageError{errors.Errorf("-input, %s or inputPath is required", constants.ENV_TRANSACTIONS_FILE_PATH)}
+	}
+
+	// The report is created before the mempool so transactions dropped by processors are reported too.
+	report := ingest.NewReport(nil)
+	if pool.RejectsPath != "" {
+		rejectsFile, err := os.Create(pool.RejectsPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to create rejects file")
+		}
+		defer rejectsFile.Close()
+		report = ingest.NewReport(rejectsFile)
+	}
+	mempool, closePool, err := pool.openPool(logger, types.WithDropHandler(report.Dropped))
+	defer closePool()
+	if err != nil {
+		return err
+	}
+	reader, closeInput, err := in.open(logger)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	waitGroup := &sync.WaitGroup{}
+	mempool.StartProcessors(waitGroup, numProcessors())
+	logger.Info("workers started", zap.Uint8("count", numProcessors()))
+	// start timer to test performance
+	start := time.Now()
+	defer func() {
+		logger.Info("Total time taken to process transactions", zap.Duration("duration", time.Since(start)))
+	}()
+	logger.Info("retrieving transactions and inserting into mempool")
+	ingestErr := ingestInput(mempool, reader, in.path, report, logger)
+	waitGroup.Wait()
+	logSummary(report.Summary(), mempool, logger)
+	if err = report.Flush(); err != nil {
+		logger.Error("error writing rejects file", zap.String("path", pool.RejectsPath), zap.Error(err))
+	}
+	mempool.CloseTxInsertChan()
+	pool.saveSnapshot(mempool, logger)
+	if ingestErr != nil {
+		return ingestErr
+	}
+
+	result, err := mempool.ExportToFile()
+	if err != nil {
+		return errors.Wrap(err, "failed to export prioritized transactions")
+	}
+	logger.Info("export complete", zap.String("path", result.Path), zap.Int("records", result.Records), zap.String("sha256", result.Checksum))
+	logger.Named("main").Info("Done...")
+	return nil
+}
+
+// serveFlags returns the flags of "serve", with the pool configuration and the configuration watch
+// interval they are parsed into.
+func serveFlags() (*flagSet, *poolFlags, *time.Duration) {
+	fs := newFlagSet("serve", "Run a pool behind the admin HTTP server until SIGINT or SIGTERM, optionally preloading\na transactions file. On shutdown the pool is saved to -snapshot, if set.\n\nOn SIGHUP, or when -config-watch sees the -config file change, the configuration is read\nagain: maxMempoolSize, minFeePerGas, statusIndexTTL and logLevel are applied to the running\npool, other changes are logged and need a restart. SIGUSR1 switches logging between debug and\nthe configured level; GET and PUT /admin/log-level read and change levels per logger.")
+	pool := &poolFlags{}
+	pool.register(fs)
+	watch := fs.Duration("config-watch", 0, "check the -config file for changes on this interval and reload it, 0 reloads only on SIGHUP")
+	fs.fromEnv("config-watch", constants.ENV_CONFIG_WATCH_INTERVAL)
+	return fs, pool, watch
+}
+
+// runServe implements "mempool serve": it keeps a pool running behind the admin HTTP server until
+// the process is interrupted, reloading its configuration on SIGHUP.
+func runServe(args []string, logger logging.LoggingSystem) error {
+	fs, pool, watch := serveFlags()
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	if err := pool.validate(); err != nil {
+		return err
+	}
+	if pool.AdminAddr == "" {
+		return usageError{errors.Errorf("-admin-addr, %s or adminAddr is required", constants.ENV_ADMIN_ADDR)}
+	}
+	configPath := fs.Lookup("config").Value.String()
+	if *watch < 0 {
+		return usageError{errors.Errorf("-config-watch must not be negative, got %v", *watch)}
+	} else if *watch > 0 && configPath == "" {
+		return usageError{errors.Errorf("-config-watch requires -config or %s", constants.ENV_CONFIG_FILE)}
+	}
+	in := pool.input()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// A SIGHUP or SIGUSR1 during startup is handled once serving instead of terminating the process.
+	hangup, toggle := make(chan os.Signal, 1), make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	signal.Notify(toggle, syscall.SIGUSR1)
+	defer signal.Stop(toggle)
+	mempool, closePool, err := pool.openPool(logger)
+	defer closePool()
+	if err != nil {
+		return err
+	}
+	waitGroup := &sync.WaitGroup{}
+	mempool.StartProcessors(waitGroup, numProcessors())
+	logger.Info("workers started", zap.Uint8("count", numProcessors()))
+
+	if in.path != "" {
+		reader, closeInput, err := in.open(logger)
+		if err != nil {
+			return err
+		}
+		report := ingest.NewReport(nil)
+		err = ingestInput(mempool, reader, in.path, report, logger)
+		closeInput()
+		waitGroup.Wait()
+		if err != nil {
+			return err
+		}
+		logSummary(report.Summary(), mempool, logger)
+	}
+
+	var poll <-chan time.Time
+	if *watch > 0 {
+		ticker := time.NewTicker(*watch)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	reloader := newReloader(args, configPath, pool.Config, mempool, pool.wal, logger)
+
+	logger.Info("serving until interrupted", zap.String("adminAddr", pool.AdminAddr))
+serve:
+	for {
+		select {
+		case <-ctx.Done():
+			break serve
+		case <-hangup:
+			logger.Info("received SIGHUP, reloading configuration")
+			reloader.reload()
+		case <-toggle:
+			reloader.toggleDebug()
+		case <-poll:
+			if reloader.changed() {
+				logger.Info("configuration file changed, reloading", zap.String("path", configPath))
+				reloader.reload()
+			}
+		}
+	}
+	logger.Info("shutting down")
+	closePool() // Stops the admin server so the snapshot sees no further changes
+	mempool.CloseTxInsertChan()
+	pool.saveSnapshot(mempool, logger)
+	return nil
+}
+
+// ingestInput admits every transaction read from reader into mempool in batches, recording each
+// outcome in report. Malformed records are logged and skipped; a read failure stops ingestion.
+func ingestInput(mempool types.Mempool, reader ingest.Reader, path string, report *ingest.Report, logger logging.LoggingSystem) error {
+	// Transactions are admitted in batches so each lock is taken once per batch rather than per transaction.
+	batch := make([]*types.Tx, 0, ingestBatchSize)
+	flush := func() {
+		for i, err := range mempool.AddTxs(batch) {
+			if errors.Is(err, types.ErrFeeTooLow) {
+				logger.Debug("transaction fee too low for full mempool", zap.String("txHash", batch[i].TxHash))
+			} else if errors.Is(err, types.ErrBelowMinFee) {
+				logger.Debug("transaction fee below minimum fee per gas", zap.String("txHash", batch[i].TxHash))
+			} else if errors.Is(err, types.ErrPaused) {
+				logger.Warn("transaction submitted while admission is paused", zap.String("txHash", batch[i].TxHash))
+			} else if err != nil {
+				logger.Error("error inserting transaction", zap.String("txHash", batch[i].TxHash), zap.Error(err))
+			}
+			if err != nil {
+				report.Rejected(batch[i], err)
+			}
+		}
+		batch = batch[:0]
+	}
+	defer flush()
+	for {
+		tx, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var lineErr *ingest.LineError
+		if errors.As(err, &lineErr) {
+			logger.Error("transaction file is misformatted", zap.String("path", path), zap.Int("line", lineErr.Line), zap.Error(lineErr.Err))
+			report.Malformed(lineErr)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read transactions from %s", path)
+		}
+		report.Parsed(tx, reader.Line())
+		if batch = append(batch, tx); len(batch) == ingestBatchSize {
+			flush()
+		}
+	}
+}
+
+func logSummary(summary ingest.Summary, mempool types.Mempool, logger logging.LoggingSystem) {
+	logger.Info("ingestion summary", zap.Int("records", summary.Records), zap.Int("parsed", summary.Parsed),
+		zap.Int("malformed", summary.Malformed), zap.Int("duplicate", summary.Duplicate), zap.Int("invalid", summary.Invalid), zap.Int("rejected", summary.Rejected),
+		zap.Int("paused", summary.Paused), zap.Int("belowMinFee", summary.BelowMin), zap.Int("feeTooLow", summary.FeeTooLow), zap.Int("evicted", summary.Evicted), zap.Uint32("pooled", mempool.MempoolLen()))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// runStats implements "mempool stats": it loads a transactions file or a snapshot into a pool and
+// prints the pool's statistics.
+func runStats(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("stats", "Load a transactions file or a snapshot into a pool and print its fee distribution,\ntotal gas and transactions per sender.")
+	var source sourceFlags
+	source.register(fs)
+	percentileList := fs.String("percentiles", "", "comma separated percentiles to report (default 10,25,50,75,90,99)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	percentiles, err := parsePercentiles(*percentileList)
+	if err != nil {
+		return usageError{err}
+	}
+
+	mempool, err := source.load(logger)
+	if err != nil {
+		return err
+	}
+	stats, err := mempool.Stats(percentiles...)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return errors.Wrap(enc.Encode(stats), "failed to write statistics")
+	}
+	return errors.Wrap(printStats(os.Stdout, stats), "failed to write statistics")
+}
+
+func parsePercentiles(list string) ([]float64, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var percentiles []float64
+	for _, field := range strings.Split(list, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, errors.Wrapf(types.ErrInvalidPercentile, "%q", field)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+func printStats(out io.Writer, stats types.PoolStats) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "transactions\t%d\t\n", stats.Count)
+	fmt.Fprintf(w, "total gas\t%s\t\n", formatFloat(stats.TotalGas))
+	fmt.Fprintf(w, "senders\t%d\t\n\n", len(stats.Senders))
+
+	fmt.Fprint(w, "\tmin\t")
+	for _, p := range stats.TotalFee.Percentiles {
+		fmt.Fprintf(w, "p%s\t", formatFloat(p.Percentile))
+	}
+	fmt.Fprint(w, "max\tmean\tmedian\t\n")
+	for _, row := range []struct {
+		name string
+		dist types.FeeDistribution
+	}{{"TotalFee", stats.TotalFee}, {"FeePerGas", stats.FeePerGas}} {
+		fmt.Fprintf(w, "%s\t%s\t", row.name, formatFloat(row.dist.Min))
+		for _, p := range row.dist.Percentiles {
+			fmt.Fprintf(w, "%s\t", formatFloat(p.Value))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", formatFloat(row.dist.Max), formatFloat(row.dist.Mean), formatFloat(row.dist.Median))
+	}
+
+	if len(stats.Senders) > 0 {
+		senders := make([]string, 0, len(stats.Senders))
+		for sender := range stats.Senders {
+			senders = append(senders, sender)
+		}
+		// Busiest senders first, then by name so the output is stable.
+		sort.Slice(senders, func(i, j int) bool {
+			if stats.Senders[senders[i]] != stats.Senders[senders[j]] {
+				return stats.Senders[senders[i]] > stats.Senders[senders[j]]
+			}
+			return senders[i] < senders[j]
+		})
+		fmt.Fprint(w, "\nsender\ttransactions\t\n")
+		for _, sender := range senders {
+			fmt.Fprintf(w, "%s\t%d\t\n", sender, stats.Senders[sender])
+		}
+	}
+	return w.Flush()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/types"
+)
+
+func TestPrintStats(t *testing.T) {
+	stats := types.PoolStats{
+		Count:    3,
+		TotalGas: 30,
+		TotalFee: types.FeeDistribution{Min: 10, Max: 40, Mean: 25, Median: 20,
+			Percentiles: []types.PercentileValue{{Percentile: 90, Value: 40}}},
+		FeePerGas: types.FeeDistribution{Min: 1, Max: 4, Mean: 2.5, Median: 2,
+			Percentiles: []types.PercentileValue{{Percentile: 90, Value: 4}}},
+		Senders: map[string]int{"bob": 1, "alice": 2},
+	}
+	var out strings.Builder
+	require.NoError(t, printStats(&out, stats))
+
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		rows = append(rows, strings.Fields(line))
+	}
+	assert.Equal(t, [][]string{
+		{"transactions", "3"},
+		{"total", "gas", "30"},
+		{"senders", "2"},
+		{},
+		{"min", "p90", "max", "mean", "median"},
+		{"TotalFee", "10", "40", "40", "25", "20"},
+		{"FeePerGas", "1", "4", "4", "2.5", "2"},
+		{},
+		{"sender", "transactions"},
+		{"alice", "2"},
+		{"bob", "1"},
+	}, rows)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// runStatus implements "mempool status": it asks a running "mempool serve" what happened to a
+// transaction hash.
+func runStatus(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("status", "Ask the admin HTTP server of a running \"mempool serve\" whether a transaction is queued\nor pooled, or why it left the pool. Discarded transactions are only known when serve runs\nwith -status-index-size.")
+	fs.expect("HASH")
+	addr := fs.String("admin-addr", "", "address of the admin HTTP server, e.g. localhost:8080 (required)")
+	fs.fromEnv("admin-addr", constants.ENV_ADMIN_ADDR)
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	timeout := fs.Duration("timeout", 5*time.Second, "how long to wait for the server")
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	if *addr == "" {
+		return usageError{errors.Errorf("-admin-addr or %s is required", constants.ENV_ADMIN_ADDR)}
+	}
+
+	status, err := queryTxStatus(&http.Client{Timeout: *timeout}, *addr, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return errors.Wrap(json.NewEncoder(os.Stdout).Encode(status), "failed to write status")
+	}
+	return errors.Wrap(printStatus(os.Stdout, status), "failed to write status")
+}
+
+// queryTxStatus gets the status of txHash from the admin server at addr, which may be a host:port
+// or a URL.
+func queryTxStatus(client *http.Client, addr, txHash string) (types.TxStatus, error) {
+	base := addr
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	resp, err := client.Get(strings.TrimSuffix(base, "/") + "/txs/" + url.PathEscape(txHash) + "/status")
+	if err != nil {
+		return types.TxStatus{}, errors.Wrap(err, "failed to query admin server")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return types.TxStatus{}, errors.Wrap(err, "failed to read admin server response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &failure) != nil || failure.Error == "" {
+			failure.Error = strings.TrimSpace(string(body))
+		}
+		return types.TxStatus{}, errors.Errorf("admin server answered %s: %s", resp.Status, failure.Error)
+	}
+	var status types.TxStatus
+	return status, errors.Wrap(json.Unmarshal(body, &status), "invalid admin server response")
+}
+
+func printStatus(w io.Writer, status types.TxStatus) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", status.TxHash, status.Status)
+	if status.Reason != "" {
+		fmt.Fprintf(&b, "  reason:     %s\n", status.Reason)
+	}
+	if status.EvictedBy != "" {
+		fmt.Fprintf(&b, "  evicted by: %s\n", status.EvictedBy)
+	}
+	if !status.Time.IsZero() {
+		fmt.Fprintf(&b, "  at:         %s\n", status.Time.Format(time.RFC3339Nano))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// problem is a record that failed validation.
+type problem struct {
+	line   int
+	txHash string
+	err    error
+}
+
+// runValidate implements "mempool validate": it checks that every record of a transactions file
+// parses, passes Tx.Validate and would be admitted by a pool with room for it.
+func runValidate(args []string, logger logging.LoggingSystem) error {
+	fs := newFlagSet("validate", "Check that every record of a transactions file parses, has a hash, a signature and positive\ngas and fee per gas, and would be admitted by a pool with room for all of them. Problems are listed by line; the command fails if there are any.")
+	var in inputFlags
+	in.register(fs)
+	minFeePerGas := fs.Float64("min-fee-per-gas", 0, "minimum FeePerGas admitted")
+	fs.fromEnv("min-fee-per-gas", constants.ENV_MIN_FEE_PER_GAS)
+	maxProblems := fs.Int("max-problems", 20, "problems listed, 0 lists every one")
+	if err := fs.parse(args); err != nil {
+		return err
+	}
+	if err := in.required(); err != nil {
+		return err
+	}
+	if *minFeePerGas < 0 {
+		return usageError{errors.Errorf("-min-fee-per-gas %v is negative", *minFeePerGas)}
+	}
+
+	reader, closeInput, err := in.open(logger)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+	report := ingest.NewReport(nil)
+	var problems []problem
+	var txs []*types.Tx
+	var lines []int
+	for {
+		tx, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var lineErr *ingest.LineError
+		if errors.As(err, &lineErr) {
+			report.Malformed(lineErr)
+			problems = append(problems, problem{line: lineErr.Line, err: lineErr.Err})
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", in.path)
+		}
+		report.Parsed(tx, reader.Line())
+		if err := tx.Validate(); err != nil {
+			report.Rejected(tx, err)
+			problems = append(problems, problem{line: reader.Line(), txHash: tx.TxHash, err: err})
+			continue
+		}
+		txs = append(txs, tx)
+		lines = append(lines, reader.Line())
+	}
+
+	// Admission rules are checked by a pool large enough that capacity never rejects a transaction.
+	mempool, err := types.NewMempool(uint32(max(len(txs), 1)), logger, types.WithMinFeePerGas(*minFeePerGas))
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(txs); start += ingestBatchSize {
+		batch := txs[start:min(start+ingestBatchSize, len(txs))]
+		for i, err := range mempool.AddTxs(batch) {
+			if err != nil {
+				report.Rejected(batch[i], err)
+				problems = append(problems, problem{line: lines[start+i], txHash: batch[i].TxHash, err: err})
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].line < problems[j].line })
+	for i, p := range problems {
+		if *maxProblems > 0 && i == *maxProblems {
+			fmt.Fprintf(os.Stdout, "... and %d more\n", len(problems)-i)
+			break
+		}
+		if p.txHash != "" {
+			fmt.Fprintf(os.Stdout, "line %d: %s: %v\n", p.line, p.txHash, p.err)
+		} else {
+			fmt.Fprintf(os.Stdout, "line %d: %v\n", p.line, p.err)
+		}
+	}
+	summary := report.Summary()
+	fmt.Fprintf(os.Stdout, "%d records: %d valid, %d malformed, %d invalid, %d duplicate, %d below minimum fee, %d otherwise rejected\n",
+		summary.Records, summary.Records-len(problems), summary.Malformed, summary.Invalid, summary.Duplicate, summary.BelowMin, summary.Rejected)
+	if len(problems) > 0 {
+		return errors.Errorf("%d of %d records failed validation", len(problems), summary.Records)
+	}
+	return nil
+}
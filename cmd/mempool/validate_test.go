@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/logging"
+)
+
+func TestRunValidate(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	t.Setenv(constants.ENV_MIN_FEE_PER_GAS, "")
+	path := filepath.Join(t.TempDir(), "transactions.txt")
+	input := strings.Join([]string{
+		"TxHash=0xa Gas=1 FeePerGas=1 Signature=0xs",
+		"TxHash=0xb Gas=0 FeePerGas=1 Signature=0xs",
+		"TxHash=0xc Gas=1 FeePerGas=-1 Signature=0xs",
+		"TxHash=0xd Gas=1 FeePerGas=1 Signature=",
+	}, "\n")
+	require.NoError(t, os.WriteFile(path, []byte(input), 0o644))
+
+	err = runValidate([]string{"-input", path}, logger)
+	assert.EqualError(t, err, "3 of 4 records failed validation")
+}
@@ -0,0 +1,170 @@
+// Package config holds the typed configuration of a mempool process. A configuration starts from
+// Default, is overlaid with a YAML or JSON file by Load, and is checked by Validate before anything
+// is built from it. The command line applies environment variables and flags on top of the file, in
+// that order, so the precedence is defaults < file < environment < flags.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+
+	"mempool/pkg/constants"
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+const DefaultStatusIndexTTL = time.Hour // How long discarded transactions stay queryable by default
+
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Config is every setting of a mempool process. The yaml keys are also used for JSON files.
+type Config struct {
+	MaxMempoolSize   uint32        `yaml:"maxMempoolSize"`   // Required
+	Shards           int           `yaml:"shards"`           // 0 for a single-lock pool
+	MinFeePerGas     float64       `yaml:"minFeePerGas"`     // 0 disables the fee floor
+	StatusIndexSize  int           `yaml:"statusIndexSize"`  // 0 disables the status index
+	StatusIndexTTL   time.Duration `yaml:"statusIndexTTL"`   // 0 keeps statuses until the index is full
+	InputPath        string        `yaml:"inputPath"`        // Transactions file, "-" for standard input
+	InputFormat      string        `yaml:"inputFormat"`      // Empty to guess from InputPath
+	RejectsPath      string        `yaml:"rejectsPath"`      // Empty disables the rejects file
+	ExportPath       string        `yaml:"exportPath"`       // Empty for the default export path
+	ExportFormat     string        `yaml:"exportFormat"`     // Empty for the text format
+	WALDir           string        `yaml:"walDir"`           // Empty disables the write-ahead log
+	WALSyncPolicy    string        `yaml:"walSyncPolicy"`    // Empty for always
+	SnapshotPath     string        `yaml:"snapshotPath"`     // Empty disables snapshots
+	SnapshotInterval time.Duration `yaml:"snapshotInterval"` // 0 saves the snapshot only on exit
+	AdminAddr        string        `yaml:"adminAddr"`        // Empty disables the admin server
+	LogLevel         string        `yaml:"logLevel"`         // debug, info, warn or error; empty for logging.DefaultLevel
+}
+
+// reloadable lists the keys of the settings a running pool can change without a restart.
+var reloadable = map[string]bool{"maxMempoolSize": true, "minFeePerGas": true, "statusIndexTTL": true, "logLevel": true}
+
+// Default returns the configuration used when nothing is set. It is not valid on its own because
+// MaxMempoolSize is required.
+func Default() Config {
+	return Config{StatusIndexTTL: DefaultStatusIndexTTL}
+}
+
+// Load reads the YAML or JSON file at path over Default. Unknown keys are rejected so a misspelt
+// setting is not silently ignored. The result is not validated.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	return cfg, LoadFile(path, &cfg)
+}
+
+// LoadFile reads the YAML or JSON file at path over cfg, leaving settings the file does not mention
+// unchanged.
+func LoadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read configuration file")
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF { // An empty file sets nothing
+		// Type errors come one per line after "unmarshal errors:".
+		message := strings.ReplaceAll(strings.Replace(strings.TrimPrefix(err.Error(), "yaml: "), ":\n  ", ": ", 1), "\n  ", "; ")
+		return errors.Wrapf(ErrInvalidConfig, "%s: %s", path, message)
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once, naming each setting by its file
+// key and environment variable.
+func (c Config) Validate() error {
+	var problems []string
+	problem := func(key, variable, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s (%s) ", key, variable)+fmt.Sprintf(format, args...))
+	}
+	if c.MaxMempoolSize == 0 {
+		problem("maxMempoolSize", constants.ENV_MAX_MEMPOOL_SIZE, "is required and must be positive")
+	}
+	if c.Shards < 0 {
+		problem("shards", constants.ENV_MEMPOOL_SHARDS, "is %d, must not be negative", c.Shards)
+	}
+	if c.MinFeePerGas < 0 || math.IsNaN(c.MinFeePerGas) || math.IsInf(c.MinFeePerGas, 0) {
+		problem("minFeePerGas", constants.ENV_MIN_FEE_PER_GAS, "is %v, must be a non-negative number", c.MinFeePerGas)
+	}
+	if c.StatusIndexSize < 0 {
+		problem("statusIndexSize", constants.ENV_STATUS_INDEX_SIZE, "is %d, must not be negative", c.StatusIndexSize)
+	}
+	if c.StatusIndexTTL < 0 {
+		problem("statusIndexTTL", constants.ENV_STATUS_INDEX_TTL, "is %v, must not be negative", c.StatusIndexTTL)
+	}
+	switch c.InputFormat {
+	case "", ingest.FormatKeyValue, ingest.FormatJSONL, ingest.FormatCSV:
+	default:
+		problem("inputFormat", constants.ENV_INPUT_FORMAT, "is %q, must be %s, %s or %s", c.InputFormat, ingest.FormatKeyValue, ingest.FormatJSONL, ingest.FormatCSV)
+	}
+	if _, err := types.LookupExportFormat(c.ExportFormat); err != nil {
+		problem("exportFormat", constants.ENV_EXPORT_FORMAT, "is %q, must be one of %s", c.ExportFormat, strings.Join(types.ExportFormats(), ", "))
+	}
+	if _, err := types.ParseWALSyncPolicy(c.WALSyncPolicy); err != nil {
+		problem("walSyncPolicy", constants.ENV_WAL_SYNC_POLICY, "is %q, must be always, interval or never", c.WALSyncPolicy)
+	}
+	if c.SnapshotInterval < 0 {
+		problem("snapshotInterval", constants.ENV_SNAPSHOT_INTERVAL, "is %v, must not be negative", c.SnapshotInterval)
+	} else if c.SnapshotInterval > 0 && c.SnapshotPath == "" {
+		problem("snapshotInterval", constants.ENV_SNAPSHOT_INTERVAL, "requires snapshotPath (%s)", constants.ENV_SNAPSHOT_PATH)
+	}
+	if _, err := c.Level(); err != nil {
+		problem("logLevel", constants.ENV_LOG_LEVEL, "is %q, must be debug, info, warn or error", c.LogLevel)
+	}
+	if len(problems) > 0 {
+		return errors.Wrap(ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Level returns the log level to run at.
+func (c Config) Level() (zapcore.Level, error) {
+	if c.LogLevel == "" {
+		return logging.DefaultLevel(), nil
+	}
+	return zapcore.ParseLevel(c.LogLevel)
+}
+
+// Change is a setting that differs between two configurations.
+type Change struct {
+	Key        string      // File key of the setting
+	Old, New   interface{} // Values before and after
+	Reloadable bool        // Whether a running pool can apply the new value
+}
+
+// Diff lists the settings of next that differ from running, in the order Config declares them.
+func Diff(running, next Config) []Change {
+	var changes []Change
+	old, updated := reflect.ValueOf(running), reflect.ValueOf(next)
+	for i := 0; i < old.NumField(); i++ {
+		if o, n := old.Field(i).Interface(), updated.Field(i).Interface(); o != n {
+			key := old.Type().Field(i).Tag.Get("yaml")
+			changes = append(changes, Change{Key: key, Old: o, New: n, Reloadable: reloadable[key]})
+		}
+	}
+	return changes
+}
+
+// MempoolOptions returns the options that apply the configuration to types.NewMempool or
+// types.NewShardedMempool. The write-ahead log is opened by the caller, which owns its lifetime.
+func (c Config) MempoolOptions() []types.MempoolOption {
+	opts := []types.MempoolOption{types.WithExport(c.ExportPath, c.ExportFormat)}
+	if c.MinFeePerGas > 0 {
+		opts = append(opts, types.WithMinFeePerGas(c.MinFeePerGas))
+	}
+	if c.StatusIndexSize > 0 {
+		opts = append(opts, types.WithStatusIndex(c.StatusIndexSize, c.StatusIndexTTL))
+	}
+	return opts
+}
@@ -0,0 +1,172 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"mempool/pkg/config"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	want := config.Default()
+	want.MaxMempoolSize = 5000
+	want.Shards = 4
+	want.MinFeePerGas = 0.5
+	want.SnapshotPath = "pool.snap"
+	want.SnapshotInterval = 30 * time.Second
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "yaml",
+			file:    "mempool.yaml",
+			content: "maxMempoolSize: 5000\nshards: 4\nminFeePerGas: 0.5\nsnapshotPath: pool.snap\nsnapshotInterval: 30s\n",
+		},
+		{
+			name:    "json",
+			file:    "mempool.json",
+			content: `{"maxMempoolSize": 5000, "shards": 4, "minFeePerGas": 0.5, "snapshotPath": "pool.snap", "snapshotInterval": "30s"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := config.Load(writeFile(t, tc.file, tc.content))
+			require.NoError(t, err)
+			assert.Equal(t, want, cfg)
+			assert.NoError(t, cfg.Validate())
+		})
+	}
+}
+
+func TestLoad_Empty(t *testing.T) {
+	cfg, err := config.Load(writeFile(t, "mempool.yaml", ""))
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		content  string
+		contains string
+	}{
+		{name: "unknown_key", content: "maxMempoolSize: 10\nmaxPoolSize: 10\n", contains: "line 2: field maxPoolSize not found"},
+		{name: "not_a_number", content: "maxMempoolSize: lots\n", contains: "unmarshal errors: line 1: cannot unmarshal !!str `lots` into uint32"},
+		{name: "several_errors", content: "maxMempoolSize: lots\nshards: many\n", contains: "into uint32; line 2: cannot unmarshal !!str `many` into int"},
+		{name: "out_of_range", content: "maxMempoolSize: 5000000000\n", contains: "line 1: cannot unmarshal !!int `5000000000` into uint32"},
+		{name: "bad_duration", content: "snapshotInterval: often\n", contains: "line 1"},
+		{name: "not_yaml", content: "{maxMempoolSize: [\n", contains: "mempool.yaml"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := config.Load(writeFile(t, "mempool.yaml", tc.content))
+			require.ErrorIs(t, err, config.ErrInvalidConfig)
+			assert.Contains(t, err.Error(), tc.contains)
+		})
+	}
+
+	_, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestValidate(t *testing.T) {
+	valid := config.Default()
+	valid.MaxMempoolSize = 10
+	for _, tc := range []struct {
+		name     string
+		modify   func(*config.Config)
+		contains []string
+	}{
+		{name: "missing_size", modify: func(c *config.Config) { c.MaxMempoolSize = 0 }, contains: []string{"maxMempoolSize (MAX_MEMPOOL_SIZE) is required"}},
+		{name: "negative_shards", modify: func(c *config.Config) { c.Shards = -1 }, contains: []string{"shards (MEMPOOL_SHARDS) is -1"}},
+		{name: "unknown_formats", modify: func(c *config.Config) { c.InputFormat, c.ExportFormat = "xml", "xml" }, contains: []string{"inputFormat (INPUT_FORMAT)", "exportFormat (EXPORT_FORMAT)"}},
+		{name: "unknown_wal_sync", modify: func(c *config.Config) { c.WALSyncPolicy = "sometimes" }, contains: []string{"walSyncPolicy (WAL_SYNC_POLICY)"}},
+		{name: "unknown_log_level", modify: func(c *config.Config) { c.LogLevel = "loud" }, contains: []string{`logLevel (LOG_LEVEL) is "loud"`}},
+		{name: "interval_without_snapshot", modify: func(c *config.Config) { c.SnapshotInterval = time.Minute }, contains: []string{"snapshotInterval (SNAPSHOT_INTERVAL) requires snapshotPath"}},
+		{
+			name:     "every_problem_reported",
+			modify:   func(c *config.Config) { c.MaxMempoolSize, c.MinFeePerGas, c.StatusIndexTTL = 0, -1, -time.Second },
+			contains: []string{"maxMempoolSize", "minFeePerGas (MIN_FEE_PER_GAS) is -1", "statusIndexTTL (STATUS_INDEX_TTL) is -1s"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := valid
+			tc.modify(&cfg)
+			err := cfg.Validate()
+			require.ErrorIs(t, err, config.ErrInvalidConfig)
+			for _, s := range tc.contains {
+				assert.Contains(t, err.Error(), s)
+			}
+		})
+	}
+	assert.NoError(t, valid.Validate())
+}
+
+func TestLevel(t *testing.T) {
+	cfg := config.Default()
+	level, err := cfg.Level()
+	require.NoError(t, err)
+	assert.Equal(t, logging.DefaultLevel(), level)
+
+	cfg.LogLevel = "warn"
+	level, err = cfg.Level()
+	require.NoError(t, err)
+	assert.Equal(t, zapcore.WarnLevel, level)
+}
+
+func TestDiff(t *testing.T) {
+	running := config.Default()
+	running.MaxMempoolSize = 10
+	running.WALDir = "wal"
+	next := running
+	assert.Empty(t, config.Diff(running, next))
+
+	next.MaxMempoolSize = 20
+	next.LogLevel = "debug"
+	next.WALDir = "other"
+	assert.Equal(t, []config.Change{
+		{Key: "maxMempoolSize", Old: uint32(10), New: uint32(20), Reloadable: true},
+		{Key: "walDir", Old: "wal", New: "other"},
+		{Key: "logLevel", Old: "", New: "debug", Reloadable: true},
+	}, config.Diff(running, next))
+}
+
+func TestMempoolOptions(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	cfg := config.Default()
+	cfg.MaxMempoolSize = 10
+	cfg.MinFeePerGas = 2
+	cfg.StatusIndexSize = 10
+	cfg.ExportPath = filepath.Join(t.TempDir(), "export.csv")
+	cfg.ExportFormat = types.FormatCSV
+
+	mempool, err := types.NewMempool(cfg.MaxMempoolSize, logger, cfg.MempoolOptions()...)
+	require.NoError(t, err)
+	errs := mempool.AddTxs([]*types.Tx{types.NewTx(logger, "0xa", "0xs", 1, 1), types.NewTx(logger, "0xb", "0xs", 1, 3)})
+	require.ErrorIs(t, errs[0], types.ErrBelowMinFee)
+	require.NoError(t, errs[1])
+	_, known := mempool.TxStatus("0xa")
+	assert.True(t, known, "the status index should remember the rejected transaction")
+
+	result, err := mempool.ExportToFile()
+	require.NoError(t, err)
+	assert.Equal(t, cfg.ExportPath, result.Path)
+	data, err := os.ReadFile(cfg.ExportPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "tx_hash,")
+}
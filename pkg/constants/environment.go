@@ -5,4 +5,19 @@ const (
 	ENV_TRANSACTIONS_FILE_PATH = "TRANSACTIONS_FILE_PATH"
 	ENV_MAX_MEMPOOL_SIZE       = "MAX_MEMPOOL_SIZE"
 	PRIORITIZED_TX_FILE_PATH   = "PRIORITIZED_TX_FILE_PATH"
+	ENV_WAL_DIR                = "WAL_DIR"
+	ENV_WAL_SYNC_POLICY        = "WAL_SYNC_POLICY"
+	ENV_SNAPSHOT_PATH          = "SNAPSHOT_PATH"
+	ENV_SNAPSHOT_INTERVAL      = "SNAPSHOT_INTERVAL"
+	ENV_EXPORT_FORMAT          = "EXPORT_FORMAT"
+	ENV_INPUT_FORMAT           = "INPUT_FORMAT"
+	ENV_REJECTS_FILE_PATH      = "REJECTS_FILE_PATH"
+	ENV_MEMPOOL_SHARDS         = "MEMPOOL_SHARDS"
+	ENV_ADMIN_ADDR             = "ADMIN_ADDR"
+	ENV_MIN_FEE_PER_GAS        = "MIN_FEE_PER_GAS"
+	ENV_STATUS_INDEX_SIZE      = "STATUS_INDEX_SIZE"
+	ENV_STATUS_INDEX_TTL       = "STATUS_INDEX_TTL"
+	ENV_CONFIG_FILE            = "MEMPOOL_CONFIG"
+	ENV_LOG_LEVEL              = "LOG_LEVEL"
+	ENV_CONFIG_WATCH_INTERVAL  = "MEMPOOL_CONFIG_WATCH"
 )
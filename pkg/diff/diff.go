@@ -0,0 +1,159 @@
+// Package diff compares two prioritized exports to show which transactions entered, left or moved
+// within the exported set.
+package diff
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/types"
+)
+
+var ErrDuplicateTx = errors.New("transaction appears more than once in an export")
+
+// Entry describes one transaction of either export. Ranks start at 1, the highest priority; a zero
+// rank or fee means the transaction is absent from that export.
+type Entry struct {
+	TxHash      string  `json:"txHash"`
+	OldRank     int     `json:"oldRank,omitempty"`
+	NewRank     int     `json:"newRank,omitempty"`
+	OldTotalFee float64 `json:"oldTotalFee,omitempty"`
+	NewTotalFee float64 `json:"newTotalFee,omitempty"`
+}
+
+// RankDelta is how many places the transaction moved up; negative when it moved down.
+func (e Entry) RankDelta() int {
+	return e.OldRank - e.NewRank
+}
+
+// FeeDelta is the change of the transaction's total fee between the exports.
+func (e Entry) FeeDelta() float64 {
+	return e.NewTotalFee - e.OldTotalFee
+}
+
+// Summary aggregates a comparison.
+type Summary struct {
+	Old           int     `json:"old"`        // Transactions in the old export
+	New           int     `json:"new"`        // Transactions in the new export
+	Common        int     `json:"common"`     // Transactions in both
+	Added         int     `json:"added"`      // Only in the new export
+	Removed       int     `json:"removed"`    // Only in the old export
+	Moved         int     `json:"moved"`      // In both at a different rank
+	FeeChanged    int     `json:"feeChanged"` // In both with a different total fee
+	MaxRankShift  int     `json:"maxRankShift"`
+	MeanRankShift float64 `json:"meanRankShift"` // Mean absolute rank change of common transactions
+	OldTotalFee   float64 `json:"oldTotalFee"`
+	NewTotalFee   float64 `json:"newTotalFee"`
+	OldMinFee     float64 `json:"oldMinFee"` // Lowest total fee that made it into the old export
+	NewMinFee     float64 `json:"newMinFee"`
+}
+
+// Result lists the differences between two exports. Added and Changed are in new rank order,
+// Removed in old rank order.
+type Result struct {
+	Added   []Entry `json:"added"`
+	Removed []Entry `json:"removed"`
+	Changed []Entry `json:"changed"` // In both with a different rank or total fee
+	Summary Summary `json:"summary"`
+}
+
+// Read decodes every transaction of an export in order, so a transaction's rank is its position.
+func Read(r io.Reader, format types.ExportFormat) ([]*types.Tx, error) {
+	reader := format.NewReader(r)
+	var txs []*types.Tx
+	for {
+		tx, err := reader.ReadTx()
+		if err == io.EOF {
+			return txs, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read record %d", len(txs)+1)
+		}
+		txs = append(txs, tx)
+	}
+}
+
+// Compare reports how the ranked transactions of newTxs differ from those of oldTxs.
+func Compare(oldTxs, newTxs []*types.Tx) (Result, error) {
+	result := Result{Added: []Entry{}, Removed: []Entry{}, Changed: []Entry{}} // Empty lists encode as [] rather than null
+	oldRanks, err := ranks(oldTxs)
+	if err != nil {
+		return result, errors.Wrap(err, "old export")
+	}
+	newRanks, err := ranks(newTxs)
+	if err != nil {
+		return result, errors.Wrap(err, "new export")
+	}
+
+	summary := &result.Summary
+	summary.Old, summary.New = len(oldTxs), len(newTxs)
+	var totalShift int
+	for i, tx := range newTxs {
+		summary.NewTotalFee += tx.TotalFee
+		entry := Entry{TxHash: tx.TxHash, NewRank: i + 1, NewTotalFee: tx.TotalFee}
+		oldRank, ok := oldRanks[tx.TxHash]
+		if !ok {
+			result.Added = append(result.Added, entry)
+			continue
+		}
+		entry.OldRank, entry.OldTotalFee = oldRank, oldTxs[oldRank-1].TotalFee
+		summary.Common++
+		shift := abs(entry.RankDelta())
+		totalShift += shift
+		summary.MaxRankShift = max(summary.MaxRankShift, shift)
+		if shift != 0 {
+			summary.Moved++
+		}
+		if entry.FeeDelta() != 0 {
+			summary.FeeChanged++
+		}
+		if shift != 0 || entry.FeeDelta() != 0 {
+			result.Changed = append(result.Changed, entry)
+		}
+	}
+	for i, tx := range oldTxs {
+		summary.OldTotalFee += tx.TotalFee
+		if _, ok := newRanks[tx.TxHash]; !ok {
+			result.Removed = append(result.Removed, Entry{TxHash: tx.TxHash, OldRank: i + 1, OldTotalFee: tx.TotalFee})
+		}
+	}
+	summary.Added, summary.Removed = len(result.Added), len(result.Removed)
+	if summary.Common > 0 {
+		summary.MeanRankShift = float64(totalShift) / float64(summary.Common)
+	}
+	summary.OldMinFee, summary.NewMinFee = minFee(oldTxs), minFee(newTxs)
+	return result, nil
+}
+
+// ranks maps every hash to its 1-based position.
+func ranks(txs []*types.Tx) (map[string]int, error) {
+	ranks := make(map[string]int, len(txs))
+	for i, tx := range txs {
+		if first, ok := ranks[tx.TxHash]; ok {
+			return nil, errors.Wrapf(ErrDuplicateTx, "%s at ranks %d and %d", tx.TxHash, first, i+1)
+		}
+		ranks[tx.TxHash] = i + 1
+	}
+	return ranks, nil
+}
+
+// minFee returns the lowest total fee of txs, which is not necessarily the last one when the export
+// was not produced in priority order.
+func minFee(txs []*types.Tx) float64 {
+	if len(txs) == 0 {
+		return 0
+	}
+	fee := txs[0].TotalFee
+	for _, tx := range txs[1:] {
+		fee = min(fee, tx.TotalFee)
+	}
+	return fee
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
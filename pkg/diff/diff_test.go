@@ -0,0 +1,81 @@
+package diff_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/diff"
+	"mempool/pkg/types"
+)
+
+func tx(hash string, totalFee float64) *types.Tx {
+	return &types.Tx{TxHash: hash, Gas: 1, FeePerGas: totalFee, TotalFee: totalFee, Signature: "0xs"}
+}
+
+func TestCompare(t *testing.T) {
+	oldTxs := []*types.Tx{tx("0xa", 50), tx("0xb", 40), tx("0xc", 30), tx("0xd", 20)}
+	newTxs := []*types.Tx{tx("0xe", 60), tx("0xb", 45), tx("0xa", 50), tx("0xc", 30)}
+
+	result, err := diff.Compare(oldTxs, newTxs)
+	require.NoError(t, err)
+	assert.Equal(t, []diff.Entry{{TxHash: "0xe", NewRank: 1, NewTotalFee: 60}}, result.Added)
+	assert.Equal(t, []diff.Entry{{TxHash: "0xd", OldRank: 4, OldTotalFee: 20}}, result.Removed)
+	assert.Equal(t, []diff.Entry{
+		{TxHash: "0xb", OldRank: 2, NewRank: 2, OldTotalFee: 40, NewTotalFee: 45},
+		{TxHash: "0xa", OldRank: 1, NewRank: 3, OldTotalFee: 50, NewTotalFee: 50},
+		{TxHash: "0xc", OldRank: 3, NewRank: 4, OldTotalFee: 30, NewTotalFee: 30},
+	}, result.Changed)
+	assert.Equal(t, 5.0, result.Changed[0].FeeDelta())
+	assert.Equal(t, -2, result.Changed[1].RankDelta())
+	assert.Equal(t, diff.Summary{
+		Old: 4, New: 4, Common: 3, Added: 1, Removed: 1, Moved: 2, FeeChanged: 1,
+		MaxRankShift: 2, MeanRankShift: 1, OldTotalFee: 140, NewTotalFee: 185, OldMinFee: 20, NewMinFee: 30,
+	}, result.Summary)
+}
+
+func TestCompare_Identical(t *testing.T) {
+	txs := []*types.Tx{tx("0xa", 2), tx("0xb", 1)}
+	result, err := diff.Compare(txs, txs)
+	require.NoError(t, err)
+	assert.Empty(t, result.Added)
+	assert.Empty(t, result.Removed)
+	assert.Empty(t, result.Changed)
+	assert.Equal(t, 2, result.Summary.Common)
+}
+
+func TestCompare_DuplicateTx(t *testing.T) {
+	_, err := diff.Compare([]*types.Tx{tx("0xa", 2)}, []*types.Tx{tx("0xa", 2), tx("0xa", 2)})
+	assert.ErrorIs(t, err, diff.ErrDuplicateTx)
+}
+
+func TestRead(t *testing.T) {
+	want := []*types.Tx{tx("0xa", 2), tx("0xb", 1)}
+	for _, name := range types.ExportFormats() {
+		t.Run(name, func(t *testing.T) {
+			format, err := types.LookupExportFormat(name)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			w := format.NewWriter(&buf)
+			for _, tx := range want {
+				require.NoError(t, w.WriteTx(tx))
+			}
+			require.NoError(t, w.Close())
+
+			got, err := diff.Read(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, got, len(want))
+			for i := range want {
+				assert.Equal(t, want[i].TxHash, got[i].TxHash)
+				assert.Equal(t, want[i].TotalFee, got[i].TotalFee)
+			}
+		})
+	}
+
+	text, err := types.LookupExportFormat(types.FormatText)
+	require.NoError(t, err)
+	_, err = diff.Read(bytes.NewBufferString("TxHash=0xa\n"), text)
+	assert.ErrorIs(t, err, types.ErrMalformedRecord)
+}
@@ -0,0 +1,377 @@
+// Package generator produces synthetic transaction files for load testing and benchmarks.
+package generator
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/ingest"
+	"mempool/pkg/types"
+)
+
+// Fee distributions of FeePerGas.
+const (
+	FeeUniform   = "uniform"   // Uniform between FeeMin and FeeMax
+	FeeLogNormal = "lognormal" // exp(N(FeeMu, FeeSigma)), the usual shape of real fee markets
+	FeePowerLaw  = "powerlaw"  // Pareto with scale FeeMin and shape FeeAlpha: many cheap, a few very expensive
+)
+
+// Sender patterns decide which sender submits each transaction.
+const (
+	SendersUniform    = "uniform"     // Every sender is equally likely
+	SendersZipf       = "zipf"        // A few senders submit most transactions
+	SendersRoundRobin = "round-robin" // Senders take turns
+)
+
+const (
+	zipfExponent      = 1.1
+	significantDigits = 6    // Generated fees are rounded so they survive a text round trip unchanged
+	recentTxs         = 4096 // Duplicates repeat one of this many most recent transactions, like resubmissions
+)
+
+var ErrInvalidConfig = errors.New("invalid generator configuration")
+
+// Config describes the transactions to generate. The zero value of every field except Count has a
+// usable default, see DefaultConfig.
+type Config struct {
+	Count           int     // Records to write, including duplicates and malformed records
+	Seed            int64   // The same seed and configuration always produce the same output
+	FeeDistribution string  // FeeUniform, FeeLogNormal or FeePowerLaw
+	FeeMin          float64 // Lower bound for FeeUniform, scale for FeePowerLaw
+	FeeMax          float64 // Upper bound for FeeUniform
+	FeeMu           float64 // Mean of ln(FeePerGas) for FeeLogNormal
+	FeeSigma        float64 // Standard deviation of ln(FeePerGas) for FeeLogNormal
+	FeeAlpha        float64 // Shape of FeePowerLaw; smaller is heavier tailed
+	GasMin          float64 // Gas is a uniform integer between GasMin and GasMax
+	GasMax          float64
+	DuplicateRatio  float64 // Fraction of records that repeat an earlier transaction
+	MalformedRatio  float64 // Fraction of records that fail to parse
+	Senders         int     // Number of distinct senders, 0 leaves Sender empty
+	SenderPattern   string  // SendersUniform, SendersZipf or SendersRoundRobin
+}
+
+// DefaultConfig returns a configuration for count well-formed, unique transactions with
+// log-normally distributed fees.
+func DefaultConfig(count int) Config {
+	return Config{
+		Count:           count,
+		Seed:            1,
+		FeeDistribution: FeeLogNormal,
+		FeeMin:          0.1,
+		FeeMax:          100,
+		FeeMu:           0,
+		FeeSigma:        1,
+		FeeAlpha:        1.5,
+		GasMin:          21000,
+		GasMax:          100000,
+		Senders:         100,
+		SenderPattern:   SendersZipf,
+	}
+}
+
+// Validate reports the first problem with the configuration.
+func (c Config) Validate() error {
+	switch {
+	case c.Count < 0:
+		return errors.Wrapf(ErrInvalidConfig, "count %d is negative", c.Count)
+	case c.GasMin <= 0 || c.GasMax < c.GasMin:
+		return errors.Wrapf(ErrInvalidConfig, "gas range [%v, %v] must be positive and ordered", c.GasMin, c.GasMax)
+	case c.DuplicateRatio < 0 || c.MalformedRatio < 0 || c.DuplicateRatio+c.MalformedRatio > 1:
+		return errors.Wrapf(ErrInvalidConfig, "duplicate ratio %v and malformed ratio %v must be non-negative and sum to at most 1", c.DuplicateRatio, c.MalformedRatio)
+	case c.Senders < 0:
+		return errors.Wrapf(ErrInvalidConfig, "senders %d is negative", c.Senders)
+	}
+	switch c.FeeDistribution {
+	case FeeUniform:
+		if c.FeeMin <= 0 || c.FeeMax < c.FeeMin {
+			return errors.Wrapf(ErrInvalidConfig, "uniform fee range [%v, %v] must be positive and ordered", c.FeeMin, c.FeeMax)
+		}
+	case FeeLogNormal:
+		if c.FeeSigma < 0 {
+			return errors.Wrapf(ErrInvalidConfig, "log-normal sigma %v is negative", c.FeeSigma)
+		}
+	case FeePowerLaw:
+		if c.FeeMin <= 0 || c.FeeAlpha <= 0 {
+			return errors.Wrapf(ErrInvalidConfig, "power-law scale %v and shape %v must be positive", c.FeeMin, c.FeeAlpha)
+		}
+	default:
+		return errors.Wrapf(ErrInvalidConfig, "unknown fee distribution %q (available: %s, %s, %s)", c.FeeDistribution, FeeUniform, FeeLogNormal, FeePowerLaw)
+	}
+	switch c.SenderPattern {
+	case SendersUniform, SendersZipf, SendersRoundRobin:
+	default:
+		if c.Senders > 0 {
+			return errors.Wrapf(ErrInvalidConfig, "unknown sender pattern %q (available: %s, %s, %s)", c.SenderPattern, SendersUniform, SendersZipf, SendersRoundRobin)
+		}
+	}
+	return nil
+}
+
+// malformedKind is the mistake a malformed record makes, rendered in the syntax of each format.
+type malformedKind int
+
+const (
+	missingField malformedKind = iota
+	invalidNumber
+	notARecord
+)
+
+// Record is one generated record.
+type Record struct {
+	Tx        *types.Tx // nil for a malformed record
+	Duplicate bool      // Tx repeats a recent record
+	malformed malformedKind
+	id        string // Hash used by a malformed record
+}
+
+// Generator produces a reproducible stream of records. Each sender's transactions use consecutive
+// nonces, and a transaction's hash is derived from the seed, its sender and its nonce, so the same
+// sender and nonce always map to the same hash.
+type Generator struct {
+	cfg    Config
+	rng    *rand.Rand
+	zipf   *rand.Zipf
+	nonces []uint64
+	recent []*types.Tx // Ring of the most recent well-formed transactions, candidates for duplicates
+	unique int         // Well-formed transactions generated so far
+	next   int         // Records generated so far
+}
+
+// New returns a generator for cfg.
+func New(cfg Config) (*Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	g := &Generator{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), nonces: make([]uint64, max(cfg.Senders, 1))}
+	if cfg.Senders > 1 && cfg.SenderPattern == SendersZipf {
+		g.zipf = rand.NewZipf(g.rng, zipfExponent, 1, uint64(cfg.Senders-1))
+	}
+	return g, nil
+}
+
+// Next returns the next record, or false once Count records have been generated.
+func (g *Generator) Next() (Record, bool) {
+	if g.next == g.cfg.Count {
+		return Record{}, false
+	}
+	g.next++
+	switch roll := g.rng.Float64(); {
+	case roll < g.cfg.MalformedRatio:
+		return Record{malformed: malformedKind(g.rng.Intn(3)), id: "0xmalformed" + strconv.Itoa(g.next)}, true
+	case roll < g.cfg.MalformedRatio+g.cfg.DuplicateRatio && len(g.recent) > 0:
+		return Record{Tx: g.recent[g.rng.Intn(len(g.recent))], Duplicate: true}, true
+	}
+	tx := g.transaction()
+	if len(g.recent) < recentTxs {
+		g.recent = append(g.recent, tx)
+	} else {
+		g.recent[g.unique%recentTxs] = tx
+	}
+	g.unique++
+	return Record{Tx: tx}, true
+}
+
+func (g *Generator) transaction() *types.Tx {
+	sender := g.sender()
+	nonce := g.nonces[sender]
+	g.nonces[sender]++
+	id := sha256.Sum256(binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, uint64(g.cfg.Seed)), uint64(sender)), nonce))
+	tx := &types.Tx{
+		TxHash:    "0x" + hex.EncodeToString(id[:]),
+		Gas:       math.Min(g.cfg.GasMin+math.Floor(g.rng.Float64()*(g.cfg.GasMax-g.cfg.GasMin+1)), g.cfg.GasMax),
+		FeePerGas: round(g.fee()),
+		Signature: "0x" + hex.EncodeToString(id[16:]) + hex.EncodeToString(id[:16]),
+	}
+	if g.cfg.Senders > 0 {
+		tx.Sender = senderAddress(g.cfg.Seed, sender)
+	}
+	return tx
+}
+
+func (g *Generator) sender() int {
+	switch {
+	case g.cfg.Senders <= 1:
+		return 0
+	case g.zipf != nil:
+		return int(g.zipf.Uint64())
+	case g.cfg.SenderPattern == SendersRoundRobin:
+		return g.next % g.cfg.Senders
+	}
+	return g.rng.Intn(g.cfg.Senders)
+}
+
+func (g *Generator) fee() float64 {
+	switch g.cfg.FeeDistribution {
+	case FeeUniform:
+		return g.cfg.FeeMin + g.rng.Float64()*(g.cfg.FeeMax-g.cfg.FeeMin)
+	case FeePowerLaw:
+		return g.cfg.FeeMin / math.Pow(1-g.rng.Float64(), 1/g.cfg.FeeAlpha)
+	}
+	return math.Exp(g.cfg.FeeMu + g.cfg.FeeSigma*g.rng.NormFloat64())
+}
+
+// senderAddress returns a stable 20 byte address for sender index i.
+func senderAddress(seed int64, i int) string {
+	id := sha256.Sum256(binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64([]byte("sender"), uint64(seed)), uint64(i)))
+	return "0x" + hex.EncodeToString(id[:20])
+}
+
+// round keeps significantDigits significant digits of a positive fee, which never rounds to zero.
+func round(f float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', significantDigits, 64), 64)
+	return math.Max(rounded, math.SmallestNonzeroFloat64)
+}
+
+// Summary counts the records written by Write.
+type Summary struct {
+	Records   int `json:"records"`
+	Unique    int `json:"unique"`
+	Duplicate int `json:"duplicate"`
+	Malformed int `json:"malformed"`
+}
+
+// Write generates cfg.Count records and writes them to w in the named input format (ingest.FormatKeyValue,
+// ingest.FormatJSONL or ingest.FormatCSV), so the output can be read back by the matching ingest reader.
+func Write(w io.Writer, format string, cfg Config) (Summary, error) {
+	var summary Summary
+	g, err := New(cfg)
+	if err != nil {
+		return summary, err
+	}
+	rw, err := newRecordWriter(w, format)
+	if err != nil {
+		return summary, err
+	}
+	for record, ok := g.Next(); ok; record, ok = g.Next() {
+		if err := rw.write(record); err != nil {
+			return summary, errors.Wrap(err, "failed to write generated transactions")
+		}
+		summary.Records++
+		switch {
+		case record.Tx == nil:
+			summary.Malformed++
+		case record.Duplicate:
+			summary.Duplicate++
+		default:
+			summary.Unique++
+		}
+	}
+	return summary, errors.Wrap(rw.flush(), "failed to write generated transactions")
+}
+
+type recordWriter struct {
+	format string
+	w      *bufio.Writer
+	csv    *csv.Writer
+}
+
+func newRecordWriter(w io.Writer, format string) (*recordWriter, error) {
+	rw := &recordWriter{format: format, w: bufio.NewWriter(w)}
+	switch format {
+	case "", ingest.FormatKeyValue, ingest.FormatJSONL:
+	case ingest.FormatCSV:
+		rw.csv = csv.NewWriter(rw.w)
+		if err := rw.csv.Write([]string{"tx_hash", "gas", "fee_per_gas", "signature", "sender"}); err != nil {
+			return nil, errors.Wrap(err, "failed to write CSV header")
+		}
+	default:
+		return nil, errors.Wrapf(ingest.ErrUnknownFormat, "%q (available: %s, %s, %s)", format, ingest.FormatKeyValue, ingest.FormatJSONL, ingest.FormatCSV)
+	}
+	return rw, nil
+}
+
+// jsonRecord uses the field names read by the JSON Lines ingest reader.
+type jsonRecord struct {
+	TxHash    string  `json:"txHash"`
+	Gas       float64 `json:"gas"`
+	FeePerGas float64 `json:"feePerGas"`
+	Signature string  `json:"signature"`
+	Sender    string  `json:"sender,omitempty"`
+}
+
+func (rw *recordWriter) write(record Record) error {
+	tx := record.Tx
+	switch rw.format {
+	case ingest.FormatJSONL:
+		if tx == nil {
+			_, err := rw.w.WriteString(malformedJSON(record) + "\n")
+			return err
+		}
+		line, err := json.Marshal(jsonRecord{TxHash: tx.TxHash, Gas: tx.Gas, FeePerGas: tx.FeePerGas, Signature: tx.Signature, Sender: tx.Sender})
+		if err != nil {
+			return err
+		}
+		_, err = rw.w.Write(append(line, '\n'))
+		return err
+	case ingest.FormatCSV:
+		if tx == nil {
+			return rw.csv.Write(malformedCSV(record))
+		}
+		return rw.csv.Write([]string{tx.TxHash, formatFloat(tx.Gas), formatFloat(tx.FeePerGas), tx.Signature, tx.Sender})
+	}
+	if tx == nil {
+		_, err := rw.w.WriteString(malformedKeyValue(record) + "\n")
+		return err
+	}
+	line := ingest.KeyTxHash + "=" + tx.TxHash + " " + ingest.KeyGas + "=" + formatFloat(tx.Gas) + " " +
+		ingest.KeyFeePerGas + "=" + formatFloat(tx.FeePerGas) + " " + ingest.KeySignature + "=" + tx.Signature
+	if tx.Sender != "" {
+		line += " " + ingest.KeySender + "=" + tx.Sender
+	}
+	_, err := rw.w.WriteString(line + "\n")
+	return err
+}
+
+func malformedKeyValue(record Record) string {
+	switch record.malformed {
+	case missingField:
+		return ingest.KeyTxHash + "=" + record.id + " " + ingest.KeyGas + "=21000 " + ingest.KeyFeePerGas + "=1"
+	case invalidNumber:
+		return ingest.KeyTxHash + "=" + record.id + " " + ingest.KeyGas + "=lots " + ingest.KeyFeePerGas + "=1 " + ingest.KeySignature + "=0xs"
+	}
+	return record.id + " 21000 1 0xs" // Positional fields
+}
+
+func malformedJSON(record Record) string {
+	switch record.malformed {
+	case missingField:
+		return `{"txHash":"` + record.id + `","gas":21000,"feePerGas":1}`
+	case invalidNumber:
+		return `{"txHash":"` + record.id + `","gas":"lots","feePerGas":1,"signature":"0xs"}`
+	}
+	return `{"txHash":"` + record.id + `",` // Truncated object
+}
+
+func malformedCSV(record Record) []string {
+	switch record.malformed {
+	case missingField:
+		return []string{record.id, "21000", "", "0xs", ""}
+	case invalidNumber:
+		return []string{record.id, "lots", "1", "0xs", ""}
+	}
+	return []string{record.id} // Too few columns
+}
+
+func (rw *recordWriter) flush() error {
+	if rw.csv != nil {
+		rw.csv.Flush()
+		if err := rw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return rw.w.Flush()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,167 @@
+package generator_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/generator"
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+)
+
+func TestWriteIsReproducible(t *testing.T) {
+	cfg := generator.DefaultConfig(500)
+	cfg.DuplicateRatio, cfg.MalformedRatio = 0.1, 0.05
+	var first, second bytes.Buffer
+	_, err := generator.Write(&first, ingest.FormatKeyValue, cfg)
+	require.NoError(t, err)
+	_, err = generator.Write(&second, ingest.FormatKeyValue, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, first.String(), second.String())
+
+	cfg.Seed++
+	var reseeded bytes.Buffer
+	_, err = generator.Write(&reseeded, ingest.FormatKeyValue, cfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.String(), reseeded.String())
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, format := range []string{ingest.FormatKeyValue, ingest.FormatJSONL, ingest.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			cfg := generator.DefaultConfig(2000)
+			cfg.DuplicateRatio, cfg.MalformedRatio = 0.2, 0.1
+			var buf bytes.Buffer
+			summary, err := generator.Write(&buf, format, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, cfg.Count, summary.Records)
+			assert.Equal(t, summary.Records, summary.Unique+summary.Duplicate+summary.Malformed)
+			assert.InDelta(t, 0.2, float64(summary.Duplicate)/float64(summary.Records), 0.05)
+			assert.InDelta(t, 0.1, float64(summary.Malformed)/float64(summary.Records), 0.05)
+
+			reader, err := ingest.NewReader(format, &buf, logger)
+			require.NoError(t, err)
+			hashes := make(map[string]struct{})
+			senders := make(map[string]struct{})
+			var parsed, malformed int
+			for {
+				tx, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				var lineErr *ingest.LineError
+				if errors.As(err, &lineErr) {
+					malformed++
+					continue
+				}
+				require.NoError(t, err)
+				parsed++
+				hashes[tx.TxHash] = struct{}{}
+				senders[tx.Sender] = struct{}{}
+				assert.GreaterOrEqual(t, tx.Gas, cfg.GasMin)
+				assert.LessOrEqual(t, tx.Gas, cfg.GasMax)
+				assert.Positive(t, tx.FeePerGas)
+			}
+			assert.Equal(t, summary.Malformed, malformed)
+			assert.Equal(t, summary.Unique+summary.Duplicate, parsed)
+			assert.Len(t, hashes, summary.Unique)
+			assert.LessOrEqual(t, len(senders), cfg.Senders)
+			assert.NotContains(t, senders, "")
+		})
+	}
+}
+
+func TestFeeDistributions(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*generator.Config)
+		check  func(t *testing.T, fee float64)
+	}{
+		{
+			name:   "uniform",
+			modify: func(c *generator.Config) { c.FeeDistribution, c.FeeMin, c.FeeMax = generator.FeeUniform, 2, 3 },
+			check: func(t *testing.T, fee float64) {
+				assert.GreaterOrEqual(t, fee, 2.0)
+				assert.LessOrEqual(t, fee, 3.0)
+			},
+		},
+		{
+			name:   "power_law",
+			modify: func(c *generator.Config) { c.FeeDistribution, c.FeeMin, c.FeeAlpha = generator.FeePowerLaw, 5, 1.2 },
+			check:  func(t *testing.T, fee float64) { assert.GreaterOrEqual(t, fee, 5.0) },
+		},
+		{
+			name:   "log_normal",
+			modify: func(c *generator.Config) { c.FeeDistribution, c.FeeMu, c.FeeSigma = generator.FeeLogNormal, 1, 0 },
+			check:  func(t *testing.T, fee float64) { assert.InDelta(t, 2.71828, fee, 1e-5) },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := generator.DefaultConfig(1000)
+			tc.modify(&cfg)
+			g, err := generator.New(cfg)
+			require.NoError(t, err)
+			for record, ok := g.Next(); ok; record, ok = g.Next() {
+				tc.check(t, record.Tx.FeePerGas)
+			}
+		})
+	}
+}
+
+func TestSenderPatterns(t *testing.T) {
+	counts := func(pattern string) map[string]int {
+		cfg := generator.DefaultConfig(1000)
+		cfg.Senders, cfg.SenderPattern = 10, pattern
+		g, err := generator.New(cfg)
+		require.NoError(t, err)
+		senders := make(map[string]int)
+		for record, ok := g.Next(); ok; record, ok = g.Next() {
+			senders[record.Tx.Sender]++
+		}
+		return senders
+	}
+
+	roundRobin := counts(generator.SendersRoundRobin)
+	require.Len(t, roundRobin, 10)
+	for _, n := range roundRobin {
+		assert.Equal(t, 100, n)
+	}
+	var busiest int
+	for _, n := range counts(generator.SendersZipf) {
+		busiest = max(busiest, n)
+	}
+	assert.Greater(t, busiest, 300, "zipf senders should be dominated by a few senders")
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*generator.Config)
+	}{
+		{name: "negative_count", modify: func(c *generator.Config) { c.Count = -1 }},
+		{name: "empty_gas_range", modify: func(c *generator.Config) { c.GasMin, c.GasMax = 10, 5 }},
+		{name: "ratios_above_one", modify: func(c *generator.Config) { c.DuplicateRatio, c.MalformedRatio = 0.6, 0.5 }},
+		{name: "negative_senders", modify: func(c *generator.Config) { c.Senders = -1 }},
+		{name: "unknown_distribution", modify: func(c *generator.Config) { c.FeeDistribution = "gaussian" }},
+		{name: "uniform_without_range", modify: func(c *generator.Config) { c.FeeDistribution, c.FeeMin = generator.FeeUniform, 0 }},
+		{name: "power_law_without_shape", modify: func(c *generator.Config) { c.FeeDistribution, c.FeeAlpha = generator.FeePowerLaw, 0 }},
+		{name: "unknown_sender_pattern", modify: func(c *generator.Config) { c.SenderPattern = "bursty" }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := generator.DefaultConfig(10)
+			tc.modify(&cfg)
+			_, err := generator.New(cfg)
+			assert.ErrorIs(t, err, generator.ErrInvalidConfig)
+		})
+	}
+
+	_, err := generator.Write(io.Discard, "xml", generator.DefaultConfig(1))
+	assert.ErrorIs(t, err, ingest.ErrUnknownFormat)
+}
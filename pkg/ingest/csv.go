@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// csvColumns maps the CSV header names (as written by the CSV export) to key=value names.
+var csvColumns = map[string]string{
+	"tx_hash":     KeyTxHash,
+	"gas":         KeyGas,
+	"fee_per_gas": KeyFeePerGas,
+	"signature":   KeySignature,
+	"sender":      KeySender,
+	"total_fee":   "", // Accepted for re-importing exports, ignored because the pool recomputes it
+}
+
+// csvReader parses CSV input with a header row. Columns may appear in any order; an unknown or
+// repeated column in the header is a fatal error because no row could be interpreted.
+type csvReader struct {
+	r       *csv.Reader
+	logger  logging.LoggingSystem
+	columns []string // Key=value name per column, "" for ignored columns
+	line    int
+	err     error // Sticky header error, returned from every call to Next
+}
+
+func NewCSVReader(r io.Reader, logger logging.LoggingSystem) Reader {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	return &csvReader{r: cr, logger: logger}
+}
+
+func (cr *csvReader) Line() int {
+	return cr.line
+}
+
+func (cr *csvReader) Next() (*types.Tx, error) {
+	if cr.err != nil {
+		return nil, cr.err
+	}
+	if cr.columns == nil {
+		if cr.err = cr.readHeader(); cr.err != nil {
+			return nil, cr.err
+		}
+	}
+
+	row, err := cr.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			cr.line = parseErr.StartLine
+			return nil, &LineError{Line: cr.line, Err: errors.Wrapf(ErrMalformedLine, "%v", parseErr.Err)}
+		}
+		return nil, errors.Wrap(err, "failed to read CSV input")
+	}
+	cr.line, _ = cr.r.FieldPos(0)
+
+	values := make(map[string]string, 4)
+	for i, key := range cr.columns {
+		if key != "" {
+			values[key] = row[i]
+		}
+	}
+	tx, err := newTx(cr.logger, values)
+	if err != nil {
+		return nil, &LineError{Line: cr.line, Raw: strings.Join(row, ","), Err: err}
+	}
+	return tx, nil
+}
+
+func (cr *csvReader) readHeader() error {
+	header, err := cr.r.Read()
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read CSV header")
+	}
+	columns := make([]string, len(header))
+	seen := make(map[string]struct{}, len(header))
+	for i, name := range header {
+		key, ok := csvColumns[name]
+		if !ok {
+			return errors.Wrapf(ErrUnknownField, "CSV header column %q", name)
+		}
+		if _, dup := seen[name]; dup {
+			return errors.Wrapf(ErrDuplicateField, "CSV header column %q", name)
+		}
+		seen[name] = struct{}{}
+		columns[i] = key
+	}
+	cr.columns = columns
+	return nil
+}
@@ -0,0 +1,75 @@
+package ingest_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+)
+
+func TestCSVReader(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	input := strings.Join([]string{
+		"signature,fee_per_gas,tx_hash,gas,total_fee",
+		"0xs1,0.5,0xa,21000,10500",
+		"0xs2,cheap,0xb,21000,0",
+		"0xs3,0.25,0xc,100",
+		"0xs4,2,0xd,10,20",
+	}, "\n")
+	reader := ingest.NewCSVReader(strings.NewReader(input), logger)
+
+	tx, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "0xa", tx.TxHash)
+	assert.Equal(t, "0xs1", tx.Signature)
+	assert.Equal(t, 21000.0, tx.Gas)
+	assert.Equal(t, 0.5, tx.FeePerGas)
+
+	var lineErr *ingest.LineError
+	_, err = reader.Next()
+	require.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line)
+	assert.ErrorIs(t, err, ingest.ErrInvalidNumber)
+
+	_, err = reader.Next()
+	require.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 4, lineErr.Line)
+	assert.ErrorIs(t, err, ingest.ErrMalformedLine)
+
+	tx, err = reader.Next()
+	require.NoError(t, err, "reader should continue after a line error")
+	assert.Equal(t, "0xd", tx.TxHash)
+
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestCSVReader_HeaderErrors(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name    string
+		header  string
+		wantErr error
+	}{
+		{name: "unknown_column", header: "tx_hash,gas,fee_per_gas,signature,nonce", wantErr: ingest.ErrUnknownField},
+		{name: "duplicate_column", header: "tx_hash,gas,gas,fee_per_gas,signature", wantErr: ingest.ErrDuplicateField},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := ingest.NewCSVReader(strings.NewReader(tc.header+"\n0xa,1,1,1,0xs\n"), logger)
+			_, err := reader.Next()
+			require.ErrorIs(t, err, tc.wantErr)
+			var lineErr *ingest.LineError
+			assert.False(t, errors.As(err, &lineErr), "header errors are fatal, not line errors")
+			_, again := reader.Next()
+			assert.Equal(t, err, again, "header errors should be sticky")
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// jsonlReader parses one JSON object per line using the field names of the JSON Lines export,
+// so exported files can be fed back into a pool. An exported "totalFee" is accepted and ignored
+// because the pool recomputes it.
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	logger  logging.LoggingSystem
+	line    int
+}
+
+type jsonlRecord struct {
+	TxHash    *string  `json:"txHash"`
+	Gas       *float64 `json:"gas"`
+	FeePerGas *float64 `json:"feePerGas"`
+	Signature *string  `json:"signature"`
+	TotalFee  *float64 `json:"totalFee"`
+	Sender    *string  `json:"sender"`
+}
+
+func NewJSONLReader(r io.Reader, logger logging.LoggingSystem) Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &jsonlReader{scanner: scanner, logger: logger}
+}
+
+func (jr *jsonlReader) Line() int {
+	return jr.line
+}
+
+func (jr *jsonlReader) Next() (*types.Tx, error) {
+	for jr.scanner.Scan() {
+		jr.line++
+		raw := jr.scanner.Text()
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		tx, err := parseJSONLine(raw, jr.logger)
+		if err != nil {
+			return nil, &LineError{Line: jr.line, Raw: raw, Err: err}
+		}
+		return tx, nil
+	}
+	if err := jr.scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read line %d", jr.line+1)
+	}
+	return nil, io.EOF
+}
+
+func parseJSONLine(raw string, logger logging.LoggingSystem) (*types.Tx, error) {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var rec jsonlRecord
+	if err := dec.Decode(&rec); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return nil, errors.Wrap(ErrUnknownField, field)
+		}
+		return nil, errors.Wrapf(ErrMalformedLine, "%v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.Wrap(ErrMalformedLine, "unexpected data after JSON object")
+	}
+
+	values := make(map[string]string, 4)
+	if rec.TxHash != nil {
+		values[KeyTxHash] = *rec.TxHash
+	}
+	if rec.Signature != nil {
+		values[KeySignature] = *rec.Signature
+	}
+	if rec.Sender != nil {
+		values[KeySender] = *rec.Sender
+	}
+	if rec.Gas != nil {
+		values[KeyGas] = strconv.FormatFloat(*rec.Gas, 'g', -1, 64)
+	}
+	if rec.FeePerGas != nil {
+		values[KeyFeePerGas] = strconv.FormatFloat(*rec.FeePerGas, 'g', -1, 64)
+	}
+	return newTx(logger, values)
+}
@@ -0,0 +1,53 @@
+package ingest_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+)
+
+func TestJSONLReader(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name       string
+		line       string
+		wantSender string
+		wantErr    error
+	}{
+		{name: "success", line: `{"txHash":"0xa","gas":21000,"feePerGas":0.5,"signature":"0xs"}`},
+		{name: "success_with_sender", line: `{"txHash":"0xa","gas":21000,"feePerGas":0.5,"signature":"0xs","sender":"alice"}`, wantSender: "alice"},
+		{name: "success_exported_total_fee", line: `{"signature":"0xs","totalFee":10500,"feePerGas":0.5,"gas":21000,"txHash":"0xa"}`},
+		{name: "failure_missing_field", line: `{"txHash":"0xa","gas":21000,"feePerGas":0.5}`, wantErr: ingest.ErrMissingField},
+		{name: "failure_unknown_field", line: `{"txHash":"0xa","gas":21000,"feePerGas":0.5,"signature":"0xs","nonce":1}`, wantErr: ingest.ErrUnknownField},
+		{name: "failure_string_number", line: `{"txHash":"0xa","gas":"21000","feePerGas":0.5,"signature":"0xs"}`, wantErr: ingest.ErrMalformedLine},
+		{name: "failure_trailing_data", line: `{"txHash":"0xa","gas":21000,"feePerGas":0.5,"signature":"0xs"} {}`, wantErr: ingest.ErrMalformedLine},
+		{name: "failure_not_json", line: `TxHash=0xa`, wantErr: ingest.ErrMalformedLine},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := ingest.NewJSONLReader(strings.NewReader(tc.line+"\n"), logger)
+			tx, err := reader.Next()
+			if tc.wantErr != nil {
+				var lineErr *ingest.LineError
+				require.ErrorAs(t, err, &lineErr)
+				assert.Equal(t, 1, lineErr.Line)
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "0xa", tx.TxHash)
+				assert.Equal(t, 21000.0, tx.Gas)
+				assert.Equal(t, 0.5, tx.FeePerGas)
+				assert.Equal(t, "0xs", tx.Signature)
+				assert.Equal(t, tc.wantSender, tx.Sender)
+			}
+			_, err = reader.Next()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+const (
+	KeyTxHash    = types.KeyTxHash
+	KeyGas       = types.KeyGas
+	KeyFeePerGas = types.KeyFeePerGas
+	KeySignature = types.KeySignature
+	KeySender    = types.KeySender   // Optional
+	KeyTotalFee  = types.KeyTotalFee // Optional, written by the text export and ignored because the pool recomputes it
+)
+
+// keyValueReader parses whitespace separated "Key=Value" lines such as
+// "TxHash=0xabc Gas=21000 FeePerGas=0.5 Signature=0xdef". Fields may appear in any order;
+// every required key must appear exactly once, Sender and TotalFee may appear once and unknown keys
+// are rejected, so lines of the text export can be read back.
+type keyValueReader struct {
+	scanner *bufio.Scanner
+	logger  logging.LoggingSystem
+	line    int
+}
+
+func NewKeyValueReader(r io.Reader, logger logging.LoggingSystem) Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &keyValueReader{scanner: scanner, logger: logger}
+}
+
+func (kr *keyValueReader) Line() int {
+	return kr.line
+}
+
+func (kr *keyValueReader) Next() (*types.Tx, error) {
+	for kr.scanner.Scan() {
+		kr.line++
+		raw := kr.scanner.Text()
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		tx, err := ParseKeyValueLine(raw, kr.logger)
+		if err != nil {
+			return nil, &LineError{Line: kr.line, Raw: raw, Err: err}
+		}
+		return tx, nil
+	}
+	if err := kr.scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read line %d", kr.line+1)
+	}
+	return nil, io.EOF
+}
+
+// ParseKeyValueLine parses a single "Key=Value" transaction line with types.ParseKeyValueFields,
+// the parser the text export is read back with.
+func ParseKeyValueLine(line string, logger logging.LoggingSystem) (*types.Tx, error) {
+	values, err := types.ParseKeyValueFields(line)
+	if err != nil {
+		return nil, err
+	}
+	if totalFee, ok := values[KeyTotalFee]; ok {
+		if _, err := parseFloat(KeyTotalFee, totalFee); err != nil {
+			return nil, err
+		}
+	}
+	return newTx(logger, values)
+}
+
+// newTx builds a transaction from raw field values keyed by the key=value names.
+func newTx(logger logging.LoggingSystem, values map[string]string) (*types.Tx, error) {
+	for _, key := range []string{KeyTxHash, KeyGas, KeyFeePerGas, KeySignature} {
+		if _, ok := values[key]; !ok {
+			return nil, errors.Wrapf(ErrMissingField, "%q", key)
+		}
+	}
+	gas, err := parseFloat(KeyGas, values[KeyGas])
+	if err != nil {
+		return nil, err
+	}
+	feePerGas, err := parseFloat(KeyFeePerGas, values[KeyFeePerGas])
+	if err != nil {
+		return nil, err
+	}
+	tx := types.NewTx(logger, values[KeyTxHash], values[KeySignature], gas, feePerGas)
+	tx.Sender = values[KeySender]
+	return tx, nil
+}
+
+func parseFloat(key, value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidNumber, "%s=%q", key, value)
+	}
+	return f, nil
+}
@@ -0,0 +1,113 @@
+package ingest_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+func TestParseKeyValueLine(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name       string
+		line       string
+		wantSender string
+		wantErr    error
+	}{
+		{name: "success", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs"},
+		{name: "success_with_sender", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs Sender=alice", wantSender: "alice"},
+		{name: "success_any_order", line: "Signature=0xs FeePerGas=0.5 TxHash=0xa Gas=21000"},
+		{name: "success_text_export", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs TotalFee=10500"},
+		{name: "success_total_fee_ignored", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs TotalFee=1"},
+		{name: "success_extra_whitespace", line: "  TxHash=0xa\tGas=21000  FeePerGas=0.5 Signature=0xs  "},
+		{name: "failure_missing_field", line: "TxHash=0xa Gas=21000 FeePerGas=0.5", wantErr: ingest.ErrMissingField},
+		{name: "failure_unknown_field", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs Nonce=1", wantErr: ingest.ErrUnknownField},
+		{name: "failure_duplicate_field", line: "TxHash=0xa TxHash=0xb Gas=21000 FeePerGas=0.5 Signature=0xs", wantErr: ingest.ErrDuplicateField},
+		{name: "failure_duplicate_sender", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs Sender=a Sender=b", wantErr: ingest.ErrDuplicateField},
+		{name: "failure_not_key_value", line: "0xa 21000 0.5 0xs", wantErr: ingest.ErrMalformedLine},
+		{name: "failure_bad_gas", line: "TxHash=0xa Gas=lots FeePerGas=0.5 Signature=0xs", wantErr: ingest.ErrInvalidNumber},
+		{name: "failure_bad_fee", line: "TxHash=0xa Gas=21000 FeePerGas= Signature=0xs", wantErr: ingest.ErrInvalidNumber},
+		{name: "failure_empty_total_fee", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs TotalFee=", wantErr: ingest.ErrInvalidNumber},
+		{name: "failure_duplicate_total_fee", line: "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs TotalFee=1 TotalFee=2", wantErr: ingest.ErrDuplicateField},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tx, err := ingest.ParseKeyValueLine(tc.line, logger)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "0xa", tx.TxHash)
+			assert.Equal(t, 21000.0, tx.Gas)
+			assert.Equal(t, 0.5, tx.FeePerGas)
+			assert.Equal(t, "0xs", tx.Signature)
+			assert.Equal(t, tc.wantSender, tx.Sender)
+			assert.Zero(t, tx.TotalFee, "an exported total fee is not trusted; the pool computes it on admission")
+		})
+	}
+}
+
+func TestKeyValueReader_LineErrors(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	input := strings.Join([]string{
+		"TxHash=0xa Gas=1 FeePerGas=1 Signature=0xs",
+		"",
+		"TxHash=0xb Gas=oops FeePerGas=1 Signature=0xs",
+		"TxHash=0xc Gas=3 FeePerGas=1 Signature=0xs",
+	}, "\n")
+	reader := ingest.NewKeyValueReader(strings.NewReader(input), logger)
+
+	tx, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "0xa", tx.TxHash)
+
+	_, err = reader.Next()
+	var lineErr *ingest.LineError
+	require.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line, "blank lines still count towards line numbers")
+	assert.Equal(t, "TxHash=0xb Gas=oops FeePerGas=1 Signature=0xs", lineErr.Raw)
+	assert.ErrorIs(t, err, ingest.ErrInvalidNumber)
+
+	tx, err = reader.Next()
+	require.NoError(t, err, "reader should continue after a line error")
+	assert.Equal(t, "0xc", tx.TxHash)
+
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// TestKeyValueReader_ReadsTextExport checks that the text export can be read back.
+func TestKeyValueReader_ReadsTextExport(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	memPool, err := types.NewMempool(10, logger)
+	require.NoError(t, err)
+	for _, err := range memPool.AddTxs([]*types.Tx{types.NewTx(logger, "0xa", "0xs", 21000, 0.5), types.NewTx(logger, "0xb", "0xs", 3, 1.25)}) {
+		require.NoError(t, err)
+	}
+	var exported strings.Builder
+	_, err = memPool.ExportTo(&exported, types.ExportOptions{})
+	require.NoError(t, err)
+
+	reader := ingest.NewKeyValueReader(strings.NewReader(exported.String()), logger)
+	var got []string
+	for {
+		tx, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, fmt.Sprintf("%s %v %v", tx.TxHash, tx.Gas, tx.FeePerGas))
+	}
+	assert.Equal(t, []string{"0xa 21000 0.5", "0xb 3 1.25"}, got)
+}
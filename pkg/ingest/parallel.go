@@ -0,0 +1,284 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+const (
+	defaultChunkSize = 1 << 20 // 1 MiB of input per chunk
+)
+
+var (
+	ErrNotLineFormat = errors.New("input format cannot be split at line boundaries")
+	errStopped       = errors.New("parallel reader stopped")
+)
+
+// LineParser parses a single input line into a transaction.
+type LineParser func(line string, logger logging.LoggingSystem) (*types.Tx, error)
+
+// ParallelOptions tunes the parallel ingestion pipeline.
+type ParallelOptions struct {
+	Workers   int // Parsing goroutines, defaults to runtime.NumCPU()
+	ChunkSize int // Approximate bytes of input per chunk, defaults to 1 MiB
+}
+
+// Record is one non-blank input line: either a parsed transaction or the error that prevented parsing it.
+type Record struct {
+	Line int
+	Tx   *types.Tx
+	Err  *LineError
+}
+
+// Batch holds the records parsed from one chunk of input, in input order.
+type Batch struct {
+	Records []Record
+}
+
+type chunk struct {
+	index     int
+	firstLine int
+	data      []byte
+}
+
+type chunkResult struct {
+	index int
+	batch Batch
+}
+
+// LineParserFor returns the parser for a line-oriented format. CSV is not line-oriented because
+// quoted fields may span lines and rows depend on the header, so it cannot be split into chunks.
+func LineParserFor(format string) (LineParser, error) {
+	switch strings.ToLower(format) {
+	case "", FormatKeyValue:
+		return ParseKeyValueLine, nil
+	case FormatJSONL:
+		return parseJSONLine, nil
+	case FormatCSV:
+		return nil, errors.Wrapf(ErrNotLineFormat, "%q", format)
+	}
+	return nil, errors.Wrapf(ErrUnknownFormat, "%q", format)
+}
+
+// ReadParallel splits r into chunks at line boundaries, parses the chunks on opts.Workers goroutines
+// and calls fn with each chunk's batch strictly in input order. Line numbers in records refer to the
+// original input. Returning an error from fn stops the pipeline and ReadParallel returns that error.
+func ReadParallel(r io.Reader, parse LineParser, logger logging.LoggingSystem, opts ParallelOptions, fn func(Batch) error) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	stop := make(chan struct{})
+	chunks := make(chan chunk, workers)
+	results := make(chan chunkResult, workers)
+	inFlight := make(chan struct{}, 2*workers) // Bounds chunks read but not yet handed to fn
+	var readErr error
+
+	go func() {
+		defer close(chunks)
+		readErr = splitChunks(r, chunkSize, chunks, inFlight, stop)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				select {
+				case results <- chunkResult{index: c.index, batch: parseChunk(c, parse, logger)}:
+				case <-stop:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Reorder results so fn always sees chunks in input order.
+	pending := make(map[int]Batch)
+	next := 0
+	var fnErr error
+	for result := range results {
+		if fnErr != nil {
+			continue // Drain so workers can exit
+		}
+		pending[result.index] = result.batch
+		for {
+			batch, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-inFlight
+			if fnErr = fn(batch); fnErr != nil {
+				close(stop)
+				break
+			}
+		}
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	if readErr != nil && readErr != errStopped {
+		return readErr
+	}
+	return nil
+}
+
+// splitChunks reads r into chunks of roughly chunkSize bytes that always end at a line boundary.
+func splitChunks(r io.Reader, chunkSize int, chunks chan<- chunk, inFlight chan struct{}, stop <-chan struct{}) error {
+	br := bufio.NewReaderSize(r, chunkSize)
+	line := 1
+	for index := 0; ; index++ {
+		data := make([]byte, chunkSize)
+		n, err := io.ReadFull(br, data)
+		data = data[:n]
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			return errors.Wrapf(err, "failed to read input near line %d", line)
+		}
+		if !eof {
+			// Extend the chunk to the end of the current line.
+			rest, err := br.ReadBytes('\n')
+			data = append(data, rest...)
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return errors.Wrapf(err, "failed to read input near line %d", line)
+			}
+		}
+		if len(data) > 0 {
+			select {
+			case inFlight <- struct{}{}:
+			case <-stop:
+				return errStopped
+			}
+			select {
+			case chunks <- chunk{index: index, firstLine: line, data: data}:
+			case <-stop:
+				return errStopped
+			}
+			line += bytes.Count(data, []byte{'\n'})
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+func parseChunk(c chunk, parse LineParser, logger logging.LoggingSystem) Batch {
+	batch := Batch{Records: make([]Record, 0, bytes.Count(c.data, []byte{'\n'})+1)}
+	line := c.firstLine
+	data := c.data
+	for len(data) > 0 {
+		var raw []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			raw, data = data[:i], data[i+1:]
+		} else {
+			raw, data = data, nil
+		}
+		raw = bytes.TrimSuffix(raw, []byte{'\r'})
+		if len(bytes.TrimSpace(raw)) > 0 {
+			text := string(raw)
+			tx, err := parse(text, logger)
+			if err != nil {
+				batch.Records = append(batch.Records, Record{Line: line, Err: &LineError{Line: line, Raw: text, Err: err}})
+			} else {
+				batch.Records = append(batch.Records, Record{Line: line, Tx: tx})
+			}
+		}
+		line++
+	}
+	return batch
+}
+
+// ParallelReader adapts ReadParallel to the Reader interface. Close must be called if the caller
+// stops reading before Next returns io.EOF or a fatal error.
+type ParallelReader struct {
+	batches chan Batch
+	stop    chan struct{}
+	done    chan struct{}
+	err     error
+	current []Record
+	line    int
+	once    sync.Once
+}
+
+// NewParallelReader starts parsing r in the background using the parser for format.
+func NewParallelReader(r io.Reader, format string, logger logging.LoggingSystem, opts ParallelOptions) (*ParallelReader, error) {
+	parse, err := LineParserFor(format)
+	if err != nil {
+		return nil, err
+	}
+	pr := &ParallelReader{
+		batches: make(chan Batch, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(pr.done)
+		defer close(pr.batches)
+		pr.err = ReadParallel(r, parse, logger, opts, func(batch Batch) error {
+			select {
+			case pr.batches <- batch:
+				return nil
+			case <-pr.stop:
+				return errStopped
+			}
+		})
+	}()
+	return pr, nil
+}
+
+func (pr *ParallelReader) Line() int {
+	return pr.line
+}
+
+func (pr *ParallelReader) Next() (*types.Tx, error) {
+	for len(pr.current) == 0 {
+		batch, ok := <-pr.batches
+		if !ok {
+			<-pr.done
+			if pr.err != nil && pr.err != errStopped {
+				return nil, pr.err
+			}
+			return nil, io.EOF
+		}
+		pr.current = batch.Records
+	}
+	record := pr.current[0]
+	pr.current = pr.current[1:]
+	pr.line = record.Line
+	if record.Err != nil {
+		return nil, record.Err
+	}
+	return record.Tx, nil
+}
+
+// Close stops the background pipeline and waits for it to exit.
+func (pr *ParallelReader) Close() error {
+	pr.once.Do(func() { close(pr.stop) })
+	for range pr.batches {
+		// Drain so the pipeline is never blocked on a send
+	}
+	<-pr.done
+	return nil
+}
@@ -0,0 +1,158 @@
+package ingest_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+)
+
+func TestReadParallel_PreservesOrderAndLines(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+
+	var input strings.Builder
+	for i := 1; i <= 500; i++ {
+		switch {
+		case i%50 == 0:
+			fmt.Fprintf(&input, "malformed line %d\n", i)
+		case i%70 == 0:
+			input.WriteString("\r\n") // Blank lines are skipped but still counted
+		default:
+			fmt.Fprintf(&input, "TxHash=tx-%d Gas=%d FeePerGas=1 Signature=sig\r\n", i, i)
+		}
+	}
+	input.WriteString("TxHash=tx-last Gas=1 FeePerGas=1 Signature=sig") // No trailing newline
+
+	for _, opts := range []ingest.ParallelOptions{
+		{Workers: 1, ChunkSize: 1},
+		{Workers: 4, ChunkSize: 64},
+		{Workers: 8, ChunkSize: 1000},
+		{}, // Defaults: a single chunk
+	} {
+		t.Run(fmt.Sprintf("workers_%d_chunk_%d", opts.Workers, opts.ChunkSize), func(t *testing.T) {
+			var records []ingest.Record
+			err := ingest.ReadParallel(strings.NewReader(input.String()), ingest.ParseKeyValueLine, logger, opts, func(batch ingest.Batch) error {
+				records = append(records, batch.Records...)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, records, 500-6+1, "blank lines should not produce records") // Lines 70, 140, 210, 280, 420 and 490 are blank
+
+			prevLine := 0
+			for _, record := range records {
+				require.Greater(t, record.Line, prevLine, "records must arrive in input order")
+				prevLine = record.Line
+				switch {
+				case record.Line == 501:
+					require.NotNil(t, record.Tx)
+					assert.Equal(t, "tx-last", record.Tx.TxHash)
+				case record.Line%50 == 0:
+					require.NotNil(t, record.Err, "line %d should fail", record.Line)
+					assert.Equal(t, record.Line, record.Err.Line)
+					assert.Equal(t, fmt.Sprintf("malformed line %d", record.Line), record.Err.Raw)
+				default:
+					require.NotNil(t, record.Tx, "line %d should parse", record.Line)
+					assert.Equal(t, fmt.Sprintf("tx-%d", record.Line), record.Tx.TxHash, "line numbers must match the original input")
+				}
+			}
+		})
+	}
+}
+
+func TestReadParallel_CallbackErrorStops(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	input := strings.Repeat("TxHash=a Gas=1 FeePerGas=1 Signature=sig\n", 1000)
+	stopErr := errors.New("stop")
+	var calls int
+	err = ingest.ReadParallel(strings.NewReader(input), ingest.ParseKeyValueLine, logger, ingest.ParallelOptions{Workers: 4, ChunkSize: 100}, func(ingest.Batch) error {
+		calls++
+		if calls == 3 {
+			return stopErr
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, stopErr)
+	assert.Equal(t, 3, calls)
+}
+
+func TestParallelReader(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	input := "{\"txHash\":\"a\",\"gas\":1,\"feePerGas\":1,\"signature\":\"s\"}\nnot json\n{\"txHash\":\"b\",\"gas\":2,\"feePerGas\":1,\"signature\":\"s\"}\n"
+	reader, err := ingest.NewParallelReader(strings.NewReader(input), ingest.FormatJSONL, logger, ingest.ParallelOptions{Workers: 2, ChunkSize: 8})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	tx, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "a", tx.TxHash)
+	var lineErr *ingest.LineError
+	_, err = reader.Next()
+	require.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 2, lineErr.Line)
+	tx, err = reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "b", tx.TxHash)
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+
+	_, err = ingest.NewParallelReader(strings.NewReader(""), ingest.FormatCSV, logger, ingest.ParallelOptions{})
+	require.ErrorIs(t, err, ingest.ErrNotLineFormat)
+}
+
+func TestParallelReader_CloseEarly(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	input := strings.Repeat("TxHash=a Gas=1 FeePerGas=1 Signature=sig\n", 10000)
+	reader, err := ingest.NewParallelReader(strings.NewReader(input), ingest.FormatKeyValue, logger, ingest.ParallelOptions{Workers: 4, ChunkSize: 128})
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.NoError(t, err)
+	require.NoError(t, reader.Close(), "closing before EOF must not block")
+}
+
+// BenchmarkIngest compares the sequential key=value reader with the parallel pipeline.
+func BenchmarkIngest(b *testing.B) {
+	logger, err := logging.Logger()
+	require.NoError(b, err, "Failed to initialize logger for benchmark")
+	var buf bytes.Buffer
+	for i := 0; i < 200000; i++ {
+		fmt.Fprintf(&buf, "TxHash=0x%064x Gas=%d FeePerGas=%d.%03d Signature=0x%0128x\n", i, 21000+i%1000, i%97+1, i%1000, i)
+	}
+	input := buf.Bytes()
+
+	b.Run("Sequential", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		for i := 0; i < b.N; i++ {
+			reader := ingest.NewKeyValueReader(bytes.NewReader(input), logger)
+			for {
+				if _, err := reader.Next(); err == io.EOF {
+					break
+				}
+			}
+		}
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("Parallel-%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				err := ingest.ReadParallel(bytes.NewReader(input), ingest.ParseKeyValueLine, logger, ingest.ParallelOptions{Workers: workers}, func(ingest.Batch) error {
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+const (
+	FormatKeyValue = "kv"
+	FormatJSONL    = "jsonl"
+	FormatCSV      = "csv"
+
+	maxLineSize = 1 << 20 // Longest input line accepted by the line based readers
+)
+
+var (
+	ErrUnknownFormat  = errors.New("unknown input format")
+	ErrMalformedLine  = types.ErrMalformedLine
+	ErrMissingField   = types.ErrMissingField
+	ErrUnknownField   = types.ErrUnknownField
+	ErrDuplicateField = types.ErrDuplicateField
+	ErrInvalidNumber  = errors.New("invalid number")
+)
+
+// Reader yields transactions parsed from an input source.
+//
+// Next returns io.EOF once the input is exhausted. A *LineError means a single record could not
+// be parsed; the reader stays usable and the caller may keep calling Next. Any other error is fatal.
+type Reader interface {
+	Next() (*types.Tx, error)
+	Line() int // Line number of the record last returned by Next
+}
+
+// LineError describes a record that could not be parsed.
+type LineError struct {
+	Line int    // 1-based line number in the input
+	Raw  string // The offending line as read
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// NewReader returns a Reader for the named input format.
+func NewReader(format string, r io.Reader, logger logging.LoggingSystem) (Reader, error) {
+	switch strings.ToLower(format) {
+	case "", FormatKeyValue:
+		return NewKeyValueReader(r, logger), nil
+	case FormatJSONL:
+		return NewJSONLReader(r, logger), nil
+	case FormatCSV:
+		return NewCSVReader(r, logger), nil
+	}
+	return nil, errors.Wrapf(ErrUnknownFormat, "%q (available: %s, %s, %s)", format, FormatKeyValue, FormatJSONL, FormatCSV)
+}
+
+// FormatFromPath guesses the input format from a file extension, ignoring a trailing compression
+// extension such as ".gz", and defaults to key=value lines.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(trimCompressionExt(path))) {
+	case ".jsonl", ".ndjson", ".json":
+		return FormatJSONL
+	case ".csv":
+		return FormatCSV
+	}
+	return FormatKeyValue
+}
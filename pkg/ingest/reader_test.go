@@ -0,0 +1,66 @@
+package ingest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+)
+
+func TestNewReader(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, format := range []string{"", ingest.FormatKeyValue, ingest.FormatJSONL, "CSV"} {
+		reader, err := ingest.NewReader(format, strings.NewReader(""), logger)
+		require.NoError(t, err, "format %q", format)
+		assert.NotNil(t, reader)
+	}
+	_, err = ingest.NewReader("xml", strings.NewReader(""), logger)
+	require.ErrorIs(t, err, ingest.ErrUnknownFormat)
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"./transactions.txt":  ingest.FormatKeyValue,
+		"./transactions":      ingest.FormatKeyValue,
+		"/data/dump.JSONL":    ingest.FormatJSONL,
+		"/data/dump.ndjson":   ingest.FormatJSONL,
+		"/data/dump.csv":      ingest.FormatCSV,
+		"/data/dump.csv.orig": ingest.FormatKeyValue,
+	} {
+		assert.Equal(t, want, ingest.FormatFromPath(path), path)
+	}
+}
+
+func TestReader_Line(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		format    string
+		input     string
+		wantLines []int
+	}{
+		{format: ingest.FormatKeyValue, input: "TxHash=0xa Gas=1 FeePerGas=1 Signature=0xs\n\nTxHash=0xb Gas=1 FeePerGas=1 Signature=0xs\n", wantLines: []int{1, 3}},
+		{format: ingest.FormatJSONL, input: `{"txHash":"0xa","gas":1,"feePerGas":1,"signature":"0xs"}` + "\n\n" + `{"txHash":"0xb","gas":1,"feePerGas":1,"signature":"0xs"}` + "\n", wantLines: []int{1, 3}},
+		{format: ingest.FormatCSV, input: "tx_hash,gas,fee_per_gas,signature\n0xa,1,1,0xs\n0xb,1,1,0xs\n", wantLines: []int{2, 3}},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			reader, err := ingest.NewReader(tc.format, strings.NewReader(tc.input), logger)
+			require.NoError(t, err)
+			var lines []int
+			for {
+				tx, err := reader.Next()
+				if err != nil {
+					break
+				}
+				require.NotNil(t, tx)
+				lines = append(lines, reader.Line())
+			}
+			assert.Equal(t, tc.wantLines, lines)
+		})
+	}
+}
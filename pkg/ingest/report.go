@@ -0,0 +1,179 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"mempool/pkg/types"
+)
+
+// ReasonCode classifies why an input transaction did not end up in the pool.
+type ReasonCode string
+
+const (
+	ReasonMalformed ReasonCode = "malformed"     // The line could not be parsed
+	ReasonDuplicate ReasonCode = "duplicate"     // Rejected by AddTx or AddTxs as a duplicate
+	ReasonInvalid   ReasonCode = "invalid"       // Missing a hash or signature, or with a non-positive gas or fee per gas
+	ReasonRejected  ReasonCode = "rejected"      // Rejected by AddTx for any other reason
+	ReasonPaused    ReasonCode = "paused"        // Submitted while admission was paused
+	ReasonBelowMin  ReasonCode = "below_min_fee" // Fee per gas below the configured minimum
+	ReasonFeeTooLow ReasonCode = "fee_too_low"   // Pool full and the fee did not beat the lowest pooled fee
+	ReasonEvicted   ReasonCode = "evicted"       // Pooled, then replaced by a higher-fee transaction
+)
+
+// Reject is one line of the rejects file.
+type Reject struct {
+	Line   int        `json:"line"` // 0 when the transaction did not come from this input, e.g. a restored snapshot
+	TxHash string     `json:"txHash,omitempty"`
+	Raw    string     `json:"raw"`
+	Reason ReasonCode `json:"reason"`
+	Detail string     `json:"detail,omitempty"`
+}
+
+// Summary counts the outcome of every non-blank input line.
+type Summary struct {
+	Records   int `json:"records"`   // Non-blank lines read
+	Parsed    int `json:"parsed"`    // Lines that parsed into a transaction
+	Malformed int `json:"malformed"` // Lines that failed to parse
+	Duplicate int `json:"duplicate"`
+	Invalid   int `json:"invalid"`
+	Rejected  int `json:"rejected"`
+	Paused    int `json:"paused"`
+	BelowMin  int `json:"belowMinFee"`
+	FeeTooLow int `json:"feeTooLow"`
+	Evicted   int `json:"evicted"`
+}
+
+// Report collects an ingestion summary and writes every rejected or discarded transaction as a
+// JSON line to the rejects writer. It is safe for concurrent use, so Dropped can be passed to
+// types.WithDropHandler.
+//
+// The report keeps no per-transaction state, so its memory does not grow with the input: the line
+// number travels with the transaction as Tx.SourceLine, and the raw text of a transaction discarded
+// by the pool is re-rendered in key=value form from its parsed fields.
+type Report struct {
+	mu      sync.Mutex
+	w       *bufio.Writer // nil when only the summary is wanted
+	enc     *json.Encoder
+	summary Summary
+	err     error // First error writing the rejects file
+}
+
+// NewReport returns a report writing rejects to w, which may be nil to only keep the summary.
+func NewReport(w io.Writer) *Report {
+	r := &Report{}
+	if w != nil {
+		r.w = bufio.NewWriter(w)
+		r.enc = json.NewEncoder(r.w)
+	}
+	return r
+}
+
+// Parsed records that line produced tx and sets tx.SourceLine, so a later reject of tx names it.
+func (r *Report) Parsed(tx *types.Tx, line int) {
+	tx.SourceLine = line
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Records++
+	r.summary.Parsed++
+}
+
+// Malformed records a line that failed to parse.
+func (r *Report) Malformed(lineErr *LineError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Records++
+	r.summary.Malformed++
+	r.writeLocked(Reject{Line: lineErr.Line, Raw: lineErr.Raw, Reason: ReasonMalformed, Detail: lineErr.Err.Error()})
+}
+
+// Rejected records a transaction that AddTx or AddTxs refused.
+func (r *Report) Rejected(tx *types.Tx, err error) {
+	reason := ReasonRejected
+	switch {
+	case errors.Is(err, types.ErrDuplicateTx):
+		reason = ReasonDuplicate
+	case errors.Is(err, types.ErrInvalidTx):
+		reason = ReasonInvalid
+	case errors.Is(err, types.ErrFeeTooLow):
+		reason = ReasonFeeTooLow
+	case errors.Is(err, types.ErrPaused):
+		reason = ReasonPaused
+	case errors.Is(err, types.ErrBelowMinFee):
+		reason = ReasonBelowMin
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rejectLocked(tx, reason, err.Error())
+}
+
+// Dropped records a transaction the pool discarded after accepting it. It matches types.DropHandler.
+func (r *Report) Dropped(tx *types.Tx, reason types.DropReason) {
+	code := ReasonCode(reason)
+	switch reason {
+	case types.DropFeeTooLow:
+		code = ReasonFeeTooLow
+	case types.DropEvicted:
+		code = ReasonEvicted
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rejectLocked(tx, code, "")
+}
+
+// Summary returns the counts collected so far.
+func (r *Report) Summary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary
+}
+
+// Flush writes buffered rejects and returns the first write error encountered, if any.
+func (r *Report) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.w != nil && r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return errors.Wrap(r.err, "failed to write rejects")
+}
+
+func (r *Report) rejectLocked(tx *types.Tx, reason ReasonCode, detail string) {
+	switch reason {
+	case ReasonDuplicate:
+		r.summary.Duplicate++
+	case ReasonInvalid:
+		r.summary.Invalid++
+	case ReasonFeeTooLow:
+		r.summary.FeeTooLow++
+	case ReasonEvicted:
+		r.summary.Evicted++
+	case ReasonPaused:
+		r.summary.Paused++
+	case ReasonBelowMin:
+		r.summary.BelowMin++
+	default:
+		r.summary.Rejected++
+	}
+	r.writeLocked(Reject{Line: tx.SourceLine, TxHash: tx.TxHash, Raw: renderKeyValue(tx), Reason: reason, Detail: detail})
+}
+
+func (r *Report) writeLocked(reject Reject) {
+	if r.enc == nil || r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(reject)
+}
+
+// renderKeyValue formats tx as an input line accepted by ParseKeyValueLine.
+func renderKeyValue(tx *types.Tx) string {
+	return KeyTxHash + "=" + tx.TxHash +
+		" " + KeyGas + "=" + strconv.FormatFloat(tx.Gas, 'g', -1, 64) +
+		" " + KeyFeePerGas + "=" + strconv.FormatFloat(tx.FeePerGas, 'g', -1, 64) +
+		" " + KeySignature + "=" + tx.Signature
+}
@@ -0,0 +1,120 @@
+package ingest_test
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/ingest"
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+func TestReport(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	input := strings.Join([]string{
+		"TxHash=0xa Gas=1 FeePerGas=5 Signature=0xs",
+		"TxHash=0xb Gas=oops FeePerGas=1 Signature=0xs",
+		"TxHash=0xc Gas=1 FeePerGas=10 Signature=0xs",
+		"TxHash=0xc Gas=1 FeePerGas=10 Signature=0xs",
+		"TxHash=0xd Gas=1 FeePerGas=1 Signature=0xs",
+		"TxHash=0xe Gas=1 FeePerGas=20 Signature=0xs",
+	}, "\n")
+
+	var out strings.Builder
+	report := ingest.NewReport(&out)
+	memPool, err := types.NewMempool(2, logger, types.WithDropHandler(report.Dropped))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 1) // A single processor admits transactions in input order
+
+	reader := ingest.NewKeyValueReader(strings.NewReader(input), logger)
+	for {
+		tx, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var lineErr *ingest.LineError
+		if errors.As(err, &lineErr) {
+			report.Malformed(lineErr)
+			continue
+		}
+		require.NoError(t, err)
+		report.Parsed(tx, reader.Line())
+		if err = memPool.AddTx(tx, wg); err != nil {
+			report.Rejected(tx, err)
+		}
+		wg.Wait()
+	}
+	memPool.CloseTxInsertChan()
+	require.NoError(t, report.Flush())
+
+	assert.Equal(t, ingest.Summary{Records: 6, Parsed: 5, Malformed: 1, Duplicate: 1, FeeTooLow: 1, Evicted: 1}, report.Summary())
+
+	var rejects []ingest.Reject
+	dec := json.NewDecoder(strings.NewReader(out.String()))
+	for dec.More() {
+		var reject ingest.Reject
+		require.NoError(t, dec.Decode(&reject))
+		rejects = append(rejects, reject)
+	}
+	require.Len(t, rejects, 4)
+	for i, want := range []struct {
+		line   int
+		txHash string
+		reason ingest.ReasonCode
+	}{
+		{line: 2, reason: ingest.ReasonMalformed},
+		{line: 4, txHash: "0xc", reason: ingest.ReasonDuplicate},
+		{line: 5, txHash: "0xd", reason: ingest.ReasonFeeTooLow},
+		{line: 1, txHash: "0xa", reason: ingest.ReasonEvicted},
+	} {
+		assert.Equal(t, want.line, rejects[i].Line, "reject %d", i)
+		assert.Equal(t, want.txHash, rejects[i].TxHash, "reject %d", i)
+		assert.Equal(t, want.reason, rejects[i].Reason, "reject %d", i)
+		assert.NotEmpty(t, rejects[i].Raw, "reject %d", i)
+	}
+	assert.Equal(t, "TxHash=0xb Gas=oops FeePerGas=1 Signature=0xs", rejects[0].Raw)
+
+	// Raw lines of pool drops are re-rendered and must parse back into the same transaction.
+	tx, err := ingest.ParseKeyValueLine(rejects[2].Raw, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "0xd", tx.TxHash)
+	assert.Equal(t, 1.0, tx.FeePerGas)
+}
+
+func TestReport_SummaryOnly(t *testing.T) {
+	report := ingest.NewReport(nil)
+	report.Malformed(&ingest.LineError{Line: 1, Raw: "garbage", Err: ingest.ErrMalformedLine})
+	report.Rejected(&types.Tx{TxHash: "0xa"}, errors.New("boom"))
+	report.Rejected(&types.Tx{TxHash: "0xb"}, errors.Wrap(types.ErrPaused, "not admitted"))
+	report.Rejected(&types.Tx{TxHash: "0xc"}, errors.Wrap(types.ErrBelowMinFee, "too cheap"))
+	report.Rejected(&types.Tx{TxHash: "0xd"}, (&types.Tx{TxHash: "0xd"}).Validate())
+	require.NoError(t, report.Flush())
+	assert.Equal(t, ingest.Summary{Records: 1, Malformed: 1, Invalid: 1, Rejected: 1, Paused: 1, BelowMin: 1}, report.Summary())
+}
+
+func TestReport_SourceLine(t *testing.T) {
+	var out strings.Builder
+	report := ingest.NewReport(&out)
+	parsed := &types.Tx{TxHash: "0xa"}
+	report.Parsed(parsed, 7)
+	report.Dropped(parsed, types.DropEvicted)
+	report.Dropped(&types.Tx{TxHash: "0xb"}, types.DropEvicted) // e.g. restored from a snapshot
+	require.NoError(t, report.Flush())
+
+	dec := json.NewDecoder(strings.NewReader(out.String()))
+	for _, want := range []ingest.Reject{{Line: 7, TxHash: "0xa"}, {Line: 0, TxHash: "0xb"}} {
+		var reject ingest.Reject
+		require.NoError(t, dec.Decode(&reject))
+		assert.Equal(t, want.Line, reject.Line, want.TxHash)
+		assert.Equal(t, want.TxHash, reject.TxHash)
+	}
+}
@@ -0,0 +1,98 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StdinPath = "-"
+
+	CompressionNone  = ""
+	CompressionGzip  = "gzip"
+	CompressionBzip2 = "bzip2"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+
+	compressionExtensions = map[string]string{
+		".gz":   CompressionGzip,
+		".gzip": CompressionGzip,
+		".bz2":  CompressionBzip2,
+	}
+)
+
+// Input is an opened transaction source. Reads return decompressed data.
+type Input struct {
+	io.Reader
+	Compression string // Detected compression, CompressionNone for plain input
+	closers     []io.Closer
+}
+
+// Close releases the decompressor and the underlying file. Standard input is never closed.
+func (in *Input) Close() error {
+	var firstErr error
+	for i := len(in.closers) - 1; i >= 0; i-- {
+		if err := in.closers[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// OpenInput opens path for reading, or standard input when path is "-". Gzip and bzip2 input is
+// decompressed transparently; compression is detected from the magic bytes so it works for stdin
+// and for files without a telling extension.
+func OpenInput(path string) (*Input, error) {
+	in := &Input{}
+	var src io.Reader = os.Stdin
+	if path != StdinPath {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open input %s", path)
+		}
+		in.closers = append(in.closers, file)
+		src = file
+	}
+
+	br := bufio.NewReader(src)
+	magic, _ := br.Peek(len(bzip2Magic)) // A short read just means the input is too small to be compressed
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			in.Close()
+			return nil, errors.Wrapf(err, "failed to read gzip header of %s", path)
+		}
+		in.closers = append(in.closers, gz)
+		in.Reader, in.Compression = gz, CompressionGzip
+	case bytes.HasPrefix(magic, bzip2Magic):
+		in.Reader, in.Compression = bzip2.NewReader(br), CompressionBzip2
+	default:
+		in.Reader = br
+	}
+	return in, nil
+}
+
+// CompressionFromPath returns the compression implied by the extension of path.
+func CompressionFromPath(path string) string {
+	return compressionExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// trimCompressionExt strips a trailing compression extension so "dump.jsonl.gz" is treated as "dump.jsonl".
+func trimCompressionExt(path string) string {
+	if CompressionFromPath(path) != CompressionNone {
+		return strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return path
+}
@@ -0,0 +1,94 @@
+package ingest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/ingest"
+)
+
+const sourceTestInput = "TxHash=0xa Gas=21000 FeePerGas=0.5 Signature=0xs1\nTxHash=0xb Gas=100 FeePerGas=2 Signature=0xs2\n"
+
+func TestOpenInput(t *testing.T) {
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "transactions.txt")
+	require.NoError(t, os.WriteFile(plainPath, []byte(sourceTestInput), 0o644))
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err := gw.Write([]byte(sourceTestInput))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	gzipPath := filepath.Join(dir, "transactions.gz")
+	require.NoError(t, os.WriteFile(gzipPath, gz.Bytes(), 0o644))
+	disguisedPath := filepath.Join(dir, "transactions.dat") // Gzip content without a telling extension
+	require.NoError(t, os.WriteFile(disguisedPath, gz.Bytes(), 0o644))
+
+	emptyPath := filepath.Join(dir, "empty.txt")
+	require.NoError(t, os.WriteFile(emptyPath, nil, 0o644))
+
+	for _, tc := range []struct {
+		name            string
+		path            string
+		wantCompression string
+		want            string
+	}{
+		{name: "plain", path: plainPath, wantCompression: ingest.CompressionNone, want: sourceTestInput},
+		{name: "gzip", path: gzipPath, wantCompression: ingest.CompressionGzip, want: sourceTestInput},
+		{name: "gzip_by_magic", path: disguisedPath, wantCompression: ingest.CompressionGzip, want: sourceTestInput},
+		{name: "bzip2", path: filepath.Join("testdata", "transactions.kv.bz2"), wantCompression: ingest.CompressionBzip2, want: sourceTestInput},
+		{name: "empty", path: emptyPath, wantCompression: ingest.CompressionNone, want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			in, err := ingest.OpenInput(tc.path)
+			require.NoError(t, err)
+			defer in.Close()
+			assert.Equal(t, tc.wantCompression, in.Compression)
+			data, err := io.ReadAll(in)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(data))
+		})
+	}
+
+	_, err = ingest.OpenInput(filepath.Join(dir, "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestOpenInput_Stdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		gw := gzip.NewWriter(w)
+		gw.Write([]byte(sourceTestInput))
+		gw.Close()
+		w.Close()
+	}()
+
+	in, err := ingest.OpenInput(ingest.StdinPath)
+	require.NoError(t, err)
+	assert.Equal(t, ingest.CompressionGzip, in.Compression)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	assert.Equal(t, sourceTestInput, string(data))
+	require.NoError(t, in.Close())
+	r.Close()
+}
+
+func TestCompressionFromPath(t *testing.T) {
+	assert.Equal(t, ingest.CompressionGzip, ingest.CompressionFromPath("dump.jsonl.GZ"))
+	assert.Equal(t, ingest.CompressionBzip2, ingest.CompressionFromPath("dump.bz2"))
+	assert.Equal(t, ingest.CompressionNone, ingest.CompressionFromPath("dump.txt"))
+	assert.Equal(t, ingest.FormatJSONL, ingest.FormatFromPath("dump.jsonl.gz"))
+	assert.Equal(t, ingest.FormatCSV, ingest.FormatFromPath("dump.csv.bz2"))
+}
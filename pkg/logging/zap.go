@@ -1,14 +1,44 @@
 package logging
 
 import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"mempool/pkg/util"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+const rootName = "mempool" // Name of the logger returned by Logger, left out of override names
+
+// Debug entries of a logger wrapped by SampleDebug are logged for the first sampleFirst of each
+// message per sampleTick, then one in every sampleThereafter.
+const (
+	sampleTick       = time.Second
+	sampleFirst      = 10
+	sampleThereafter = 1000
+)
+
 var logger LoggingSystem = nil
 
+// level is shared by every logger returned by Logger, so SetLevel takes effect everywhere at once.
+var level = zap.NewAtomicLevel()
+
+// overrides holds the per-logger levels set by SetLevelFor, nil when there are none. It is replaced
+// whole on each change so loggers read it without locking.
+var (
+	overrides   atomic.Pointer[overrideSet]
+	overridesMu sync.Mutex // Serialises changes to overrides
+)
+
+type overrideSet struct {
+	levels map[string]zapcore.Level // Logger name -> minimum level
+	lowest zapcore.Level            // Lowest of levels, so Enabled can answer without a name
+}
+
 type LoggingSystem interface {
 	Sugar() *zap.SugaredLogger
 	Named(string) *zap.Logger
@@ -29,11 +59,155 @@ type LoggingSystem interface {
 func Logger() (LoggingSystem, error) {
 	var err error
 	if logger == nil {
+		level.SetLevel(DefaultLevel()) // Read when the first logger is built, after .env has been loaded
+		// The built core accepts every level; levelCore filters by level and overrides instead.
+		wrap := zap.WrapCore(func(core zapcore.Core) zapcore.Core { return levelCore{core} })
 		if util.DevelopmentEnvironment() {
-			logger, err = zap.NewDevelopment(zap.AddStacktrace(zapcore.ErrorLevel))
+			config := zap.NewDevelopmentConfig()
+			config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+			logger, err = config.Build(wrap, zap.AddStacktrace(zapcore.ErrorLevel))
 		} else {
-			logger, err = zap.NewProduction()
+			config := zap.NewProductionConfig()
+			config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+			logger, err = config.Build(wrap)
+		}
+	}
+	return logger.Named(rootName), err
+}
+
+// DefaultLevel is the level loggers start at: debug when DEBUG is true, info otherwise.
+func DefaultLevel() zapcore.Level {
+	if util.DevelopmentEnvironment() {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
+
+// Level returns the current minimum level of the loggers returned by Logger.
+func Level() zapcore.Level {
+	return level.Level()
+}
+
+// SetLevel changes the minimum level of every logger returned by Logger, including loggers that
+// already exist. Loggers with an override keep their own level.
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+// SetLevelFor overrides the minimum level of the logger called name, as passed to Named, and of the
+// loggers named under it: an override for "mempool" also applies to "mempool/AddTx" unless that
+// logger has an override of its own.
+func SetLevelFor(name string, l zapcore.Level) {
+	updateOverrides(func(levels map[string]zapcore.Level) { levels[name] = l })
+}
+
+// ClearLevelFor removes the override set for name by SetLevelFor, if any.
+func ClearLevelFor(name string) {
+	updateOverrides(func(levels map[string]zapcore.Level) { delete(levels, name) })
+}
+
+// Overrides returns a copy of the levels set by SetLevelFor, by logger name.
+func Overrides() map[string]zapcore.Level {
+	levels := make(map[string]zapcore.Level)
+	if set := overrides.Load(); set != nil {
+		for name, l := range set.levels {
+			levels[name] = l
+		}
+	}
+	return levels
+}
+
+func updateOverrides(change func(map[string]zapcore.Level)) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	levels := Overrides()
+	change(levels)
+	if len(levels) == 0 {
+		overrides.Store(nil)
+		return
+	}
+	set := &overrideSet{levels: levels, lowest: zapcore.FatalLevel}
+	for _, l := range levels {
+		set.lowest = min(set.lowest, l)
+	}
+	overrides.Store(set)
+}
+
+// enabled reports whether an entry at l from the logger with the given full name is logged. The
+// closest override wins: the name itself, then each parent at a "/" or "." boundary.
+func enabled(name string, l zapcore.Level) bool {
+	set := overrides.Load()
+	if set == nil {
+		return level.Enabled(l)
+	}
+	if name == rootName {
+		name = ""
+	}
+	for name = strings.TrimPrefix(name, rootName+"."); name != ""; {
+		if floor, ok := set.levels[name]; ok {
+			return l >= floor
 		}
+		name = name[:max(strings.LastIndexAny(name, "/."), 0)]
+	}
+	return level.Enabled(l)
+}
+
+// levelCore filters entries by the shared level and the overrides of their logger.
+type levelCore struct {
+	zapcore.Core
+}
+
+// Enabled reports whether l may be logged by some logger, so disabled entries are dropped before
+// their fields are built.
+func (c levelCore) Enabled(l zapcore.Level) bool {
+	if set := overrides.Load(); set != nil && l >= set.lowest {
+		return true
+	}
+	return level.Enabled(l)
+}
+
+// Level is the lowest level Enabled accepts, for zap.Logger.Level.
+func (c levelCore) Level() zapcore.Level {
+	if set := overrides.Load(); set != nil {
+		return min(set.lowest, level.Level())
+	}
+	return level.Level()
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{c.Core.With(fields)}
+}
+
+func (c levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !enabled(entry.LoggerName, entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// SampleDebug returns l with its debug entries sampled, for loggers on per-transaction paths that
+// would otherwise flood the output and slow the pool down at debug level. Entries above debug are
+// all logged. Samples are counted per message across every logger derived from the result, so build
+// it once and keep it.
+func SampleDebug(l *zap.Logger) *zap.Logger {
+	return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return debugSampler{Core: core, sampled: zapcore.NewSamplerWithOptions(core, sampleTick, sampleFirst, sampleThereafter)}
+	}))
+}
+
+// debugSampler checks debug entries with a sampler and every other entry with the core it wraps.
+type debugSampler struct {
+	zapcore.Core
+	sampled zapcore.Core
+}
+
+func (c debugSampler) With(fields []zapcore.Field) zapcore.Core {
+	return debugSampler{Core: c.Core.With(fields), sampled: c.sampled.With(fields)}
+}
+
+func (c debugSampler) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level == zapcore.DebugLevel {
+		return c.sampled.Check(entry, checked)
 	}
-	return logger.Named("mempool"), err
+	return c.Core.Check(entry, checked)
 }
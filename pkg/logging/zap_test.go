@@ -0,0 +1,65 @@
+package logging_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"mempool/pkg/logging"
+)
+
+func TestSetLevelFor(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	previous := logging.Level()
+	logging.SetLevel(zapcore.InfoLevel)
+	t.Cleanup(func() {
+		logging.SetLevel(previous)
+		for name := range logging.Overrides() {
+			logging.ClearLevelFor(name)
+		}
+	})
+
+	logging.SetLevelFor("mempool", zapcore.WarnLevel)
+	logging.SetLevelFor("mempool/processTx", zapcore.DebugLevel)
+	assert.Equal(t, map[string]zapcore.Level{"mempool": zapcore.WarnLevel, "mempool/processTx": zapcore.DebugLevel}, logging.Overrides())
+
+	for _, tc := range []struct {
+		name  string
+		level zapcore.Level
+		want  bool
+	}{
+		{name: "mempool/processTx", level: zapcore.DebugLevel, want: true},
+		{name: "mempool/AddTx", level: zapcore.InfoLevel, want: false},
+		{name: "mempool/AddTx", level: zapcore.WarnLevel, want: true},
+		{name: "mempool", level: zapcore.InfoLevel, want: false},
+		{name: "server/Start", level: zapcore.DebugLevel, want: false},
+		{name: "server/Start", level: zapcore.InfoLevel, want: true},
+		{name: "", level: zapcore.InfoLevel, want: true}, // The root logger is not named "mempool"
+	} {
+		named := logger.Named(tc.name)
+		assert.Equal(t, tc.want, named.Check(tc.level, "message") != nil, "%s at %s", tc.name, tc.level)
+	}
+
+	logging.ClearLevelFor("mempool")
+	assert.NotNil(t, logger.Named("mempool/AddTx").Check(zapcore.InfoLevel, "message"))
+	logging.SetLevel(zapcore.ErrorLevel)
+	assert.Nil(t, logger.Named("mempool/AddTx").Check(zapcore.WarnLevel, "message"))
+	assert.NotNil(t, logger.Named("mempool/processTx").Check(zapcore.DebugLevel, "message"))
+}
+
+func TestSampleDebug(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := logging.SampleDebug(zap.New(core))
+	for i := 0; i < 5000; i++ {
+		logger.Debug("hot")
+		logger.Info("rare")
+	}
+	// 10 logged first, then one in every 1000 of the remaining 4990.
+	assert.Equal(t, 14, logs.FilterMessage("hot").Len())
+	assert.Equal(t, 5000, logs.FilterMessage("rare").Len())
+}
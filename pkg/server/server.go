@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// readHeaderTimeout bounds how long a client may take to send request headers.
+const readHeaderTimeout = 5 * time.Second
+
+// Server exposes administrative operations on a running mempool over HTTP.
+type Server struct {
+	mempool types.Mempool
+	logger  logging.LoggingSystem
+	http    *http.Server
+}
+
+// CapacityResponse is returned by the capacity endpoints.
+type CapacityResponse struct {
+	MaxMemPoolSize uint32 `json:"maxMemPoolSize"`
+	Size           uint32 `json:"size"`
+	Evicted        int    `json:"evicted,omitempty"` // Transactions evicted by a PUT that shrank the pool
+}
+
+// AdmissionResponse is returned by the admission endpoints.
+type AdmissionResponse struct {
+	Paused bool   `json:"paused"`
+	Size   uint32 `json:"size"`
+}
+
+// FeeEstimateResponse is returned by GET /fees/estimate.
+type FeeEstimateResponse struct {
+	Percentile float64 `json:"percentile"`
+	TotalFee   float64 `json:"totalFee"`
+}
+
+// FeeSuggestionResponse is returned by GET /fees/suggest.
+type FeeSuggestionResponse struct {
+	FeePerGas float64 `json:"feePerGas"`
+}
+
+// LogLevelResponse is returned by the log level endpoints.
+type LogLevelResponse struct {
+	Level     string            `json:"level"`
+	Overrides map[string]string `json:"overrides,omitempty"` // Logger name -> level, see logging.SetLevelFor
+}
+
+// logLevelRequest is the body of PUT /admin/log-level. Either field may be left out. An override
+// set to "" is removed.
+type logLevelRequest struct {
+	Level     *string           `json:"level"`
+	Overrides map[string]string `json:"overrides"`
+}
+
+// capacityRequest is the body of PUT /admin/capacity.
+type capacityRequest struct {
+	MaxMemPoolSize *uint32 `json:"maxMemPoolSize"`
+}
+
+// errorResponse is the body of every non-2xx response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a Server for mempool that listens on addr once Start is called.
+func New(addr string, mempool types.Mempool, logger logging.LoggingSystem) *Server {
+	s := &Server{mempool: mempool, logger: logger}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/capacity", s.getCapacity)
+	mux.HandleFunc("PUT /admin/capacity", s.putCapacity)
+	mux.HandleFunc("GET /admin/admission", s.getAdmission)
+	mux.HandleFunc("POST /admin/pause", s.pause)
+	mux.HandleFunc("POST /admin/resume", s.resume)
+	mux.HandleFunc("GET /admin/log-level", s.getLogLevel)
+	mux.HandleFunc("PUT /admin/log-level", s.putLogLevel)
+	mux.HandleFunc("GET /txs/{hash}/status", s.getTxStatus)
+	mux.HandleFunc("GET /fees/estimate", s.getFeeEstimate)
+	mux.HandleFunc("GET /fees/suggest", s.getFeeSuggestion)
+	s.http = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: readHeaderTimeout}
+	return s
+}
+
+// Handler returns the HTTP handler serving every endpoint, which lets tests use httptest.
+func (s *Server) Handler() http.Handler {
+	return s.http.Handler
+}
+
+// Start listens on the configured address and serves requests in the background.
+// It returns the bound address, which differs from the configured one when the port is 0.
+func (s *Server) Start() (net.Addr, error) {
+	listener, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on %s", s.http.Addr)
+	}
+	go func() {
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Named("server/Start").Error("admin server stopped", zap.Error(err))
+		}
+	}()
+	s.logger.Named("server/Start").Info("admin server listening", zap.String("addr", listener.Addr().String()))
+	return listener.Addr(), nil
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return errors.Wrap(s.http.Shutdown(ctx), "failed to shut down admin server")
+}
+
+// getCapacity reports the current capacity and size of the pool.
+func (s *Server) getCapacity(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, CapacityResponse{MaxMemPoolSize: s.mempool.MaxMemPoolSize(), Size: s.mempool.MempoolLen()})
+}
+
+// putCapacity resizes the pool, evicting the lowest-priority transactions when it shrinks.
+func (s *Server) putCapacity(w http.ResponseWriter, r *http.Request) {
+	var req capacityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid request body"))
+		return
+	}
+	if req.MaxMemPoolSize == nil {
+		s.writeError(w, http.StatusBadRequest, errors.New("maxMemPoolSize is required"))
+		return
+	}
+	evicted, err := s.mempool.SetMaxMemPoolSize(*req.MaxMemPoolSize)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, CapacityResponse{MaxMemPoolSize: s.mempool.MaxMemPoolSize(), Size: s.mempool.MempoolLen(), Evicted: evicted})
+}
+
+// getAdmission reports whether admission is paused.
+func (s *Server) getAdmission(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.admission())
+}
+
+// pause stops admission of new transactions while reads and processing continue.
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	s.mempool.Pause()
+	s.writeJSON(w, http.StatusOK, s.admission())
+}
+
+// resume admits new transactions again.
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	s.mempool.Resume()
+	s.writeJSON(w, http.StatusOK, s.admission())
+}
+
+func (s *Server) admission() AdmissionResponse {
+	return AdmissionResponse{Paused: s.mempool.Paused(), Size: s.mempool.MempoolLen()}
+}
+
+// getLogLevel reports the level of every logger and the per-logger overrides.
+func (s *Server) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, logLevel())
+}
+
+// putLogLevel changes the level of every logger and sets or removes per-logger overrides. Nothing is
+// changed unless every level in the request is valid.
+func (s *Server) putLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid request body"))
+		return
+	}
+	var level zapcore.Level
+	if req.Level != nil {
+		var err error
+		if level, err = zapcore.ParseLevel(*req.Level); err != nil {
+			s.writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid level"))
+			return
+		}
+	}
+	overrides := make(map[string]zapcore.Level, len(req.Overrides))
+	for name, value := range req.Overrides {
+		if name == "" {
+			s.writeError(w, http.StatusBadRequest, errors.New("override for an empty logger name"))
+			return
+		}
+		if value == "" {
+			continue
+		}
+		l, err := zapcore.ParseLevel(value)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, errors.Wrapf(err, "invalid level for %s", name))
+			return
+		}
+		overrides[name] = l
+	}
+
+	if req.Level != nil {
+		logging.SetLevel(level)
+	}
+	for name := range req.Overrides {
+		if l, ok := overrides[name]; ok {
+			logging.SetLevelFor(name, l)
+		} else {
+			logging.ClearLevelFor(name)
+		}
+	}
+	response := logLevel()
+	s.logger.Named("server/putLogLevel").Info("changed log level", zap.String("level", response.Level), zap.Any("overrides", response.Overrides))
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+func logLevel() LogLevelResponse {
+	response := LogLevelResponse{Level: logging.Level().String()}
+	for name, l := range logging.Overrides() {
+		if response.Overrides == nil {
+			response.Overrides = make(map[string]string)
+		}
+		response.Overrides[name] = l.String()
+	}
+	return response
+}
+
+// getTxStatus reports whether a transaction is queued or pooled, or why it recently left the pool.
+func (s *Server) getTxStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.mempool.TxStatus(r.PathValue("hash"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, errors.Errorf("no status known for transaction %s", r.PathValue("hash")))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
+// getFeeEstimate returns the total fee that outranks the requested percentile of the pool.
+func (s *Server) getFeeEstimate(w http.ResponseWriter, r *http.Request) {
+	percentile, err := strconv.ParseFloat(r.URL.Query().Get("percentile"), 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid percentile"))
+		return
+	}
+	totalFee, err := s.mempool.EstimateFee(percentile)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, FeeEstimateResponse{Percentile: percentile, TotalFee: totalFee})
+}
+
+// getFeeSuggestion returns the fee per gas likely to be included within the requested number of
+// blocks, which defaults to 1.
+func (s *Server) getFeeSuggestion(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	gas, err := strconv.ParseFloat(query.Get("gas"), 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid gas"))
+		return
+	}
+	blockGasLimit, err := strconv.ParseFloat(query.Get("blockGasLimit"), 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid blockGasLimit"))
+		return
+	}
+	blocks := 1
+	if query.Has("blocks") {
+		if blocks, err = strconv.Atoi(query.Get("blocks")); err != nil {
+			s.writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid blocks"))
+			return
+		}
+	}
+	feePerGas, err := s.mempool.SuggestFeeForInclusion(gas, blockGasLimit, blocks)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, FeeSuggestionResponse{FeePerGas: feePerGas})
+}
+
+// writeError writes err as a JSON error body with the given status.
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// writeJSON writes v as a JSON body with the given status.
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Named("server/writeJSON").Error("failed to write response", zap.Error(err))
+	}
+}
@@ -0,0 +1,219 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/server"
+	"mempool/pkg/types"
+)
+
+func TestServer_Capacity(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "get", method: http.MethodGet, wantStatus: http.StatusOK, wantBody: `{"maxMemPoolSize":5,"size":5}`},
+		{name: "put_shrink", method: http.MethodPut, body: `{"maxMemPoolSize":2}`, wantStatus: http.StatusOK, wantBody: `{"maxMemPoolSize":2,"size":2,"evicted":3}`},
+		{name: "put_grow", method: http.MethodPut, body: `{"maxMemPoolSize":10}`, wantStatus: http.StatusOK, wantBody: `{"maxMemPoolSize":10,"size":5}`},
+		{name: "put_zero", method: http.MethodPut, body: `{"maxMemPoolSize":0}`, wantStatus: http.StatusBadRequest},
+		{name: "put_missing", method: http.MethodPut, body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "put_malformed", method: http.MethodPut, body: `{"maxMemPoolSize":-1}`, wantStatus: http.StatusBadRequest},
+		{name: "method_not_allowed", method: http.MethodPost, body: `{"maxMemPoolSize":2}`, wantStatus: http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			memPool, err := types.NewMempool(5, logger)
+			require.NoError(t, err)
+			for i := 1; i <= 5; i++ {
+				for _, err := range memPool.AddTxs([]*types.Tx{types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 1, float64(i))}) {
+					require.NoError(t, err)
+				}
+			}
+			handler := server.New("", memPool, logger).Handler()
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, "/admin/capacity", strings.NewReader(tc.body)))
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			switch {
+			case tc.wantBody != "":
+				assert.JSONEq(t, tc.wantBody, rec.Body.String())
+			case rec.Code == http.StatusBadRequest:
+				var body map[string]string
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.NotEmpty(t, body["error"])
+			}
+		})
+	}
+}
+
+func TestServer_Admission(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	memPool, err := types.NewMempool(5, logger)
+	require.NoError(t, err)
+	handler := server.New("", memPool, logger).Handler()
+
+	// Each step runs against the same pool, so they must stay in order.
+	for _, step := range []struct {
+		method   string
+		path     string
+		wantBody string
+	}{
+		{method: http.MethodGet, path: "/admin/admission", wantBody: `{"paused":false,"size":0}`},
+		{method: http.MethodPost, path: "/admin/pause", wantBody: `{"paused":true,"size":0}`},
+		{method: http.MethodPost, path: "/admin/pause", wantBody: `{"paused":true,"size":0}`},
+		{method: http.MethodGet, path: "/admin/admission", wantBody: `{"paused":true,"size":0}`},
+		{method: http.MethodPost, path: "/admin/resume", wantBody: `{"paused":false,"size":0}`},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(step.method, step.path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, "%s %s", step.method, step.path)
+		assert.JSONEq(t, step.wantBody, rec.Body.String(), "%s %s", step.method, step.path)
+		if step.path == "/admin/pause" {
+			assert.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "tx", "sig", 1, 1)})[0], types.ErrPaused)
+		}
+	}
+	assert.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "tx", "sig", 1, 1)})[0])
+}
+
+func TestServer_LogLevel(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	previous := logging.Level()
+	logging.SetLevel(zapcore.InfoLevel)
+	t.Cleanup(func() {
+		logging.SetLevel(previous)
+		logging.ClearLevelFor("mempool/processTx")
+	})
+	memPool, err := types.NewMempool(5, logger)
+	require.NoError(t, err)
+	handler := server.New("", memPool, logger).Handler()
+
+	// Each step runs against the same levels, so they must stay in order.
+	for _, step := range []struct {
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{method: http.MethodGet, wantStatus: http.StatusOK, wantBody: `{"level":"info"}`},
+		{method: http.MethodPut, body: `{"level":"warn"}`, wantStatus: http.StatusOK, wantBody: `{"level":"warn"}`},
+		{method: http.MethodPut, body: `{"overrides":{"mempool/processTx":"debug"}}`, wantStatus: http.StatusOK, wantBody: `{"level":"warn","overrides":{"mempool/processTx":"debug"}}`},
+		{method: http.MethodPut, body: `{"level":"loud"}`, wantStatus: http.StatusBadRequest},
+		{method: http.MethodPut, body: `{"level":"error","overrides":{"server":"noisy"}}`, wantStatus: http.StatusBadRequest},
+		{method: http.MethodGet, wantStatus: http.StatusOK, wantBody: `{"level":"warn","overrides":{"mempool/processTx":"debug"}}`},
+		{method: http.MethodPut, body: `{"level":"info","overrides":{"mempool/processTx":""}}`, wantStatus: http.StatusOK, wantBody: `{"level":"info"}`},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(step.method, "/admin/log-level", strings.NewReader(step.body)))
+		require.Equal(t, step.wantStatus, rec.Code, "%s %s", step.method, step.body)
+		if step.wantBody != "" {
+			assert.JSONEq(t, step.wantBody, rec.Body.String(), "%s %s", step.method, step.body)
+		}
+	}
+}
+
+func TestServer_TxStatus(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	memPool, err := types.NewMempool(1, logger, types.WithStatusIndex(10, 0))
+	require.NoError(t, err)
+	require.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "pooled", "sig", 1, 2)})[0])
+	require.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "cheap", "sig", 1, 1)})[0], types.ErrFeeTooLow)
+	handler := server.New("", memPool, logger).Handler()
+
+	for _, tc := range []struct {
+		hash       string
+		wantStatus int
+		wantState  types.StatusCode
+		wantBody   string
+	}{
+		{hash: "pooled", wantStatus: http.StatusOK, wantState: types.StatusPooled, wantBody: `{"txHash":"pooled","status":"pooled"}`},
+		{hash: "cheap", wantStatus: http.StatusOK, wantState: types.StatusRejected},
+		{hash: "unknown", wantStatus: http.StatusNotFound},
+	} {
+		t.Run(tc.hash, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/txs/"+tc.hash+"/status", nil))
+			require.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, rec.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK {
+				var status types.TxStatus
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+				assert.Equal(t, tc.hash, status.TxHash)
+				assert.Equal(t, tc.wantState, status.Status)
+			}
+		})
+	}
+}
+
+func TestServer_Fees(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	memPool, err := types.NewMempool(10, logger)
+	require.NoError(t, err)
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 10, float64(i))})[0])
+	}
+	handler := server.New("", memPool, logger).Handler()
+
+	for _, tc := range []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "estimate_zero", path: "/fees/estimate?percentile=0", wantStatus: http.StatusOK, wantBody: `{"percentile":0,"totalFee":0}`},
+		{name: "estimate_median", path: "/fees/estimate?percentile=50", wantStatus: http.StatusOK},
+		{name: "estimate_missing", path: "/fees/estimate", wantStatus: http.StatusBadRequest},
+		{name: "estimate_out_of_range", path: "/fees/estimate?percentile=101", wantStatus: http.StatusBadRequest},
+		{name: "suggest_all_fit", path: "/fees/suggest?gas=10&blockGasLimit=100", wantStatus: http.StatusOK, wantBody: `{"feePerGas":0}`},
+		{name: "suggest_one_block", path: "/fees/suggest?gas=10&blockGasLimit=30", wantStatus: http.StatusOK},
+		{name: "suggest_missing_gas", path: "/fees/suggest?blockGasLimit=30", wantStatus: http.StatusBadRequest},
+		{name: "suggest_bad_blocks", path: "/fees/suggest?gas=10&blockGasLimit=30&blocks=x", wantStatus: http.StatusBadRequest},
+		{name: "suggest_gas_above_limit", path: "/fees/suggest?gas=40&blockGasLimit=30", wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantBody != "" {
+				assert.JSONEq(t, tc.wantBody, rec.Body.String())
+			}
+			if rec.Code == http.StatusBadRequest {
+				var body map[string]string
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.NotEmpty(t, body["error"])
+			}
+		})
+	}
+
+	// The median of fees 10, 20, 30 and 40 is 20, and with room for two transactions of gas 10
+	// ahead of ours in a 30-gas block, the third-ranked fee of 20 must be beaten.
+	for path, check := range map[string]func(body map[string]float64){
+		"/fees/estimate?percentile=50":          func(body map[string]float64) { assert.Greater(t, body["totalFee"], 20.0) },
+		"/fees/suggest?gas=10&blockGasLimit=30": func(body map[string]float64) { assert.Greater(t, body["feePerGas"]*10, 20.0) },
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]float64
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		check(body)
+	}
+}
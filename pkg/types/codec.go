@@ -0,0 +1,70 @@
+package types
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	errMalformedTxEncoding = errors.New("malformed binary transaction encoding")
+)
+
+// appendTxBinary appends the compact binary encoding of tx to buf. The layout is shared by the WAL
+// and snapshot formats: hash, signature, gas, fee per gas, arrival time (unix nanoseconds) and sequence.
+func appendTxBinary(buf []byte, tx *Tx) []byte {
+	buf = appendBinaryString(buf, tx.TxHash)
+	buf = appendBinaryString(buf, tx.Signature)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(tx.Gas))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(tx.FeePerGas))
+	var arrival int64
+	if !tx.ArrivalTime.IsZero() {
+		arrival = tx.ArrivalTime.UnixNano()
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(arrival))
+	return binary.LittleEndian.AppendUint64(buf, tx.Sequence)
+}
+
+// decodeTxBinary decodes a transaction written by appendTxBinary and returns the number of bytes consumed.
+func decodeTxBinary(data []byte) (*Tx, int, error) {
+	txHash, n, err := readBinaryString(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	signature, m, err := readBinaryString(data[n:])
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := n + m
+	if len(data)-offset < 32 {
+		return nil, 0, errMalformedTxEncoding
+	}
+	fixed := data[offset : offset+32]
+	tx := &Tx{
+		TxHash:    txHash,
+		Signature: signature,
+		Gas:       math.Float64frombits(binary.LittleEndian.Uint64(fixed[0:8])),
+		FeePerGas: math.Float64frombits(binary.LittleEndian.Uint64(fixed[8:16])),
+		Sequence:  binary.LittleEndian.Uint64(fixed[24:32]),
+	}
+	if arrival := int64(binary.LittleEndian.Uint64(fixed[16:24])); arrival != 0 {
+		tx.ArrivalTime = time.Unix(0, arrival)
+	}
+	return tx, offset + 32, nil
+}
+
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func readBinaryString(data []byte) (string, int, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return "", 0, errMalformedTxEncoding
+	}
+	end := n + int(length)
+	return string(data[n:end]), end, nil
+}
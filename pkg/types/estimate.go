@@ -0,0 +1,124 @@
+package types
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	feeHistorySize   = 256         // Evictions remembered for the eviction floor
+	feeHistoryWindow = time.Minute // Evictions older than this no longer raise the floor
+)
+
+var ErrInvalidFeeQuery = errors.New("invalid fee estimation parameters")
+
+// feeHistory remembers the total fees of recent evictions. A fee that was evicted moments ago is
+// unlikely to survive now, so estimates are raised above the highest of them.
+type feeHistory struct {
+	mu    sync.Mutex
+	fees  [feeHistorySize]float64
+	times [feeHistorySize]time.Time
+	next  int // Ring buffer position of the next eviction
+}
+
+// evicted records the eviction of tx. A nil *feeHistory records nothing.
+func (h *feeHistory) evicted(tx *Tx) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fees[h.next], h.times[h.next] = tx.TotalFee, time.Now()
+	h.next = (h.next + 1) % feeHistorySize
+}
+
+// floor returns the highest total fee evicted within feeHistoryWindow before now, or 0.
+func (h *feeHistory) floor(now time.Time) float64 {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var floor float64
+	for i, fee := range h.fees {
+		if !h.times[i].IsZero() && now.Sub(h.times[i]) <= feeHistoryWindow && fee > floor {
+			floor = fee
+		}
+	}
+	return floor
+}
+
+// feeSnapshot is the state a fee estimate is computed from.
+type feeSnapshot struct {
+	txs          []*Tx // Pooled transactions in no particular order
+	full         bool
+	evictedFloor float64 // See feeHistory.floor
+	minFeePerGas float64
+}
+
+// floor is the total fee a new transaction must beat to enter the pool and stay there.
+func (fs feeSnapshot) floor() float64 {
+	if !fs.full || len(fs.txs) == 0 {
+		return fs.evictedFloor
+	}
+	lowest := fs.txs[0].TotalFee
+	for _, tx := range fs.txs[1:] {
+		lowest = math.Min(lowest, tx.TotalFee)
+	}
+	return math.Max(fs.evictedFloor, lowest)
+}
+
+// estimateFee returns the smallest total fee that outranks targetPercentile percent of the pooled
+// transactions and beats the floor, or 0 when there is nothing to beat.
+func estimateFee(fs feeSnapshot, targetPercentile float64) (float64, error) {
+	if targetPercentile < 0 || targetPercentile > 100 || math.IsNaN(targetPercentile) {
+		return 0, errors.Wrapf(ErrInvalidFeeQuery, "percentile %v is not between 0 and 100", targetPercentile)
+	}
+	fees := make([]float64, len(fs.txs))
+	for i, tx := range fs.txs {
+		fees[i] = tx.TotalFee
+	}
+	sort.Float64s(fees)
+	threshold := fs.floor()
+	// Nearest rank: outranking k transactions means paying more than the k-th lowest fee.
+	if k := int(math.Ceil(targetPercentile / 100 * float64(len(fees)))); k > 0 {
+		threshold = math.Max(threshold, fees[k-1])
+	}
+	if threshold == 0 {
+		return 0, nil
+	}
+	return math.Nextafter(threshold, math.Inf(1)), nil
+}
+
+// suggestFeeForInclusion returns the smallest fee per gas at which a transaction using gas would be
+// included within the next blocks blocks of blockGasLimit each, assuming blocks are filled in
+// priority order, and would beat the floor. It returns the minimum fee per gas, possibly 0, when
+// there is nothing to beat.
+func suggestFeeForInclusion(fs feeSnapshot, gas, blockGasLimit float64, blocks int) (float64, error) {
+	if gas <= 0 || blockGasLimit < gas || blocks < 1 {
+		return 0, errors.Wrapf(ErrInvalidFeeQuery, "gas %v, block gas limit %v, blocks %d", gas, blockGasLimit, blocks)
+	}
+	txs := append([]*Tx(nil), fs.txs...)
+	sort.Slice(txs, func(i, j int) bool { return txs[i].outranks(txs[j]) })
+	threshold := fs.floor()
+	budget := float64(blocks)*blockGasLimit - gas // Gas the transactions ranked above ours may use
+	var used float64
+	for _, tx := range txs {
+		if used += tx.Gas; used > budget {
+			threshold = math.Max(threshold, tx.TotalFee) // The first transaction left out must be outranked
+			break
+		}
+	}
+	if threshold == 0 {
+		return fs.minFeePerGas, nil
+	}
+	feePerGas := threshold / gas
+	for feePerGas*gas <= threshold {
+		feePerGas = math.Nextafter(feePerGas, math.Inf(1))
+	}
+	return math.Max(feePerGas, fs.minFeePerGas), nil
+}
@@ -0,0 +1,164 @@
+package types_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// feePools returns constructors for both implementations, each filled with a transaction of gas 10
+// for every fee per gas in feesPerGas.
+func feePools(t *testing.T, logger logging.LoggingSystem) []struct {
+	name string
+	new  func(maxSize uint32, feesPerGas []float64, opts ...types.MempoolOption) types.Mempool
+} {
+	fill := func(memPool types.Mempool, err error, feesPerGas []float64) types.Mempool {
+		require.NoError(t, err)
+		for i, feePerGas := range feesPerGas {
+			require.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 10, feePerGas)})[0])
+		}
+		return memPool
+	}
+	return []struct {
+		name string
+		new  func(maxSize uint32, feesPerGas []float64, opts ...types.MempoolOption) types.Mempool
+	}{
+		{name: "single", new: func(maxSize uint32, feesPerGas []float64, opts ...types.MempoolOption) types.Mempool {
+			memPool, err := types.NewMempool(maxSize, logger, opts...)
+			return fill(memPool, err, feesPerGas)
+		}},
+		{name: "sharded", new: func(maxSize uint32, feesPerGas []float64, opts ...types.MempoolOption) types.Mempool {
+			memPool, err := types.NewShardedMempool(maxSize, 4, logger, opts...)
+			return fill(memPool, err, feesPerGas)
+		}},
+	}
+}
+
+func TestMempool_EstimateFee(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	oneToTen := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, pool := range feePools(t, logger) {
+		for _, tc := range []struct {
+			name       string
+			maxSize    uint32
+			feesPerGas []float64
+			percentile float64
+			wantAbove  float64 // The estimate must be the smallest total fee above this, or 0 when it is 0
+			wantErr    error
+		}{
+			{name: "empty", maxSize: 10, percentile: 50},
+			{name: "zero_percentile_with_room", maxSize: 20, feesPerGas: oneToTen, percentile: 0},
+			{name: "median", maxSize: 20, feesPerGas: oneToTen, percentile: 50, wantAbove: 50},
+			{name: "nearest_rank", maxSize: 20, feesPerGas: oneToTen, percentile: 41, wantAbove: 50},
+			{name: "maximum", maxSize: 20, feesPerGas: oneToTen, percentile: 100, wantAbove: 100},
+			{name: "full_pool_raises_floor", maxSize: 10, feesPerGas: oneToTen, percentile: 0, wantAbove: 10},
+			{name: "negative", maxSize: 10, percentile: -1, wantErr: types.ErrInvalidFeeQuery},
+			{name: "above_100", maxSize: 10, percentile: 100.5, wantErr: types.ErrInvalidFeeQuery},
+			{name: "nan", maxSize: 10, percentile: math.NaN(), wantErr: types.ErrInvalidFeeQuery},
+		} {
+			t.Run(pool.name+"/"+tc.name, func(t *testing.T) {
+				memPool := pool.new(tc.maxSize, tc.feesPerGas)
+				fee, err := memPool.EstimateFee(tc.percentile)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				if tc.wantAbove == 0 {
+					assert.Zero(t, fee)
+					return
+				}
+				assert.Equal(t, math.Nextafter(tc.wantAbove, math.Inf(1)), fee)
+			})
+		}
+	}
+}
+
+// TestMempool_EstimateFeeEvictionHistory checks that recently evicted fees keep the estimate up
+// after the pool drains, since a fee that was just evicted is unlikely to survive now.
+func TestMempool_EstimateFeeEvictionHistory(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, pool := range feePools(t, logger) {
+		t.Run(pool.name, func(t *testing.T) {
+			memPool := pool.new(2, []float64{5, 6, 7}) // tx-0 is evicted by tx-2
+			assert.Equal(t, 1, memPool.Update([]*types.Tx{types.NewTx(logger, "tx-1", "sig", 10, 6)}))
+			assert.Equal(t, 1, memPool.Update([]*types.Tx{types.NewTx(logger, "tx-2", "sig", 10, 7)}))
+			require.Zero(t, memPool.MempoolLen())
+
+			fee, err := memPool.EstimateFee(0)
+			require.NoError(t, err)
+			assert.Equal(t, math.Nextafter(50, math.Inf(1)), fee)
+			feePerGas, err := memPool.SuggestFeeForInclusion(10, 100, 1)
+			require.NoError(t, err)
+			assert.Greater(t, feePerGas*10, 50.0)
+		})
+	}
+}
+
+func TestMempool_SuggestFeeForInclusion(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	oneToTen := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, pool := range feePools(t, logger) {
+		for _, tc := range []struct {
+			name          string
+			maxSize       uint32
+			feesPerGas    []float64
+			opts          []types.MempoolOption
+			gas           float64
+			blockGasLimit float64
+			blocks        int
+			wantAbove     float64 // The suggested fee per gas times gas must just exceed this total fee
+			want          float64 // Exact suggestion when wantAbove is 0
+			wantErr       error
+		}{
+			// Two 10-gas transactions fit ahead of ours in a 30-gas block, so the third-ranked (80) must be beaten.
+			{name: "one_block", maxSize: 20, feesPerGas: oneToTen, gas: 10, blockGasLimit: 30, blocks: 1, wantAbove: 80},
+			{name: "two_blocks", maxSize: 20, feesPerGas: oneToTen, gas: 10, blockGasLimit: 30, blocks: 2, wantAbove: 50},
+			{name: "larger_transaction", maxSize: 20, feesPerGas: oneToTen, gas: 20, blockGasLimit: 30, blocks: 1, wantAbove: 90},
+			{name: "everything_fits", maxSize: 20, feesPerGas: oneToTen, gas: 10, blockGasLimit: 200, blocks: 1},
+			{name: "everything_fits_min_fee", maxSize: 20, feesPerGas: oneToTen, opts: []types.MempoolOption{types.WithMinFeePerGas(0.5)}, gas: 10, blockGasLimit: 200, blocks: 1, want: 0.5},
+			{name: "full_pool_raises_floor", maxSize: 10, feesPerGas: oneToTen, gas: 10, blockGasLimit: 200, blocks: 1, wantAbove: 10},
+			{name: "zero_gas", maxSize: 10, gas: 0, blockGasLimit: 30, blocks: 1, wantErr: types.ErrInvalidFeeQuery},
+			{name: "gas_above_limit", maxSize: 10, gas: 40, blockGasLimit: 30, blocks: 1, wantErr: types.ErrInvalidFeeQuery},
+			{name: "zero_blocks", maxSize: 10, gas: 10, blockGasLimit: 30, blocks: 0, wantErr: types.ErrInvalidFeeQuery},
+		} {
+			t.Run(pool.name+"/"+tc.name, func(t *testing.T) {
+				memPool := pool.new(tc.maxSize, tc.feesPerGas, tc.opts...)
+				feePerGas, err := memPool.SuggestFeeForInclusion(tc.gas, tc.blockGasLimit, tc.blocks)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				if tc.wantAbove == 0 {
+					assert.Equal(t, tc.want, feePerGas)
+					return
+				}
+				assert.Greater(t, feePerGas*tc.gas, tc.wantAbove)
+				assert.InDelta(t, tc.wantAbove/tc.gas, feePerGas, 1e-9)
+
+				// A transaction paying the suggestion is admitted and ranks inside the budget.
+				tx := types.NewTx(logger, "suggested", "sig", tc.gas, feePerGas)
+				require.NoError(t, memPool.AddTxs([]*types.Tx{tx})[0])
+				ranked := memPool.ReapMaxTxs(int(memPool.MempoolLen()))
+				var used float64
+				for _, reaped := range ranked {
+					used += reaped.Gas
+					if reaped.TxHash == tx.TxHash {
+						break
+					}
+				}
+				assert.LessOrEqual(t, used, float64(tc.blocks)*tc.blockGasLimit)
+			})
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package types
+
+import (
+	"compress/gzip"
+	"container/heap"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/logging"
+)
+
+const (
+	defaultExportFileName  = "./prioritized-transactions.txt"
+	defaultExportBatchSize = 4096
+	exportStdoutPath       = "-"
+)
+
+var (
+	ErrUnsupportedCompression = errors.New("unsupported export compression")
+)
+
+// ExportResult describes a completed export so callers can log and verify it.
+type ExportResult struct {
+	Path     string // Final location of the exported file
+	Records  int    // Number of transactions written
+	Bytes    int64  // Size of the exported file
+	Checksum string // Hex encoded SHA-256 of the file contents
+}
+
+// ExportOptions controls how ExportTo streams the pool.
+type ExportOptions struct {
+	Format    string // Name of a registered ExportFormat, defaults to the text format
+	BatchSize int    // Transactions selected per pass over the pool; bounds export memory. Defaults to 4096
+	Limit     int    // Maximum number of transactions to export, 0 exports the whole pool
+}
+
+// ExportTo streams pooled transactions to w from highest to lowest priority without modifying the pool.
+// Memory use is bounded by opts.BatchSize: each pass takes the lock, selects the next batch of
+// transactions ranking below the last one written, and writes it with the lock released. Transactions
+// admitted or evicted while an export is in progress may or may not appear, but none appear twice.
+//
+// Each pass scans the whole pool under the lock in O(n log BatchSize), and an export of n transactions
+// takes n/BatchSize passes. A larger BatchSize holds the lock fewer times at the cost of more memory;
+// BenchmarkMempool_ExportTo measures both.
+func (mp *mempool) ExportTo(w io.Writer, opts ExportOptions) (int, error) {
+	return exportTo(w, opts, mp.nextExportBatch)
+}
+
+// ReapMaxTxs returns up to max of the highest priority transactions, best first, without removing
+// them from the pool. A max of 0 or less returns every pooled transaction.
+func (mp *mempool) ReapMaxTxs(max int) []*Tx {
+	return reapMaxTxs(max, mp.MempoolLen(), mp.nextExportBatch)
+}
+
+// batchSelector fills batch with the highest ranked pooled transactions ranking below cursor, up to
+// the capacity of batch. Pools implement it so export and reaping work on any of them.
+type batchSelector func(batch rankHeap, cursor *Tx) rankHeap
+
+func exportTo(w io.Writer, opts ExportOptions, next batchSelector) (int, error) {
+	format, err := LookupExportFormat(opts.Format)
+	if err != nil {
+		return 0, err
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultExportBatchSize
+	}
+	tw := format.NewWriter(w)
+	batch := make(rankHeap, 0, batchSize)
+	var cursor *Tx // Lowest ranked transaction written so far
+	var written int
+	for opts.Limit <= 0 || written < opts.Limit {
+		batch = next(batch[:0], cursor)
+		if len(batch) == 0 {
+			break
+		}
+		sort.Slice(batch, func(i, j int) bool { return batch[i].outranks(batch[j]) })
+		for _, tx := range batch {
+			if opts.Limit > 0 && written >= opts.Limit {
+				break
+			}
+			if err := tw.WriteTx(tx); err != nil {
+				return written, errors.Wrap(err, "failed to write transaction")
+			}
+			written++
+		}
+		cursor = batch[len(batch)-1]
+	}
+	if err := tw.Close(); err != nil {
+		return written, errors.Wrap(err, "failed to flush export")
+	}
+	return written, nil
+}
+
+func reapMaxTxs(max int, poolLen uint32, next batchSelector) []*Tx {
+	if max <= 0 || max > int(poolLen) {
+		max = int(poolLen)
+	}
+	if max == 0 {
+		return nil
+	}
+	batch := next(make(rankHeap, 0, max), nil)
+	sort.Slice(batch, func(i, j int) bool { return batch[i].outranks(batch[j]) })
+	return batch
+}
+
+// nextExportBatch fills batch with the highest ranked transactions that rank below cursor (or the
+// highest ranked overall when cursor is nil), up to the capacity of batch.
+func (mp *mempool) nextExportBatch(batch rankHeap, cursor *Tx) rankHeap {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	for _, tx := range mp.txHeap {
+		if cursor != nil && !cursor.outranks(tx) {
+			continue
+		}
+		if len(batch) < cap(batch) {
+			heap.Push(&batch, tx)
+		} else if tx.outranks(batch[0]) {
+			batch[0] = tx
+			heap.Fix(&batch, 0)
+		}
+	}
+	return batch
+}
+
+// ExportToFile exports the contents of the mempool to the file set by WithExport, sorted by TotalFee
+// descending. The output is streamed into a temporary file in the destination directory, fsynced and renamed
+// into place, so a crash midway never leaves a truncated export behind. A ".gz" destination is
+// gzip compressed and "-" writes to standard output.
+func (mp *mempool) ExportToFile() (ExportResult, error) {
+	return ExportToPath(mp, mp.exportPath, mp.exportFormat, mp.logger)
+}
+
+// ExportToPath exports pool to fileName in the named format like ExportToFile. An empty fileName uses the default export path and an empty format the text format.
+func ExportToPath(pool Mempool, fileName, format string, logger logging.LoggingSystem) (ExportResult, error) {
+	if fileName == "" {
+		fileName = defaultExportFileName
+	}
+	logger.Info("Exporting transactions", zap.Uint32("count", pool.MempoolLen()))
+
+	if fileName == exportStdoutPath {
+		result, err := exportToWriter(pool, os.Stdout, fileName, format)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to write to standard output")
+		}
+		logExportResult(logger, result)
+		return result, nil
+	}
+
+	dir := filepath.Dir(fileName)
+	tmp, err := os.CreateTemp(dir, filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return ExportResult{Path: fileName}, errors.Wrapf(err, "failed to create temp file for %s", fileName)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	result, err := exportToWriter(pool, tmp, fileName, format)
+	if err != nil {
+		tmp.Close()
+		return result, errors.Wrapf(err, "failed to write to file %s", fileName)
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return result, errors.Wrapf(err, "failed to sync file %s", fileName)
+	}
+	if err = tmp.Close(); err != nil {
+		return result, errors.Wrapf(err, "failed to close file %s", fileName)
+	}
+	if err = os.Rename(tmp.Name(), fileName); err != nil {
+		return result, errors.Wrapf(err, "failed to move export into place at %s", fileName)
+	}
+	syncDir(dir)
+	logExportResult(logger, result)
+	return result, nil
+}
+
+// unsupportedExportCompression maps the extensions of compression formats exports cannot produce to
+// the format's name. They are refused rather than written uncompressed under a misleading name.
+var unsupportedExportCompression = map[string]string{
+	".bz2": "bzip2", ".bzip2": "bzip2", ".xz": "xz", ".lzma": "lzma", ".lz": "lzip", ".lz4": "lz4",
+	".zst": "zstd", ".zstd": "zstd", ".br": "brotli", ".sz": "snappy", ".z": "compress", ".zip": "zip",
+}
+
+// CheckCompression returns an error wrapping ErrUnsupportedCompression when the extension of path
+// names a compression format other than gzip, which exports cannot write.
+func CheckCompression(path string) error {
+	if name, ok := unsupportedExportCompression[strings.ToLower(filepath.Ext(path))]; ok {
+		return errors.Wrapf(ErrUnsupportedCompression, "%s (%s), only gzip is supported", name, path)
+	}
+	return nil
+}
+
+// exportToWriter exports the pool into w, compressing it when the extension of fileName asks for it.
+// The byte count and checksum in the result describe the bytes written to w.
+func exportToWriter(pool Mempool, w io.Writer, fileName, format string) (ExportResult, error) {
+	result := ExportResult{Path: fileName}
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(w, hash)}
+
+	var out io.Writer = counter
+	var gz *gzip.Writer
+	if err := CheckCompression(fileName); err != nil {
+		return result, err
+	}
+	if ext := strings.ToLower(filepath.Ext(fileName)); ext == ".gz" || ext == ".gzip" {
+		gz = gzip.NewWriter(counter)
+		out = gz
+	}
+
+	var err error
+	if result.Records, err = pool.ExportTo(out, ExportOptions{Format: format}); err != nil {
+		return result, err
+	}
+	if gz != nil {
+		if err = gz.Close(); err != nil {
+			return result, errors.Wrap(err, "failed to finish gzip stream")
+		}
+	}
+	result.Bytes = counter.n
+	result.Checksum = hex.EncodeToString(hash.Sum(nil))
+	return result, nil
+}
+
+func logExportResult(logger logging.LoggingSystem, result ExportResult) {
+	logger.Info("Exported transactions to file", zap.Int("records", result.Records), zap.Int64("bytes", result.Bytes),
+		zap.String("sha256", result.Checksum), zap.String("fileName", result.Path))
+}
+
+// appendTextRecord appends the line format read by cmd/mempool, equivalent to
+// fmt.Sprintf("TxHash=%v Gas=%v FeePerGas=%v Signature=%v TotalFee=%v \n", ...) without allocating.
+func appendTextRecord(buf []byte, tx *Tx) []byte {
+	buf = append(buf, "TxHash="...)
+	buf = append(buf, tx.TxHash...)
+	buf = append(buf, " Gas="...)
+	buf = strconv.AppendFloat(buf, tx.Gas, 'g', -1, 64)
+	buf = append(buf, " FeePerGas="...)
+	buf = strconv.AppendFloat(buf, tx.FeePerGas, 'g', -1, 64)
+	buf = append(buf, " Signature="...)
+	buf = append(buf, tx.Signature...)
+	buf = append(buf, " TotalFee="...)
+	buf = strconv.AppendFloat(buf, tx.TotalFee, 'g', -1, 64)
+	return append(buf, " \n"...)
+}
+
+// rankHeap is a min-heap of transactions ordered by priority (see Tx.outranks), used to select
+// the top transactions of a pass without sorting the whole pool.
+type rankHeap []*Tx
+
+func (h rankHeap) Len() int           { return len(h) }
+func (h rankHeap) Less(i, j int) bool { return h[j].outranks(h[i]) }
+func (h rankHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *rankHeap) Push(x interface{}) {
+	*h = append(*h, x.(*Tx))
+}
+
+func (h *rankHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
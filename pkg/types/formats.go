@@ -0,0 +1,442 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatText   = "text"
+	FormatJSONL  = "jsonl"
+	FormatCSV    = "csv"
+	FormatBinary = "binary"
+)
+
+// Field names of key=value lines, written by the text export and read by ParseKeyValueFields.
+const (
+	KeyTxHash    = "TxHash"
+	KeyGas       = "Gas"
+	KeyFeePerGas = "FeePerGas"
+	KeySignature = "Signature"
+	KeySender    = "Sender"
+	KeyTotalFee  = "TotalFee"
+)
+
+var (
+	ErrUnknownFormat   = errors.New("unknown export format")
+	ErrMalformedRecord = errors.New("malformed export record")
+	ErrMalformedLine   = errors.New("malformed line")
+	ErrMissingField    = errors.New("missing required field")
+	ErrUnknownField    = errors.New("unknown field")
+	ErrDuplicateField  = errors.New("duplicate field")
+
+	binaryExportMagic = []byte("MPTX\x01") // Magic plus format version
+	csvExportHeader   = []string{"tx_hash", "gas", "fee_per_gas", "signature", "total_fee"}
+)
+
+// ExportFormat encodes transactions for export and decodes them again for re-import.
+type ExportFormat interface {
+	Name() string                   // Name used to select the format in configuration.
+	NewWriter(w io.Writer) TxWriter // Returns a writer that encodes transactions to w.
+	NewReader(r io.Reader) TxReader // Returns a reader that decodes transactions written by NewWriter.
+}
+
+// TxWriter encodes transactions one at a time. Close must be called to flush buffered output;
+// it does not close the underlying writer.
+type TxWriter interface {
+	WriteTx(tx *Tx) error
+	Close() error
+}
+
+// TxReader decodes transactions one at a time and returns io.EOF after the last one.
+type TxReader interface {
+	ReadTx() (*Tx, error)
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]ExportFormat{}
+)
+
+func init() {
+	RegisterExportFormat(textFormat{})
+	RegisterExportFormat(jsonlFormat{})
+	RegisterExportFormat(csvFormat{})
+	RegisterExportFormat(binaryFormat{})
+}
+
+// RegisterExportFormat makes f selectable by its name, replacing any format registered under the same name.
+func RegisterExportFormat(f ExportFormat) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[f.Name()] = f
+}
+
+// LookupExportFormat returns the format registered under name. An empty name selects the text format.
+func LookupExportFormat(name string) (ExportFormat, error) {
+	if name == "" {
+		name = FormatText
+	}
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	f, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownFormat, "%q (available: %s)", name, strings.Join(exportFormatNamesLocked(), ", "))
+	}
+	return f, nil
+}
+
+// DetectExportFormat guesses which built-in format wrote an export from its first bytes, and falls
+// back to the text format.
+func DetectExportFormat(prefix []byte) string {
+	switch {
+	case bytes.HasPrefix(prefix, binaryExportMagic):
+		return FormatBinary
+	case bytes.HasPrefix(prefix, []byte(strings.Join(csvExportHeader, ","))):
+		return FormatCSV
+	case bytes.HasPrefix(bytes.TrimLeft(prefix, " \t\r\n"), []byte("{")):
+		return FormatJSONL
+	}
+	return FormatText
+}
+
+// ExportFormats returns the names of all registered formats in sorted order.
+func ExportFormats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return exportFormatNamesLocked()
+}
+
+func exportFormatNamesLocked() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// textFormat is the original "TxHash=... Gas=... FeePerGas=... Signature=... TotalFee=... " line format.
+type textFormat struct{}
+
+func (textFormat) Name() string { return FormatText }
+
+func (textFormat) NewWriter(w io.Writer) TxWriter { return &textWriter{w: bufio.NewWriter(w)} }
+
+func (textFormat) NewReader(r io.Reader) TxReader { return &textReader{s: bufio.NewScanner(r)} }
+
+type textWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (tw *textWriter) WriteTx(tx *Tx) error {
+	tw.buf = appendTextRecord(tw.buf[:0], tx)
+	_, err := tw.w.Write(tw.buf)
+	return err
+}
+
+func (tw *textWriter) Close() error { return tw.w.Flush() }
+
+type textReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func (tr *textReader) ReadTx() (*Tx, error) {
+	for tr.s.Scan() {
+		tr.line++
+		line := strings.TrimSpace(tr.s.Text())
+		if line == "" {
+			continue
+		}
+		values, err := ParseKeyValueFields(line, KeyTxHash, KeyGas, KeyFeePerGas, KeySignature, KeyTotalFee)
+		if err != nil {
+			return nil, errors.Wrapf(ErrMalformedRecord, "line %d: %v", tr.line, err)
+		}
+		tx, err := newExportedTx(values[KeyTxHash], values[KeyGas], values[KeyFeePerGas], values[KeySignature], values[KeyTotalFee])
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", tr.line)
+		}
+		tx.Sender = values[KeySender]
+		return tx, nil
+	}
+	if err := tr.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ParseKeyValueFields splits a line of whitespace separated "Key=Value" fields, such as
+// "TxHash=0xabc Gas=21000 FeePerGas=0.5 Signature=0xdef", into values keyed by field name. Fields
+// may appear in any order, but each at most once, and every key in required must appear. Keys
+// other than the Key constants are rejected.
+func ParseKeyValueFields(line string, required ...string) (map[string]string, error) {
+	values := make(map[string]string, 5)
+	for _, field := range strings.Fields(line) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, errors.Wrapf(ErrMalformedLine, "field %q is not in Key=Value form", field)
+		}
+		switch key {
+		case KeyTxHash, KeyGas, KeyFeePerGas, KeySignature, KeySender, KeyTotalFee:
+		default:
+			return nil, errors.Wrapf(ErrUnknownField, "%q", key)
+		}
+		if _, seen := values[key]; seen {
+			return nil, errors.Wrapf(ErrDuplicateField, "%q", key)
+		}
+		values[key] = value
+	}
+	for _, key := range required {
+		if _, ok := values[key]; !ok {
+			return nil, errors.Wrapf(ErrMissingField, "%q", key)
+		}
+	}
+	return values, nil
+}
+
+// jsonlFormat writes one JSON object per line.
+type jsonlFormat struct{}
+
+type jsonlRecord struct {
+	TxHash    string  `json:"txHash"`
+	Gas       float64 `json:"gas"`
+	FeePerGas float64 `json:"feePerGas"`
+	Signature string  `json:"signature"`
+	TotalFee  float64 `json:"totalFee"`
+}
+
+func (jsonlFormat) Name() string { return FormatJSONL }
+
+func (jsonlFormat) NewWriter(w io.Writer) TxWriter {
+	bw := bufio.NewWriter(w)
+	return &jsonlWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (jsonlFormat) NewReader(r io.Reader) TxReader {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	return &jsonlReader{dec: dec}
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (jw *jsonlWriter) WriteTx(tx *Tx) error {
+	return jw.enc.Encode(jsonlRecord{TxHash: tx.TxHash, Gas: tx.Gas, FeePerGas: tx.FeePerGas, Signature: tx.Signature, TotalFee: tx.TotalFee})
+}
+
+func (jw *jsonlWriter) Close() error { return jw.w.Flush() }
+
+type jsonlReader struct {
+	dec    *json.Decoder
+	record int
+}
+
+func (jr *jsonlReader) ReadTx() (*Tx, error) {
+	var rec jsonlRecord
+	if err := jr.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Wrapf(ErrMalformedRecord, "record %d: %v", jr.record+1, err)
+	}
+	jr.record++
+	return &Tx{TxHash: rec.TxHash, Gas: rec.Gas, FeePerGas: rec.FeePerGas, Signature: rec.Signature, TotalFee: rec.TotalFee}, nil
+}
+
+// csvFormat writes a header row followed by one row per transaction.
+type csvFormat struct{}
+
+func (csvFormat) Name() string { return FormatCSV }
+
+func (csvFormat) NewWriter(w io.Writer) TxWriter { return &csvWriter{w: csv.NewWriter(w)} }
+
+func (csvFormat) NewReader(r io.Reader) TxReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvExportHeader)
+	return &csvReader{r: cr}
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (cw *csvWriter) WriteTx(tx *Tx) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	return cw.w.Write([]string{
+		tx.TxHash,
+		strconv.FormatFloat(tx.Gas, 'g', -1, 64),
+		strconv.FormatFloat(tx.FeePerGas, 'g', -1, 64),
+		tx.Signature,
+		strconv.FormatFloat(tx.TotalFee, 'g', -1, 64),
+	})
+}
+
+// Close writes the header even for an empty export so readers can always validate it.
+func (cw *csvWriter) Close() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) writeHeader() error {
+	if cw.headerWritten {
+		return nil
+	}
+	cw.headerWritten = true
+	return cw.w.Write(csvExportHeader)
+}
+
+type csvReader struct {
+	r          *csv.Reader
+	headerRead bool
+}
+
+func (cr *csvReader) ReadTx() (*Tx, error) {
+	if !cr.headerRead {
+		header, err := cr.r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errors.Wrap(ErrMalformedRecord, "missing CSV header")
+			}
+			return nil, errors.Wrapf(ErrMalformedRecord, "CSV header: %v", err)
+		}
+		if strings.Join(header, ",") != strings.Join(csvExportHeader, ",") {
+			return nil, errors.Wrapf(ErrMalformedRecord, "unexpected CSV header %q", strings.Join(header, ","))
+		}
+		cr.headerRead = true
+	}
+	row, err := cr.r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Wrapf(ErrMalformedRecord, "%v", err)
+	}
+	tx, err := newExportedTx(row[0], row[1], row[2], row[3], row[4])
+	if err != nil {
+		line, _ := cr.r.FieldPos(0)
+		return nil, errors.Wrapf(err, "line %d", line)
+	}
+	return tx, nil
+}
+
+// binaryFormat writes a magic header followed by uvarint length-prefixed records in the
+// appendTxBinary layout. TotalFee is not stored; readers recompute it from Gas and FeePerGas.
+type binaryFormat struct{}
+
+func (binaryFormat) Name() string { return FormatBinary }
+
+func (binaryFormat) NewWriter(w io.Writer) TxWriter { return &binaryWriter{w: bufio.NewWriter(w)} }
+
+func (binaryFormat) NewReader(r io.Reader) TxReader { return &binaryReader{r: bufio.NewReader(r)} }
+
+type binaryWriter struct {
+	w             *bufio.Writer
+	buf           []byte
+	headerWritten bool
+}
+
+func (bw *binaryWriter) WriteTx(tx *Tx) error {
+	if err := bw.writeHeader(); err != nil {
+		return err
+	}
+	bw.buf = appendTxBinary(bw.buf[:0], tx)
+	var prefix [binary.MaxVarintLen64]byte
+	if _, err := bw.w.Write(prefix[:binary.PutUvarint(prefix[:], uint64(len(bw.buf)))]); err != nil {
+		return err
+	}
+	_, err := bw.w.Write(bw.buf)
+	return err
+}
+
+func (bw *binaryWriter) Close() error {
+	if err := bw.writeHeader(); err != nil {
+		return err
+	}
+	return bw.w.Flush()
+}
+
+func (bw *binaryWriter) writeHeader() error {
+	if bw.headerWritten {
+		return nil
+	}
+	bw.headerWritten = true
+	_, err := bw.w.Write(binaryExportMagic)
+	return err
+}
+
+type binaryReader struct {
+	r          *bufio.Reader
+	buf        []byte
+	headerRead bool
+	record     int
+}
+
+func (br *binaryReader) ReadTx() (*Tx, error) {
+	if !br.headerRead {
+		magic := make([]byte, len(binaryExportMagic))
+		if _, err := io.ReadFull(br.r, magic); err != nil || !bytes.Equal(magic, binaryExportMagic) {
+			return nil, errors.Wrap(ErrMalformedRecord, "missing or unsupported binary export header")
+		}
+		br.headerRead = true
+	}
+	length, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.Wrapf(ErrMalformedRecord, "record %d length: %v", br.record+1, err)
+	}
+	if length > walMaxRecordSize {
+		return nil, errors.Wrapf(ErrMalformedRecord, "record %d length %d is too large", br.record+1, length)
+	}
+	if uint64(cap(br.buf)) < length {
+		br.buf = make([]byte, length)
+	}
+	br.buf = br.buf[:length]
+	if _, err = io.ReadFull(br.r, br.buf); err != nil {
+		return nil, errors.Wrapf(ErrMalformedRecord, "record %d: %v", br.record+1, err)
+	}
+	tx, n, err := decodeTxBinary(br.buf)
+	if err != nil || n != len(br.buf) {
+		return nil, errors.Wrapf(ErrMalformedRecord, "record %d is corrupt", br.record+1)
+	}
+	br.record++
+	tx.calculateTotalFees()
+	return tx, nil
+}
+
+func newExportedTx(txHash, gas, feePerGas, signature, totalFee string) (*Tx, error) {
+	tx := &Tx{TxHash: txHash, Signature: signature}
+	var err error
+	if tx.Gas, err = strconv.ParseFloat(gas, 64); err != nil {
+		return nil, errors.Wrapf(ErrMalformedRecord, "invalid gas %q", gas)
+	}
+	if tx.FeePerGas, err = strconv.ParseFloat(feePerGas, 64); err != nil {
+		return nil, errors.Wrapf(ErrMalformedRecord, "invalid fee per gas %q", feePerGas)
+	}
+	if tx.TotalFee, err = strconv.ParseFloat(totalFee, 64); err != nil {
+		return nil, errors.Wrapf(ErrMalformedRecord, "invalid total fee %q", totalFee)
+	}
+	return tx, nil
+}
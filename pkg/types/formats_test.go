@@ -0,0 +1,126 @@
+package types_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/types"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func goldenTxs() []*types.Tx {
+	arrival := time.Unix(0, 1700000000123456789)
+	return []*types.Tx{
+		{TxHash: "0xhigh", Gas: 60, FeePerGas: 2, Signature: "0xsig1", TotalFee: 120, ArrivalTime: arrival, Sequence: 1},
+		{TxHash: "0xmid", Gas: 54.5, FeePerGas: 0.4934, Signature: "0xsig2", TotalFee: 54.5 * 0.4934, ArrivalTime: arrival.Add(time.Second), Sequence: 2},
+		{TxHash: "0xlow", Gas: 10, FeePerGas: 0.1, Signature: "0xsig,3", TotalFee: 1, Sequence: 3},
+	}
+}
+
+func TestExportFormats_Golden(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		golden string
+	}{
+		{format: types.FormatText, golden: "export_text.golden"},
+		{format: types.FormatJSONL, golden: "export_jsonl.golden"},
+		{format: types.FormatCSV, golden: "export_csv.golden"},
+		{format: types.FormatBinary, golden: "export_binary.golden"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			format, err := types.LookupExportFormat(tc.format)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			tw := format.NewWriter(&buf)
+			for _, tx := range goldenTxs() {
+				require.NoError(t, tw.WriteTx(tx))
+			}
+			require.NoError(t, tw.Close())
+
+			goldenPath := filepath.Join("testdata", tc.golden)
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, buf.Bytes(), 0o644))
+			}
+			golden, err := os.ReadFile(goldenPath)
+			require.NoError(t, err)
+			assert.Equal(t, golden, buf.Bytes(), "output differs from %s (run with -update to regenerate)", goldenPath)
+			assert.Equal(t, tc.format, types.DetectExportFormat(golden[:min(len(golden), 64)]))
+
+			// The matching reader must re-import the golden file.
+			tr := format.NewReader(bytes.NewReader(golden))
+			for _, want := range goldenTxs() {
+				got, err := tr.ReadTx()
+				require.NoError(t, err)
+				assert.Equal(t, want.TxHash, got.TxHash)
+				assert.Equal(t, want.Gas, got.Gas)
+				assert.Equal(t, want.FeePerGas, got.FeePerGas)
+				assert.Equal(t, want.Signature, got.Signature)
+				assert.InDelta(t, want.TotalFee, got.TotalFee, 1e-9)
+				if tc.format == types.FormatBinary {
+					assert.Equal(t, want.Sequence, got.Sequence)
+					assert.True(t, want.ArrivalTime.Equal(got.ArrivalTime))
+				}
+			}
+			_, err = tr.ReadTx()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestExportFormats_Malformed(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{name: "text_missing_field", format: types.FormatText, input: "TxHash=a Gas=1 FeePerGas=1 Signature=s\n"},
+		{name: "text_duplicate_field", format: types.FormatText, input: "TxHash=a Gas=1 Gas=2 FeePerGas=1 Signature=s TotalFee=1\n"},
+		{name: "text_unknown_field", format: types.FormatText, input: "TxHash=a Gas=1 FeePerGas=1 Signature=s TotalFee=1 Nonce=3\n"},
+		{name: "text_bad_number", format: types.FormatText, input: "TxHash=a Gas=x FeePerGas=1 Signature=s TotalFee=1\n"},
+		{name: "jsonl_unknown_field", format: types.FormatJSONL, input: `{"txHash":"a","extra":1}` + "\n"},
+		{name: "csv_bad_header", format: types.FormatCSV, input: "hash,gas\n"},
+		{name: "binary_bad_magic", format: types.FormatBinary, input: "TxHash=a"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := types.LookupExportFormat(tc.format)
+			require.NoError(t, err)
+			_, err = format.NewReader(strings.NewReader(tc.input)).ReadTx()
+			require.ErrorIs(t, err, types.ErrMalformedRecord)
+		})
+	}
+}
+
+// TestExportFormats_TextKeyValue checks that the text reader shares the key=value input parser, so it
+// reads fields in any order and keeps the optional Sender.
+func TestExportFormats_TextKeyValue(t *testing.T) {
+	format, err := types.LookupExportFormat(types.FormatText)
+	require.NoError(t, err)
+	tx, err := format.NewReader(strings.NewReader("TotalFee=6 Sender=alice Signature=s FeePerGas=3 Gas=2 TxHash=a\n")).ReadTx()
+	require.NoError(t, err)
+	assert.Equal(t, &types.Tx{TxHash: "a", Gas: 2, FeePerGas: 3, TotalFee: 6, Signature: "s", Sender: "alice"}, tx)
+}
+
+func TestLookupExportFormat(t *testing.T) {
+	format, err := types.LookupExportFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, types.FormatText, format.Name())
+
+	_, err = types.LookupExportFormat("xml")
+	require.ErrorIs(t, err, types.ErrUnknownFormat)
+	assert.Equal(t, []string{"binary", "csv", "jsonl", "text"}, types.ExportFormats())
+}
+
+func TestDetectExportFormat_Empty(t *testing.T) {
+	assert.Equal(t, types.FormatText, types.DetectExportFormat(nil))
+}
@@ -0,0 +1,174 @@
+package types
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/logging"
+)
+
+// Listener observes changes to a mempool without wrapping it. Each listener is called from its own
+// goroutine, never with a mempool lock held, so a slow listener cannot stall processors.
+//
+// Events are queued at the moment the pool changes, so a listener sees them in the order they
+// happened: a transaction's OnAdded precedes its OnEvicted or OnRemoved, and the OnEvicted of a
+// replaced transaction precedes the OnAdded of the transaction that replaced it. When a listener
+// falls so far behind that its queue is full, further events are discarded and counted in a warning;
+// a listener that needs to know implements OverflowListener. CloseTxInsertChan stops delivery once every queued event has been handled; later events are not
+// delivered.
+type Listener interface {
+	OnAdded(tx *Tx)                  // tx entered the pool
+	OnEvicted(tx *Tx, evictedBy *Tx) // tx was replaced by evictedBy, or evictedBy is nil when the pool shrank
+	OnRejected(tx *Tx, err error)    // tx was refused by AddTx, AddTxs, Reinject or a processor
+	OnRemoved(tx *Tx)                // tx was removed by Update because a block included it
+}
+
+// OverflowListener is a Listener told when its events were discarded because its queue was full.
+type OverflowListener interface {
+	Listener
+	// OnDropped reports that count events were discarded since the previous call. It is called from
+	// the listener's goroutine once it catches up, and at CloseTxInsertChan if any loss is unreported.
+	OnDropped(count uint64)
+}
+
+// WithListener registers l to receive pool events through a queue of bufferSize events.
+func WithListener(l Listener, bufferSize int) MempoolOption {
+	return func(mp *mempool) {
+		mp.events.listeners = append(mp.events.listeners, &listenerQueue{listener: l, events: make(chan event, bufferSize), done: make(chan struct{})})
+	}
+}
+
+type eventKind int
+
+const (
+	eventAdded eventKind = iota
+	eventEvicted
+	eventRejected
+	eventRemoved
+)
+
+type event struct {
+	kind      eventKind
+	tx        *Tx
+	evictedBy *Tx
+	err       error
+}
+
+// listenerQueue delivers events to one listener from a dedicated goroutine.
+type listenerQueue struct {
+	listener Listener
+	events   chan event
+	dropped  uint64        // Events discarded because the queue was full, accessed atomically
+	mu       sync.RWMutex  // Held for reading to send to events, and for writing to close it
+	closed   bool          // Set once events is closed, guarded by mu
+	done     chan struct{} // Closed when the delivery goroutine has handled every queued event
+}
+
+// observers fans pool events out to the status index and registered listeners. Events must be
+// emitted while holding the lock that protects the change they describe, which is what orders them.
+type observers struct {
+	status    *statusIndex // Optional, see WithStatusIndex
+	history   *feeHistory  // Recent evictions for fee estimates
+	stats     *poolStats   // Running totals for Stats
+	listeners []*listenerQueue
+}
+
+// start launches one delivery goroutine per listener. They run until close.
+func (o *observers) start(logger logging.LoggingSystem) {
+	for _, q := range o.listeners {
+		go q.deliver(logger)
+	}
+}
+
+// close stops accepting events and waits for every listener to handle the events already queued.
+// It is safe to call more than once.
+func (o *observers) close() {
+	for _, q := range o.listeners {
+		q.mu.Lock()
+		if !q.closed {
+			q.closed = true
+			close(q.events)
+		}
+		q.mu.Unlock()
+	}
+	for _, q := range o.listeners {
+		<-q.done
+	}
+}
+
+func (o *observers) added(tx *Tx) {
+	o.stats.added(tx)
+	o.emit(event{kind: eventAdded, tx: tx})
+}
+
+// evicted reports that tx was evicted by evictedBy, which is nil when the pool shrank.
+func (o *observers) evicted(tx, evictedBy *Tx) {
+	o.status.evicted(tx, evictedBy)
+	o.history.evicted(tx)
+	o.stats.removed(tx)
+	o.emit(event{kind: eventEvicted, tx: tx, evictedBy: evictedBy})
+}
+
+// rejected reports that tx was refused with err, which wraps one of the package's sentinel errors.
+// Duplicates are not recorded in the status index so a hash keeps the status of its first submission.
+func (o *observers) rejected(tx *Tx, err error) {
+	if !errors.Is(err, ErrDuplicateTx) {
+		o.status.rejected(tx.TxHash, errors.Cause(err))
+	}
+	o.emit(event{kind: eventRejected, tx: tx, err: err})
+}
+
+// removed reports that tx was removed by Update.
+func (o *observers) removed(tx *Tx) {
+	o.status.included(tx)
+	o.stats.removed(tx)
+	o.emit(event{kind: eventRemoved, tx: tx})
+}
+
+func (o *observers) emit(e event) {
+	for _, q := range o.listeners {
+		q.mu.RLock()
+		if !q.closed {
+			select {
+			case q.events <- e:
+			default:
+				atomic.AddUint64(&q.dropped, 1)
+			}
+		}
+		q.mu.RUnlock()
+	}
+}
+
+func (q *listenerQueue) deliver(logger logging.LoggingSystem) {
+	defer close(q.done)
+	var reported uint64
+	// Overflows are reported here rather than where they happen to keep logging off the hot path.
+	reportDropped := func() {
+		dropped := atomic.LoadUint64(&q.dropped)
+		if dropped == reported {
+			return
+		}
+		logger.Named("mempool/listener").Warn("listener queue full, events discarded", zap.Uint64("discarded", dropped-reported), zap.Uint64("totalDiscarded", dropped))
+		if overflow, ok := q.listener.(OverflowListener); ok {
+			overflow.OnDropped(dropped - reported)
+		}
+		reported = dropped
+	}
+	defer reportDropped()
+	for e := range q.events {
+		switch e.kind {
+		case eventAdded:
+			q.listener.OnAdded(e.tx)
+		case eventEvicted:
+			q.listener.OnEvicted(e.tx, e.evictedBy)
+		case eventRejected:
+			q.listener.OnRejected(e.tx, e.err)
+		case eventRemoved:
+			q.listener.OnRemoved(e.tx)
+		}
+		reportDropped()
+	}
+}
@@ -0,0 +1,267 @@
+package types_test
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+// recordingListener records every event as a short string, in delivery order, and counts the
+// events it was told were discarded.
+type recordingListener struct {
+	mu      sync.Mutex
+	events  []string
+	byHash  map[string][]string
+	dropped uint64
+	release chan struct{} // When set, OnAdded blocks until it is closed
+}
+
+func newRecordingListener() *recordingListener {
+	return &recordingListener{byHash: make(map[string][]string)}
+}
+
+func (l *recordingListener) record(txHash, event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+	l.byHash[txHash] = append(l.byHash[txHash], event)
+}
+
+func (l *recordingListener) OnAdded(tx *types.Tx) {
+	if l.release != nil {
+		<-l.release
+	}
+	l.record(tx.TxHash, "added "+tx.TxHash)
+}
+
+func (l *recordingListener) OnEvicted(tx *types.Tx, evictedBy *types.Tx) {
+	by := "-"
+	if evictedBy != nil {
+		by = evictedBy.TxHash
+	}
+	l.record(tx.TxHash, "evicted "+tx.TxHash+" by "+by)
+}
+
+func (l *recordingListener) OnRejected(tx *types.Tx, err error) {
+	reason := "other"
+	switch {
+	case errors.Is(err, types.ErrDuplicateTx):
+		reason = "duplicate"
+	case errors.Is(err, types.ErrFeeTooLow):
+		reason = "fee_too_low"
+	}
+	l.record(tx.TxHash, "rejected "+tx.TxHash+" "+reason)
+}
+
+func (l *recordingListener) OnRemoved(tx *types.Tx) {
+	l.record(tx.TxHash, "removed "+tx.TxHash)
+}
+
+func (l *recordingListener) OnDropped(count uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dropped += count
+}
+
+func (l *recordingListener) snapshot() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+func TestListener_Ordering(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func(opts ...types.MempoolOption) (types.Mempool, error)
+	}{
+		{name: "single", new: func(opts ...types.MempoolOption) (types.Mempool, error) { return types.NewMempool(2, logger, opts...) }},
+		{name: "sharded", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewShardedMempool(2, 4, logger, opts...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := newRecordingListener()
+			memPool, err := tc.new(types.WithListener(listener, 64))
+			require.NoError(t, err)
+			wg := &sync.WaitGroup{}
+			memPool.StartProcessors(wg, 1)
+			for _, tx := range []*types.Tx{
+				types.NewTx(logger, "a", "sig", 1, 1),
+				types.NewTx(logger, "b", "sig", 1, 2),
+				types.NewTx(logger, "a", "sig", 1, 1),
+				types.NewTx(logger, "c", "sig", 1, 3),
+				types.NewTx(logger, "d", "sig", 1, 0.5),
+			} {
+				memPool.AddTx(tx, wg)
+				wg.Wait()
+			}
+			memPool.Update([]*types.Tx{types.NewTx(logger, "b", "sig", 1, 2)})
+			memPool.AddTxs([]*types.Tx{types.NewTx(logger, "e", "sig", 1, 4)})
+			_, err = memPool.SetMaxMemPoolSize(1)
+			require.NoError(t, err)
+			memPool.CloseTxInsertChan()
+
+			want := []string{
+				"added a",
+				"added b",
+				"rejected a duplicate",
+				"evicted a by c", // An eviction precedes the admission that caused it
+				"added c",
+				"rejected d fee_too_low",
+				"removed b",
+				"added e",
+				"evicted c by -", // The pool shrank
+			}
+			require.Eventually(t, func() bool { return len(listener.snapshot()) == len(want) }, time.Second, time.Millisecond)
+			assert.Equal(t, want, listener.snapshot())
+		})
+	}
+}
+
+// TestListener_ConcurrentOrdering checks that, with many processors racing, every transaction's
+// events still arrive in a valid order.
+func TestListener_ConcurrentOrdering(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	const numTxs, maxPoolSize = 2000, 100
+	for _, tc := range []struct {
+		name string
+		new  func(opts ...types.MempoolOption) (types.Mempool, error)
+	}{
+		{name: "single", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewMempool(maxPoolSize, logger, opts...)
+		}},
+		{name: "sharded", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewShardedMempool(maxPoolSize, 16, logger, opts...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := newRecordingListener()
+			memPool, err := tc.new(types.WithListener(listener, 2*numTxs))
+			require.NoError(t, err)
+			wg := &sync.WaitGroup{}
+			memPool.StartProcessors(wg, 32)
+			for i, fee := range rand.New(rand.NewSource(3)).Perm(numTxs) {
+				require.NoError(t, memPool.AddTx(types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 1, float64(fee+1)), wg))
+			}
+			wg.Wait()
+			memPool.CloseTxInsertChan()
+
+			// Every transaction ends up pooled, rejected, or added then evicted.
+			require.Eventually(t, func() bool {
+				listener.mu.Lock()
+				defer listener.mu.Unlock()
+				return len(listener.events) == 2*numTxs-int(memPool.MempoolLen())-countRejected(listener.byHash)
+			}, 5*time.Second, time.Millisecond)
+			listener.mu.Lock()
+			defer listener.mu.Unlock()
+			require.Len(t, listener.byHash, numTxs)
+			for txHash, events := range listener.byHash {
+				switch len(events) {
+				case 1:
+					assert.Contains(t, []string{"added " + txHash, "rejected " + txHash + " fee_too_low"}, events[0])
+				case 2:
+					assert.Equal(t, "added "+txHash, events[0])
+					assert.Contains(t, events[1], "evicted "+txHash+" by ")
+				default:
+					t.Errorf("unexpected events for %s: %v", txHash, events)
+				}
+			}
+		})
+	}
+}
+
+func countRejected(byHash map[string][]string) (n int) {
+	for _, events := range byHash {
+		if len(events) == 1 && strings.HasPrefix(events[0], "rejected") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestListener_SlowListenerDoesNotStall(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	const numTxs = 1000
+	listener := newRecordingListener()
+	listener.release = make(chan struct{})
+	memPool, err := types.NewMempool(numTxs, logger, types.WithListener(listener, 8))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 4)
+	for i := 0; i < numTxs; i++ {
+		require.NoError(t, memPool.AddTx(generateUniqueTx(logger, i), wg))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("processors stalled behind a blocked listener")
+	}
+	assert.Equal(t, uint32(numTxs), memPool.MempoolLen())
+
+	close(listener.release)
+	memPool.CloseTxInsertChan()
+	// One event may be held by the blocked listener in addition to the full queue; the rest are discarded.
+	assert.GreaterOrEqual(t, len(listener.snapshot()), 8)
+	assert.LessOrEqual(t, len(listener.snapshot()), 9)
+	assert.Equal(t, uint64(numTxs-len(listener.snapshot())), listener.dropped, "every discarded event is reported to OnDropped")
+}
+
+// TestListener_Close checks that closing a pool delivers the queued events before stopping the
+// listener goroutines, and that later events are ignored.
+func TestListener_Close(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func(opts ...types.MempoolOption) (types.Mempool, error)
+	}{
+		{name: "single", new: func(opts ...types.MempoolOption) (types.Mempool, error) { return types.NewMempool(10, logger, opts...) }},
+		{name: "sharded", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewShardedMempool(10, 4, logger, opts...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := runtime.NumGoroutine()
+			listeners := []*recordingListener{newRecordingListener(), newRecordingListener()}
+			memPool, err := tc.new(types.WithListener(listeners[0], 16), types.WithListener(listeners[1], 16))
+			require.NoError(t, err)
+			txs := []*types.Tx{types.NewTx(logger, "a", "sig", 1, 1), types.NewTx(logger, "b", "sig", 1, 2)}
+			memPool.AddTxs(txs)
+			memPool.CloseTxInsertChan()
+			for _, listener := range listeners {
+				assert.Equal(t, []string{"added a", "added b"}, listener.snapshot(), "queued events are delivered before CloseTxInsertChan returns")
+			}
+
+			assert.Equal(t, 1, memPool.Update(txs[:1]), "the pool stays usable")
+			// The goroutines have handled their last event; allow them a moment to return.
+			for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			assert.LessOrEqual(t, runtime.NumGoroutine(), before, "listener goroutines stop")
+			for _, listener := range listeners {
+				assert.Len(t, listener.snapshot(), 2, "events after CloseTxInsertChan are not delivered")
+			}
+		})
+	}
+}
@@ -2,98 +2,560 @@ package types
 
 import (
 	"container/heap"
-	"fmt"
-	"os"
-	"strings"
+	"io"
+	"math"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
-	"mempool/pkg/constants"
 	"mempool/pkg/logging"
 )
 
 var (
 	ErrMempoolSize = errors.New("mempool size cannot be less than or equal to 0")
+	ErrDuplicateTx = errors.New("duplicate transaction")
+	ErrFeeTooLow   = errors.New("fee too low to enter the full mempool")
+	ErrPaused      = errors.New("mempool admission is paused")
+	ErrBelowMinFee = errors.New("fee per gas below the mempool minimum")
 )
 
+// DropReason explains why a transaction accepted by AddTx did not stay in the pool.
+type DropReason string
+
+const (
+	DropFeeTooLow DropReason = "fee_too_low" // Pool full and the fee did not beat the lowest pooled fee
+	DropEvicted   DropReason = "evicted"     // Was pooled, then replaced by a higher-fee transaction
+)
+
+// txState is the admission state of a reserved transaction hash.
+type txState int32
+
+const (
+	txQueued txState = iota + 1 // Accepted by AddTx and waiting in txChan or being processed
+	txPooled                    // In txMap and txHeap
+)
+
+// DropHandler is called from processor goroutines, without any mempool lock held, whenever a
+// transaction is discarded after AddTx accepted it. It must be safe for concurrent use.
+type DropHandler func(tx *Tx, reason DropReason)
+
 type mempool struct {
 	mu             *sync.Mutex    // Protects txMap and txHeap
 	txMap          map[string]*Tx // O(1) lookup by hash
 	txHeap         TxHeap         // Min-heap for priority management O(log n) for insertion and removal
 	txChan         chan *Tx
-	maxMemPoolSize uint32 // Maximum size of the mempool (max value of uint32 is 4,294,967,295)
+	maxMemPoolSize uint32 // Maximum size of the mempool (max value of uint32 is 4,294,967,295), accessed atomically
 	logger         logging.LoggingSystem
+	hot            hotLoggers
 
-	// New fields for handling in-flight/pending transactions
-	muPendingChecks *sync.Mutex
-	pendingChecks   map[string]struct{} // Tracks hashes submitted to txChan but not yet in Transactions
+	// Every hash that is queued, being processed or pooled is reserved here exactly once, so
+	// duplicates are rejected with a single atomic LoadOrStore instead of locking mu.
+	reserved sync.Map // Transaction hash -> txState
+
+	wal    *WAL        // Optional write-ahead log of admissions and removals, nil when persistence is disabled
+	seq    uint64      // Last admission sequence number handed out, accessed atomically
+	onDrop DropHandler // Optional, nil when nobody needs to know about dropped transactions
+	paused uint32      // 1 while admission is paused, accessed atomically
+
+	minFeePerGas uint64    // math.Float64bits of the floor below which AddTx and AddTxs reject transactions, accessed atomically; 0 disables it
+	events       observers // Status index and listeners, both optional
+
+	exportPath   string // ExportToFile destination, empty for the default path
+	exportFormat string // ExportToFile format, empty for the text format
+}
+
+// hotLoggers log from the per-transaction paths with their debug entries sampled. They are built once
+// per pool so the sampler sees every call.
+type hotLoggers struct {
+	addTx, processTx *zap.Logger
+}
+
+func newHotLoggers(ls logging.LoggingSystem) hotLoggers {
+	return hotLoggers{addTx: logging.SampleDebug(ls.Named("mempool/AddTx")), processTx: logging.SampleDebug(ls.Named("mempool/processTx"))}
+}
+
+// MempoolOption configures optional mempool behaviour at construction time.
+type MempoolOption func(*mempool)
+
+// WithWAL persists admissions and removals to w and rebuilds the pool from it on construction.
+func WithWAL(w *WAL) MempoolOption {
+	return func(mp *mempool) {
+		mp.wal = w
+	}
+}
+
+// WithDropHandler reports transactions discarded after admission (see DropReason) to h.
+func WithDropHandler(h DropHandler) MempoolOption {
+	return func(mp *mempool) {
+		mp.onDrop = h
+	}
+}
+
+// WithMinFeePerGas makes AddTx and AddTxs reject transactions whose FeePerGas is below min with
+// ErrBelowMinFee. Reinject is exempt.
+func WithMinFeePerGas(min float64) MempoolOption {
+	return func(mp *mempool) {
+		mp.minFeePerGas = math.Float64bits(min)
+	}
+}
+
+// WithStatusIndex remembers why up to capacity recently discarded transactions left the pool, for
+// at most ttl (0 for no time limit), so TxStatus can report them.
+func WithStatusIndex(capacity int, ttl time.Duration) MempoolOption {
+	return func(mp *mempool) {
+		mp.events.status = newStatusIndex(capacity, ttl)
+	}
+}
+
+// WithExport makes ExportToFile write to path in the named export format. Without it ExportToFile
+// writes text to ./prioritized-transactions.txt.
+func WithExport(path, format string) MempoolOption {
+	return func(mp *mempool) {
+		mp.exportPath, mp.exportFormat = path, format
+	}
 }
 
 type Mempool interface {
-	AddTx(tx *Tx, group *sync.WaitGroup) (err error)         // Adds a transaction to the mempool, processing it in a goroutine.
-	GetTx(txHash string) (*Tx, bool)                         // Retrieves a transaction by its hash from the mempool.
-	MempoolLen() uint32                                      // Returns the current number of transactions in the mempool.
-	CloseTxInsertChan()                                      // Closes the transaction insertion channel.
-	ExportToFile() error                                     // Exports the mempool contents to a file.
-	MaxMemPoolSize() uint32                                  // Returns the maximum size of the mempool.
-	StartProcessors(wg *sync.WaitGroup, numProcessors uint8) // Starts a specified number of goroutines to process transactions from the mempool.
+	AddTx(tx *Tx, group *sync.WaitGroup) (err error)                                // Adds a transaction to the mempool, processing it in a goroutine.
+	AddTxs(txs []*Tx) []error                                                       // Synchronously adds a batch of transactions, returning one result per transaction.
+	GetTx(txHash string) (*Tx, bool)                                                // Retrieves a transaction by its hash from the mempool.
+	MempoolLen() uint32                                                             // Returns the current number of transactions in the mempool.
+	CloseTxInsertChan()                                                             // Closes the transaction insertion channel and waits for listeners to handle queued events.
+	ExportToFile() (ExportResult, error)                                            // Atomically exports the mempool contents to the file set by WithExport.
+	ExportTo(w io.Writer, opts ExportOptions) (int, error)                          // Streams the mempool contents to w in priority order.
+	ReapMaxTxs(max int) []*Tx                                                       // Returns up to max of the highest priority transactions without removing them.
+	MaxMemPoolSize() uint32                                                         // Returns the maximum size of the mempool.
+	SetMaxMemPoolSize(n uint32) (int, error)                                        // Changes the capacity at runtime, returning how many transactions were evicted.
+	MinFeePerGas() float64                                                          // Returns the minimum FeePerGas admitted, 0 when there is no floor.
+	SetMinFeePerGas(min float64)                                                    // Changes the minimum FeePerGas admitted at runtime.
+	SetStatusIndexTTL(ttl time.Duration)                                            // Changes how long the status index remembers discarded transactions.
+	StartProcessors(wg *sync.WaitGroup, numProcessors uint8)                        // Starts a specified number of goroutines to process transactions from the mempool.
+	SaveSnapshot(path string) error                                                 // Atomically writes all pooled transactions and pool metadata to path.
+	LoadSnapshot(path string) error                                                 // Admits the transactions from a snapshot written by SaveSnapshot.
+	Pause()                                                                         // Stops admitting new transactions; queued transactions are still processed.
+	Resume()                                                                        // Admits new transactions again after Pause.
+	Paused() bool                                                                   // Reports whether admission is paused.
+	Reinject(txs []*Tx) []error                                                     // Re-admits transactions from an orphaned block, ignoring Pause and the fee floor.
+	Update(included []*Tx) int                                                      // Removes transactions included in a new block, returning how many were pooled.
+	TxStatus(txHash string) (TxStatus, bool)                                        // Reports whether a hash is queued or pooled, or why it recently left the pool.
+	EstimateFee(targetPercentile float64) (float64, error)                          // Returns a total fee that outranks targetPercentile percent of the pool.
+	SuggestFeeForInclusion(gas, blockGasLimit float64, blocks int) (float64, error) // Returns a fee per gas likely to be included within blocks blocks.
+	Stats(percentiles ...float64) (PoolStats, error)                                // Summarises pooled fees, gas and senders, with DefaultStatsPercentiles when none are given.
 }
 
 var _ Mempool = (*mempool)(nil)
 
-func NewMempool(maxPoolSize uint32, ls logging.LoggingSystem) (Mempool, error) {
+func NewMempool(maxPoolSize uint32, ls logging.LoggingSystem, opts ...MempoolOption) (Mempool, error) {
 	if maxPoolSize <= 0 {
 		return nil, ErrMempoolSize
 	}
-	return &mempool{
-		mu:              &sync.Mutex{},
-		maxMemPoolSize:  maxPoolSize,
-		logger:          ls,
-		txMap:           make(map[string]*Tx, maxPoolSize),
-		txHeap:          make(TxHeap, 0, maxPoolSize),
-		txChan:          make(chan *Tx, 200000), // Buffered channel to hold transactions before processing
-		muPendingChecks: &sync.Mutex{},
-		pendingChecks:   make(map[string]struct{}),
-	}, nil
+	mp := &mempool{
+		mu:             &sync.Mutex{},
+		maxMemPoolSize: maxPoolSize,
+		logger:         ls,
+		hot:            newHotLoggers(ls),
+		txMap:          make(map[string]*Tx, maxPoolSize),
+		txHeap:         make(TxHeap, 0, maxPoolSize),
+		txChan:         make(chan *Tx, 200000), // Buffered channel to hold transactions before processing
+		events:         observers{history: &feeHistory{}, stats: newPoolStats()},
+	}
+	for _, opt := range opts {
+		opt(mp)
+	}
+	mp.events.start(ls)
+	if mp.wal != nil {
+		if err := mp.restoreFromWAL(); err != nil {
+			return nil, err
+		}
+	}
+	return mp, nil
 }
 
-func (mp *mempool) MaxMemPoolSize() uint32 {
-	return mp.maxMemPoolSize
+// restoreFromWAL re-validates the transactions replayed by the WAL and inserts them into the pool,
+// then compacts the log so it matches the rebuilt state exactly.
+func (mp *mempool) restoreFromWAL() error {
+	recovered := mp.wal.Recovered()
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	for _, tx := range recovered {
+		if err := tx.Validate(); err != nil {
+			mp.logger.Named("mempool/restoreFromWAL").Warn("discarding invalid transaction from WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+			continue
+		}
+		tx.calculateTotalFees()
+		if _, reserved := mp.claim(tx.TxHash, txQueued); reserved {
+			continue
+		}
+		mp.insertLocked(tx, false)
+		if tx.Sequence > mp.seq {
+			mp.seq = tx.Sequence // No processors are running yet, so no atomic access is needed
+		}
+	}
+	mp.logger.Named("mempool/restoreFromWAL").Info("restored transactions from WAL", zap.Int("replayed", len(recovered)), zap.Int("restored", len(mp.txMap)))
+	return errors.Wrap(mp.wal.Compact(mp.txHeap), "failed to compact WAL after restore")
 }
 
-func (mp *mempool) AddTx(tx *Tx, group *sync.WaitGroup) (err error) {
-	mp.logger.Named("mempool/AddTx").Debug("calculating total fee for transaction", zap.String("txHash", tx.TxHash))
-	tx.calculateTotalFees()
+func (mp *mempool) MaxMemPoolSize() uint32 {
+	return atomic.LoadUint32(&mp.maxMemPoolSize)
+}
 
-	// Check 1: Is it already fully processed and in the main Transactions map?
+// SetMaxMemPoolSize changes the capacity of the pool at runtime. Growing only raises the limit;
+// shrinking evicts the lowest-priority transactions until the pool fits, reporting each one to the
+// drop handler as DropEvicted. It returns the number of evicted transactions.
+func (mp *mempool) SetMaxMemPoolSize(n uint32) (int, error) {
+	if n == 0 {
+		return 0, ErrMempoolSize
+	}
+	var evicted []*Tx
 	mp.mu.Lock()
-	if _, exists := mp.txMap[tx.TxHash]; exists {
-		mp.mu.Unlock()
-		mp.logger.Named("mempool/AddTx").Warn("rejected duplicate transaction (already in main pool)", zap.String("txHash", tx.TxHash))
-		return errors.Errorf("Transaction with hash [%s] already exists in mempool", tx.TxHash)
+	previous := atomic.SwapUint32(&mp.maxMemPoolSize, n)
+	for uint32(len(mp.txHeap)) > n {
+		minTx := mp.popMinLocked(true)
+		evicted = append(evicted, minTx)
+		mp.events.evicted(minTx, nil)
 	}
 	mp.mu.Unlock()
+	mp.commitWAL()
+	for _, tx := range evicted {
+		mp.drop(tx, DropEvicted)
+	}
+	mp.logger.Named("mempool/SetMaxMemPoolSize").Info("changed mempool capacity", zap.Uint32("previous", previous), zap.Uint32("maxMemPoolSize", n), zap.Int("evicted", len(evicted)))
+	return len(evicted), nil
+}
 
-	// Check 2: Is it currently pending processing (in txChan or about to be)?
-	mp.muPendingChecks.Lock()
-	if _, pending := mp.pendingChecks[tx.TxHash]; pending {
-		mp.muPendingChecks.Unlock()
-		mp.logger.Named("mempool/AddTx").Warn("rejected duplicate transaction (pending processing)", zap.String("txHash", tx.TxHash))
-		return errors.Errorf("Transaction with hash [%s] is already pending processing", tx.TxHash)
+func (mp *mempool) MinFeePerGas() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&mp.minFeePerGas))
+}
+
+// SetMinFeePerGas changes the fee floor of WithMinFeePerGas at runtime; 0 disables it. Pooled
+// transactions paying less are kept.
+func (mp *mempool) SetMinFeePerGas(min float64) {
+	previous := math.Float64frombits(atomic.SwapUint64(&mp.minFeePerGas, math.Float64bits(min)))
+	mp.logger.Named("mempool/SetMinFeePerGas").Info("changed minimum fee per gas", zap.Float64("previous", previous), zap.Float64("minFeePerGas", min))
+}
+
+// SetStatusIndexTTL changes how long the status index of WithStatusIndex remembers discarded
+// transactions; 0 keeps them until the index is full. It does nothing without a status index.
+func (mp *mempool) SetStatusIndexTTL(ttl time.Duration) {
+	mp.events.status.setTTL(ttl)
+}
+
+// Pause makes AddTx and AddTxs reject new transactions with ErrPaused until Resume is called.
+// Transactions already accepted are still processed, and reads, exports and snapshots keep working.
+// A submission racing with Pause may still be accepted.
+func (mp *mempool) Pause() {
+	if atomic.SwapUint32(&mp.paused, 1) == 0 {
+		mp.logger.Named("mempool/Pause").Info("paused admission")
 	}
-	// If not pending, mark it as pending before sending to channel
-	mp.pendingChecks[tx.TxHash] = struct{}{}
-	mp.muPendingChecks.Unlock()
+}
+
+// Resume admits new transactions again after Pause.
+func (mp *mempool) Resume() {
+	if atomic.SwapUint32(&mp.paused, 0) == 1 {
+		mp.logger.Named("mempool/Resume").Info("resumed admission")
+	}
+}
+
+func (mp *mempool) Paused() bool {
+	return atomic.LoadUint32(&mp.paused) == 1
+}
+
+// pausedErrors returns ErrPaused for every transaction of a batch submitted while paused and
+// reports the rejections to events.
+func pausedErrors(txs []*Tx, events *observers) []error {
+	results := make([]error, len(txs))
+	for i, tx := range txs {
+		results[i] = errPaused(tx)
+		events.rejected(tx, results[i])
+	}
+	return results
+}
+
+func errPaused(tx *Tx) error {
+	return errors.Wrapf(ErrPaused, "Transaction with hash [%s] was not admitted", tx.TxHash)
+}
+
+func (mp *mempool) AddTx(tx *Tx, group *sync.WaitGroup) (err error) {
+	defer func() {
+		if err != nil {
+			mp.events.rejected(tx, err)
+		}
+	}()
+	if mp.Paused() {
+		return errPaused(tx)
+	}
+	if err = tx.Validate(); err != nil {
+		return err
+	}
+	if err = checkMinFee(tx, mp.MinFeePerGas()); err != nil {
+		return err
+	}
+	// Reserve first: a resubmitted duplicate may be the very *Tx a processor is reading.
+	if err = mp.reserve(tx); err != nil {
+		return err
+	}
+	mp.hot.addTx.Debug("calculating total fee for transaction", zap.String("txHash", tx.TxHash))
+	tx.calculateTotalFees()
+	if tx.ArrivalTime.IsZero() {
+		tx.ArrivalTime = time.Now()
+	}
+	tx.Sequence = atomic.AddUint64(&mp.seq, 1)
 
 	// Only increment WaitGroup if the transaction will actually be sent to the channel
 	group.Add(1)
 	mp.txChan <- tx
-	mp.logger.Named("mempool/AddTx").Debug("Transaction with hash accepted and sent to processing channel", zap.String("txHash", tx.TxHash))
+	mp.hot.addTx.Debug("Transaction with hash accepted and sent to processing channel", zap.String("txHash", tx.TxHash))
 	return nil // Successfully queued
 }
 
+func errFeeTooLow(tx *Tx) error {
+	return errors.Wrapf(ErrFeeTooLow, "Transaction with hash [%s] has total fee %v", tx.TxHash, tx.TotalFee)
+}
+
+// checkMinFee returns ErrBelowMinFee when tx pays less than minFeePerGas per gas.
+func checkMinFee(tx *Tx, minFeePerGas float64) error {
+	if tx.FeePerGas < minFeePerGas {
+		return errors.Wrapf(ErrBelowMinFee, "Transaction with hash [%s] pays %v per gas, the minimum is %v", tx.TxHash, tx.FeePerGas, minFeePerGas)
+	}
+	return nil
+}
+
+// reserve rejects tx if its hash is already queued, being processed or pooled, and otherwise
+// reserves the hash until the transaction is dropped or evicted.
+func (mp *mempool) reserve(tx *Tx) error {
+	state, reserved := mp.claim(tx.TxHash, txQueued)
+	switch {
+	case !reserved:
+		return nil
+	case state == txPooled:
+		mp.logger.Named("mempool/AddTx").Warn("rejected duplicate transaction (already in main pool)", zap.String("txHash", tx.TxHash))
+		return errors.Wrapf(ErrDuplicateTx, "Transaction with hash [%s] already exists in mempool", tx.TxHash)
+	default:
+		mp.logger.Named("mempool/AddTx").Warn("rejected duplicate transaction (pending processing)", zap.String("txHash", tx.TxHash))
+		return errors.Wrapf(ErrDuplicateTx, "Transaction with hash [%s] is already pending processing", tx.TxHash)
+	}
+}
+
+// claim atomically reserves txHash in state. When the hash is already reserved it reports the
+// existing state and true, and the caller must not admit the transaction.
+func (mp *mempool) claim(txHash string, state txState) (txState, bool) {
+	existing, reserved := mp.reserved.LoadOrStore(txHash, state)
+	return existing.(txState), reserved
+}
+
+// claimForBatch reserves txHash for AddTxs, returning ErrDuplicateTx when it is already reserved.
+func (mp *mempool) claimForBatch(txHash string) error {
+	state, reserved := mp.claim(txHash, txQueued)
+	switch {
+	case !reserved:
+		return nil
+	case state == txPooled:
+		return errors.Wrapf(ErrDuplicateTx, "Transaction with hash [%s] already exists in mempool", txHash)
+	default:
+		return errors.Wrapf(ErrDuplicateTx, "Transaction with hash [%s] is already pending processing", txHash)
+	}
+}
+
+// AddTxs admits a batch of transactions synchronously, taking each lock once for the whole batch
+// instead of once per transaction. The result at index i is nil when txs[i] is in the pool afterwards,
+// ErrInvalidTx when it is missing a hash or signature or has a non-positive gas or fee per gas (the
+// check a WAL or snapshot restore applies), ErrDuplicateTx when it repeats an earlier transaction in
+// the batch, a pooled transaction or one pending processing, ErrBelowMinFee when it pays less than the fee floor, and ErrFeeTooLow when the
+// pool is full of transactions that outrank it. Pooled transactions displaced by the batch are
+// reported to the drop handler as DropEvicted.
+func (mp *mempool) AddTxs(txs []*Tx) []error {
+	if mp.Paused() {
+		return pausedErrors(txs, &mp.events)
+	}
+	return mp.addBatch(txs, false)
+}
+
+// Reinject returns the transactions of an orphaned block to the pool. Unlike AddTxs it works while
+// paused and ignores the fee floor, and it keeps each transaction's ArrivalTime and Sequence so a
+// re-injected transaction regains its original place among equal fees. Capacity and duplicates are
+// enforced as in AddTxs, and the results have the same meaning.
+func (mp *mempool) Reinject(txs []*Tx) []error {
+	return mp.addBatch(txs, true)
+}
+
+// addBatch admits txs under a single lock for AddTxs and Reinject.
+func (mp *mempool) addBatch(txs []*Tx, reinject bool) []error {
+	results := make([]error, len(txs))
+	candidates := make([]*Tx, 0, len(txs))
+	seen := make(map[string]struct{}, len(txs))
+	now := time.Now()
+
+	mp.mu.Lock()
+	for i, tx := range txs {
+		if _, dup := seen[tx.TxHash]; dup {
+			results[i] = errors.Wrapf(ErrDuplicateTx, "Transaction with hash [%s] appears more than once in the batch", tx.TxHash)
+			mp.events.rejected(tx, results[i])
+			continue
+		}
+		seen[tx.TxHash] = struct{}{}
+		if results[i] = tx.Validate(); results[i] != nil {
+			mp.events.rejected(tx, results[i])
+			continue
+		}
+		if !reinject {
+			if results[i] = checkMinFee(tx, mp.MinFeePerGas()); results[i] != nil {
+				mp.events.rejected(tx, results[i])
+				continue
+			}
+		}
+		if results[i] = mp.claimForBatch(tx.TxHash); results[i] != nil {
+			mp.events.rejected(tx, results[i])
+			continue
+		}
+		tx.calculateTotalFees()
+		if tx.ArrivalTime.IsZero() {
+			tx.ArrivalTime = now
+		}
+		if !reinject || tx.Sequence == 0 {
+			tx.Sequence = atomic.AddUint64(&mp.seq, 1)
+		}
+		candidates = append(candidates, tx)
+	}
+	evicted := mp.insertBatchLocked(candidates)
+	var rejected int
+	for i, tx := range txs {
+		if results[i] == nil && mp.txMap[tx.TxHash] != tx {
+			results[i] = errFeeTooLow(tx)
+			mp.events.rejected(tx, results[i])
+		}
+		if results[i] != nil {
+			rejected++
+		}
+	}
+	mp.mu.Unlock()
+	mp.commitWAL()
+
+	for _, tx := range evicted {
+		mp.drop(tx, DropEvicted)
+	}
+	if reinject {
+		mp.logger.Named("mempool/Reinject").Info("re-injected transactions", zap.Int("count", len(txs)), zap.Int("rejected", rejected), zap.Int("evicted", len(evicted)))
+	} else {
+		mp.logger.Named("mempool/AddTxs").Debug("added transaction batch", zap.Int("count", len(txs)), zap.Int("rejected", rejected), zap.Int("evicted", len(evicted)))
+	}
+	return results
+}
+
+// Update removes the transactions of a newly committed block from the pool and releases their
+// hashes, so they can be re-injected if the block is later orphaned. Included transactions that are
+// not pooled, including ones still waiting for a processor, are ignored. It returns the number of
+// transactions removed.
+func (mp *mempool) Update(included []*Tx) int {
+	mp.mu.Lock()
+	removed := mp.removeLocked(included)
+	for _, tx := range removed {
+		mp.events.removed(tx)
+	}
+	mp.mu.Unlock()
+	mp.commitWAL()
+	mp.logger.Named("mempool/Update").Info("removed included transactions", zap.Int("included", len(included)), zap.Int("removed", len(removed)))
+	return len(removed)
+}
+
+// removeLocked removes the pooled transactions among txs, rebuilding the heap in one O(n) pass, and
+// returns them. Removals are appended to the WAL when there is one. mp.mu must be held.
+func (mp *mempool) removeLocked(txs []*Tx) (removed []*Tx) {
+	for _, tx := range txs {
+		if pooled, ok := mp.txMap[tx.TxHash]; ok {
+			delete(mp.txMap, tx.TxHash)
+			mp.reserved.Delete(tx.TxHash)
+			removed = append(removed, pooled)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	kept := mp.txHeap[:0]
+	for _, tx := range mp.txHeap {
+		if _, ok := mp.txMap[tx.TxHash]; ok {
+			kept = append(kept, tx)
+		}
+	}
+	clear(mp.txHeap[len(kept):]) // Drop references to removed transactions
+	mp.txHeap = kept
+	heap.Init(&mp.txHeap)
+	if mp.wal != nil {
+		for _, tx := range removed {
+			if err := mp.wal.logRemove(tx.TxHash); err != nil {
+				mp.logger.Named("mempool/removeLocked").Error("failed to log removal to WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+			}
+		}
+	}
+	return removed
+}
+
+// insertBatchLocked adds candidates to the pool and returns the pooled transactions they evicted.
+// Candidates that fit are appended and the heap is rebuilt in one O(n) pass when that is cheaper
+// than pushing them one by one; the rest are admitted in descending priority, each replacing the
+// lowest-fee pooled transaction it beats. mp.mu must be held.
+func (mp *mempool) insertBatchLocked(candidates []*Tx) (evicted []*Tx) {
+	if len(candidates) == 0 {
+		return nil
+	}
+	space := int(mp.MaxMemPoolSize()) - len(mp.txHeap)
+	if space < len(candidates) {
+		// Only the best candidates can enter a full pool, so admit them best first.
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].outranks(candidates[j]) })
+	}
+	n := min(max(space, 0), len(candidates))
+	fits := candidates[:n:n] // Capped so appending to admitted never overwrites candidates
+	if len(fits) > len(mp.txHeap) {
+		mp.txHeap = append(mp.txHeap, fits...)
+		heap.Init(&mp.txHeap)
+	} else {
+		for _, tx := range fits {
+			heap.Push(&mp.txHeap, tx)
+		}
+	}
+	admitted := fits
+	for _, tx := range candidates[len(fits):] {
+		if tx.TotalFee <= mp.txHeap[0].TotalFee {
+			break // Candidates are sorted, so no later one can beat the minimum either
+		}
+		minTx := mp.txHeap[0]
+		delete(mp.txMap, minTx.TxHash)
+		mp.txHeap[0] = tx
+		heap.Fix(&mp.txHeap, 0)
+		admitted = append(admitted, tx)
+		evicted = append(evicted, minTx)
+		mp.events.evicted(minTx, tx)
+	}
+	for _, tx := range admitted {
+		mp.txMap[tx.TxHash] = tx
+		mp.reserved.Store(tx.TxHash, txPooled)
+		mp.events.added(tx)
+	}
+	for _, tx := range candidates[len(admitted):] {
+		mp.reserved.Delete(tx.TxHash) // Admitted candidates are always a prefix of candidates
+	}
+	for _, tx := range evicted {
+		mp.reserved.Delete(tx.TxHash)
+	}
+
+	if mp.wal != nil {
+		for _, tx := range evicted {
+			if err := mp.wal.logRemove(tx.TxHash); err != nil {
+				mp.logger.Named("mempool/insertBatchLocked").Error("failed to log eviction to WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+			}
+		}
+		for _, tx := range admitted {
+			if err := mp.wal.logAdd(tx); err != nil {
+				mp.logger.Named("mempool/insertBatchLocked").Error("failed to log admission to WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+			}
+		}
+	}
+	return evicted
+}
+
 // StartProcessors starts a specified number of goroutines to process transactions from the mempool.
 func (mp *mempool) StartProcessors(wg *sync.WaitGroup, numProcessors uint8) {
 	for i := uint8(0); i < numProcessors; i++ {
@@ -105,82 +567,155 @@ func (mp *mempool) StartProcessors(wg *sync.WaitGroup, numProcessors uint8) {
 func (mp *mempool) processTx(wg *sync.WaitGroup, txReadOnly <-chan *Tx) {
 	for transaction := range txReadOnly { // Loop until channel is closed
 		currentTxHash := transaction.TxHash
-		mp.logger.Named("mempool/processTx").Debug("Processing transaction", zap.String("txHash", currentTxHash))
-
-		// Remove from pendingChecks now that we've picked it up for processing.
-		mp.muPendingChecks.Lock()
-		delete(mp.pendingChecks, currentTxHash)
-		mp.muPendingChecks.Unlock()
+		mp.hot.processTx.Debug("Processing transaction", zap.String("txHash", currentTxHash))
 
+		// AddTx reserved the hash, so no duplicate can reach this point.
 		mp.mu.Lock() // Lock for main Transactions map operations
-
-		// Final check for duplicates right before insertion attempt.
-		if _, exists := mp.txMap[currentTxHash]; exists {
-			mp.logger.Named("mempool/processTx").Warn("Transaction already exists in main pool (caught by final processor check). Discarding.", zap.String("txHash", currentTxHash))
-			mp.mu.Unlock()
-			wg.Done() // Signal completion for this transaction
-			continue
-		}
-
-		// Logic for when mempool is full: prioritize transactions with higher fee
-		if uint32(len(mp.txHeap)) >= mp.maxMemPoolSize {
-			// Pool full: check if new tx has higher priority than the current min (top of min-heap)
-			minTx := mp.txHeap[0]
-			if transaction.TotalFee > minTx.TotalFee {
-				// Replace minTx with the new higher-fee transaction
-				delete(mp.txMap, minTx.TxHash)
-				heap.Pop(&mp.txHeap)
-			} else {
-				mp.mu.Unlock()
-				wg.Done() // Signal completion for this transaction
-				continue
-			}
-		}
-		// Insert new tx
-		heap.Push(&mp.txHeap, transaction)
-		mp.txMap[currentTxHash] = transaction
+		inserted, evicted := mp.insertLocked(transaction, true)
 		mp.mu.Unlock()
+		mp.commitWAL()
+		if !inserted {
+			mp.drop(transaction, DropFeeTooLow)
+		} else if evicted != nil {
+			mp.drop(evicted, DropEvicted)
+		}
 		wg.Done() // Signal completion for this transaction
 	}
-	mp.logger.Named("mempool/processTx").Info("Channel closed, processor shutting down.")
+	mp.hot.processTx.Info("Channel closed, processor shutting down.")
 }
 
-// ExportToFile exports the contents of the mempool to a file, sorted by TotalFee descending.
-func (mp *mempool) ExportToFile() error {
-	var sb strings.Builder
-	txsDesc := make([]*Tx, 0, len(mp.txHeap))
-	mp.logger.Info("Exporting transactions", zap.Int("count", len(mp.txHeap)))
-	for mp.txHeap.Len() > 0 {
-		tx := heap.Pop(&mp.txHeap).(*Tx)
-		txsDesc = append(txsDesc, tx)
+// drop reports a discarded transaction to the drop handler, if any. mp.mu must not be held.
+func (mp *mempool) drop(tx *Tx, reason DropReason) {
+	if mp.onDrop != nil {
+		mp.onDrop(tx, reason)
 	}
+}
 
-	// Sort transactions by TotalFee in descending order
-	for i := len(txsDesc) - 1; i >= 0; i-- {
-		tx := txsDesc[i]
-		fmt.Fprintf(&sb, "TxHash=%v Gas=%v FeePerGas=%v Signature=%v TotalFee=%v \n", tx.TxHash, tx.Gas, tx.FeePerGas, tx.Signature, tx.TotalFee)
+// insertLocked adds tx to the pool, evicting the lowest-fee transaction when the pool is full.
+// It reports whether tx was inserted and which transaction, if any, it evicted. The caller must
+// have claimed tx.TxHash; the claim becomes txPooled or is released when tx does not fit. When
+// logToWAL is set the admission and any eviction are appended to the WAL. mp.mu must be held.
+func (mp *mempool) insertLocked(tx *Tx, logToWAL bool) (inserted bool, evicted *Tx) {
+	// Logic for when mempool is full: prioritize transactions with higher fee
+	if uint32(len(mp.txHeap)) >= mp.MaxMemPoolSize() {
+		// Pool full: check if new tx has higher priority than the current min (top of min-heap)
+		if tx.TotalFee <= mp.txHeap[0].TotalFee {
+			mp.reserved.Delete(tx.TxHash)
+			mp.events.rejected(tx, errFeeTooLow(tx))
+			return false, nil
+		}
+		// Replace the minimum with the new higher-fee transaction
+		evicted = mp.popMinLocked(logToWAL)
+		mp.events.evicted(evicted, tx)
+	}
+	// Insert new tx
+	heap.Push(&mp.txHeap, tx)
+	mp.txMap[tx.TxHash] = tx
+	mp.reserved.Store(tx.TxHash, txPooled)
+	mp.events.added(tx)
+	if logToWAL && mp.wal != nil {
+		if err := mp.wal.logAdd(tx); err != nil {
+			mp.logger.Named("mempool/insertLocked").Error("failed to log admission to WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+		}
 	}
+	return true, evicted
+}
 
-	fileName := os.Getenv(constants.PRIORITIZED_TX_FILE_PATH)
-	if fileName == "" {
-		fileName = "./prioritized-transactions.txt"
+// popMinLocked removes the lowest-fee transaction and releases its reservation, appending the
+// removal to the WAL when logToWAL is set. The pool must not be empty and mp.mu must be held.
+func (mp *mempool) popMinLocked(logToWAL bool) *Tx {
+	minTx := heap.Pop(&mp.txHeap).(*Tx)
+	delete(mp.txMap, minTx.TxHash)
+	mp.reserved.Delete(minTx.TxHash)
+	if logToWAL && mp.wal != nil {
+		if err := mp.wal.logRemove(minTx.TxHash); err != nil {
+			mp.logger.Named("mempool/popMinLocked").Error("failed to log eviction to WAL", zap.String("txHash", minTx.TxHash), zap.Error(err))
+		}
 	}
-	file, err := os.Create(fileName)
-	if err != nil {
-		return errors.Wrapf(err, "failed to create file %s", fileName)
+	return minTx
+}
+
+// commitWAL syncs the records appended under mp.mu and starts a background compaction when the WAL
+// asks for one. Only copying the heap's pointers holds the lock; the snapshot is written without it.
+// mp.mu must not be held.
+func (mp *mempool) commitWAL() {
+	if mp.wal == nil {
+		return
+	}
+	if err := mp.wal.syncAppended(); err != nil {
+		mp.logger.Named("mempool/commitWAL").Error("failed to sync WAL", zap.Error(err))
+	}
+	if !mp.wal.ShouldCompact() {
+		return
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	txs := append([]*Tx(nil), mp.txHeap...)
+	if err := mp.wal.startCompaction(txs, mp.compacted); err != nil {
+		mp.logger.Named("mempool/commitWAL").Error("failed to compact WAL", zap.Error(err))
 	}
-	defer file.Close()
-	bytes, err := file.WriteString(sb.String())
+}
+
+// compacted logs the outcome of a background compaction.
+func (mp *mempool) compacted(err error) {
 	if err != nil {
-		return errors.Wrapf(err, "failed to write to file %s", fileName)
+		mp.logger.Named("mempool/commitWAL").Error("failed to compact WAL, compaction suspended", zap.Error(err))
 	}
-	mp.logger.Info("Exported bytes to file", zap.Int("bytes", bytes), zap.String("fileName", fileName))
-	return nil
 }
 
-// CloseTxInsertChan closes the transaction insertion channel.
+// CloseTxInsertChan closes the transaction insertion channel, then stops the listener goroutines
+// once they have handled every queued event. Events after this are not delivered.
 func (mp *mempool) CloseTxInsertChan() {
 	close(mp.txChan)
+	mp.events.close()
+}
+
+// TxStatus reports the lifecycle state of txHash: queued or pooled while its hash is reserved, and
+// otherwise the last terminal status kept by the index enabled with WithStatusIndex.
+func (mp *mempool) TxStatus(txHash string) (TxStatus, bool) {
+	if status, ok := liveStatus(&mp.reserved, txHash); ok {
+		return status, true
+	}
+	return mp.events.status.lookup(txHash)
+}
+
+// EstimateFee returns the smallest total fee that outranks targetPercentile percent (0 to 100) of the
+// pooled transactions, raised above the lowest pooled fee when the pool is full and above every fee
+// evicted in the last minute, so a transaction paying it is likely to survive eviction. It returns 0
+// when any fee would do.
+func (mp *mempool) EstimateFee(targetPercentile float64) (float64, error) {
+	return estimateFee(mp.feeSnapshot(), targetPercentile)
+}
+
+// SuggestFeeForInclusion returns the smallest fee per gas at which a transaction using gas would be
+// included within the next blocks blocks of blockGasLimit, assuming blocks are built from the pool in
+// priority order, and would survive eviction as in EstimateFee. It never suggests less than the
+// minimum fee per gas.
+func (mp *mempool) SuggestFeeForInclusion(gas, blockGasLimit float64, blocks int) (float64, error) {
+	return suggestFeeForInclusion(mp.feeSnapshot(), gas, blockGasLimit, blocks)
+}
+
+func (mp *mempool) feeSnapshot() feeSnapshot {
+	mp.mu.Lock()
+	txs := append([]*Tx(nil), mp.txHeap...)
+	mp.mu.Unlock()
+	return feeSnapshot{
+		txs:          txs,
+		full:         uint32(len(txs)) >= mp.MaxMemPoolSize(),
+		evictedFloor: mp.events.history.floor(time.Now()),
+		minFeePerGas: mp.MinFeePerGas(),
+	}
+}
+
+// Stats summarises the pooled transactions from running totals kept as the pool changes, so its
+// cost depends on the spread of fees rather than on the size of the pool. Only after the last
+// transaction paying the smallest or largest fee has left does it scan the pool, once, under mp.mu.
+func (mp *mempool) Stats(percentiles ...float64) (PoolStats, error) {
+	return mp.events.stats.snapshot(percentiles, func(fn func(txs []*Tx)) {
+		mp.mu.Lock()
+		defer mp.mu.Unlock()
+		fn(mp.txHeap)
+	})
 }
 
 // GetTx retrieves a transaction from the mempool in a thread-safe manner.
@@ -1,16 +1,25 @@
 package types_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
 	"mempool/mocks"
 	"mempool/pkg/logging"
@@ -39,6 +48,7 @@ func TestNewMempool(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			mockLogger := mocks.NewMockLoggingSystem(ctrl)
+			mockLogger.EXPECT().Named(gomock.Any()).Return(zap.NewNop()).AnyTimes() // Sampled loggers of the hot paths
 
 			result, err := types.NewMempool(tc.maxPoolSize, mockLogger)
 			if tc.isError {
@@ -238,7 +248,7 @@ func TestMempool_ExportToFile(t *testing.T) {
 
 			err = memPool.AddTx(tx, wg)
 			require.NoError(t, err)
-			err = memPool.ExportToFile()
+			_, err = memPool.ExportToFile()
 			require.NoError(t, err)
 
 			if !tc.isError {
@@ -249,6 +259,622 @@ func TestMempool_ExportToFile(t *testing.T) {
 	}
 }
 
+func TestMempool_ExportToFile_Atomic(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "prioritized.txt")
+	require.NoError(t, os.WriteFile(fileName, []byte("stale export"), 0o644))
+
+	memPool, err := types.NewMempool(3, logger, types.WithExport(fileName, ""))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 1)
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "low", "sig", 10, 1), wg))
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "high", "sig", 10, 3), wg))
+	wg.Wait()
+	memPool.CloseTxInsertChan()
+
+	result, err := memPool.ExportToFile()
+	require.NoError(t, err)
+	assert.Equal(t, fileName, result.Path)
+	assert.Equal(t, 2, result.Records)
+
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.Checksum)
+	assert.Equal(t, int64(len(data)), result.Bytes)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "TxHash=high "), "highest fee should be exported first")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files should be left behind")
+}
+
+func TestMempool_ExportToFile_Compressed(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "prioritized.txt.gz")
+	memPool, err := types.NewMempool(2, logger, types.WithExport(fileName, ""))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 1)
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "low", "sig", 10, 1), wg))
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "high", "sig", 10, 3), wg))
+	wg.Wait()
+	memPool.CloseTxInsertChan()
+
+	result, err := memPool.ExportToFile()
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Records)
+
+	compressed, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	sum := sha256.Sum256(compressed)
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.Checksum, "checksum should cover the bytes on disk")
+	gr, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	data, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "TxHash=high "))
+
+	for _, ext := range []string{".bz2", ".bzip2", ".xz", ".lzma", ".lz", ".lz4", ".zst", ".zstd", ".br", ".sz", ".Z", ".zip"} {
+		_, err = types.ExportToPath(memPool, filepath.Join(dir, "prioritized.txt"+ext), "", logger)
+		require.ErrorIs(t, err, types.ErrUnsupportedCompression, ext)
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "a failed export must not leave files behind")
+}
+
+func TestMempool_ExportTo(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	memPool, err := types.NewMempool(100, logger)
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 2)
+	// Five transactions share each fee, so with small batches equal fees straddle batch boundaries and
+	// exercise the cursor's tie-breaking.
+	for i := 0; i < 50; i++ {
+		feePerGas := float64(i%10 + 1)
+		require.NoError(t, memPool.AddTx(types.NewTx(logger, fmt.Sprintf("tx-%02d", i), "sig", 10, feePerGas), wg))
+	}
+	wg.Wait()
+	memPool.CloseTxInsertChan()
+
+	for _, tc := range []struct {
+		name      string
+		opts      types.ExportOptions
+		wantCount int
+	}{
+		{name: "single_pass", opts: types.ExportOptions{BatchSize: 100}, wantCount: 50},
+		{name: "small_batches", opts: types.ExportOptions{BatchSize: 3}, wantCount: 50},
+		{name: "default_batch", opts: types.ExportOptions{}, wantCount: 50},
+		{name: "limit", opts: types.ExportOptions{BatchSize: 4, Limit: 10}, wantCount: 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf strings.Builder
+			n, err := memPool.ExportTo(&buf, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCount, n)
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, tc.wantCount)
+			seen := make(map[string]struct{}, len(lines))
+			prevFee := -1.0
+			for _, line := range lines {
+				var txHash, signature string
+				var gas, feePerGas, totalFee float64
+				_, err := fmt.Sscanf(line, "TxHash=%s Gas=%v FeePerGas=%v Signature=%s TotalFee=%v", &txHash, &gas, &feePerGas, &signature, &totalFee)
+				require.NoError(t, err)
+				if prevFee >= 0 {
+					assert.LessOrEqual(t, totalFee, prevFee, "transactions must be exported in descending fee order")
+				}
+				prevFee = totalFee
+				_, dup := seen[txHash]
+				assert.False(t, dup, "transaction %s exported twice", txHash)
+				seen[txHash] = struct{}{}
+			}
+		})
+	}
+	assert.Equal(t, uint32(50), memPool.MempoolLen(), "export must not remove transactions from the pool")
+
+	_, err = memPool.ExportTo(io.Discard, types.ExportOptions{Format: "xml"})
+	require.ErrorIs(t, err, types.ErrUnknownFormat)
+}
+
+func TestMempool_DropHandler(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	var mu sync.Mutex
+	dropped := make(map[string]types.DropReason)
+	memPool, err := types.NewMempool(2, logger, types.WithDropHandler(func(tx *types.Tx, reason types.DropReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped[tx.TxHash] = reason
+	}))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 1) // A single processor admits transactions in submission order
+	for _, tx := range []*types.Tx{
+		types.NewTx(logger, "low", "sig", 1, 5),
+		types.NewTx(logger, "mid", "sig", 1, 10),
+		types.NewTx(logger, "lowest", "sig", 1, 1),   // Pool full and cheaper than everything pooled
+		types.NewTx(logger, "highest", "sig", 1, 20), // Evicts "low"
+	} {
+		require.NoError(t, memPool.AddTx(tx, wg))
+		wg.Wait()
+	}
+	err = memPool.AddTx(types.NewTx(logger, "mid", "sig", 1, 10), wg)
+	require.ErrorIs(t, err, types.ErrDuplicateTx)
+	memPool.CloseTxInsertChan()
+
+	assert.Equal(t, map[string]types.DropReason{"lowest": types.DropFeeTooLow, "low": types.DropEvicted}, dropped)
+	assert.Equal(t, uint32(2), memPool.MempoolLen())
+}
+
+func TestMempool_AddTxs(t *testing.T) {
+	type batchTx struct {
+		txHash    string
+		feePerGas float64
+	}
+	for _, tc := range []struct {
+		name        string
+		maxPoolSize uint32
+		pooled      []batchTx // Added in an earlier batch
+		batch       []batchTx
+		wantErrs    []error
+		wantPool    []string // Hashes in descending fee order
+		wantEvicted []string
+	}{
+		{
+			name:        "success_fits_empty_pool",
+			maxPoolSize: 10,
+			batch:       []batchTx{{"a", 1}, {"b", 3}, {"c", 2}},
+			wantErrs:    []error{nil, nil, nil},
+			wantPool:    []string{"b", "c", "a"},
+		},
+		{
+			name:        "failure_duplicate_within_batch",
+			maxPoolSize: 10,
+			batch:       []batchTx{{"a", 1}, {"b", 2}, {"a", 5}},
+			wantErrs:    []error{nil, nil, types.ErrDuplicateTx},
+			wantPool:    []string{"b", "a"},
+		},
+		{
+			name:        "failure_duplicate_of_pooled",
+			maxPoolSize: 10,
+			pooled:      []batchTx{{"a", 1}},
+			batch:       []batchTx{{"a", 1}, {"b", 2}},
+			wantErrs:    []error{types.ErrDuplicateTx, nil},
+			wantPool:    []string{"b", "a"},
+		},
+		{
+			name:        "success_full_pool_keeps_highest_fees",
+			maxPoolSize: 3,
+			pooled:      []batchTx{{"p1", 1}, {"p2", 2}, {"p3", 3}},
+			batch:       []batchTx{{"a", 5}, {"b", 0.5}, {"c", 4}, {"d", 2.5}},
+			wantErrs:    []error{nil, types.ErrFeeTooLow, nil, types.ErrFeeTooLow},
+			wantPool:    []string{"a", "c", "p3"},
+			wantEvicted: []string{"p1", "p2"},
+		},
+		{
+			name:        "success_batch_larger_than_pool",
+			maxPoolSize: 2,
+			batch:       []batchTx{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}},
+			wantErrs:    []error{types.ErrFeeTooLow, types.ErrFeeTooLow, nil, nil},
+			wantPool:    []string{"d", "c"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := logging.Logger()
+			require.NoError(t, err, "Failed to initialize logger for test")
+			var evicted []string
+			memPool, err := types.NewMempool(tc.maxPoolSize, logger, types.WithDropHandler(func(tx *types.Tx, reason types.DropReason) {
+				assert.Equal(t, types.DropEvicted, reason)
+				evicted = append(evicted, tx.TxHash) // AddTxs reports drops on the calling goroutine
+			}))
+			require.NoError(t, err)
+			toTxs := func(batch []batchTx) []*types.Tx {
+				txs := make([]*types.Tx, len(batch))
+				for i, b := range batch {
+					txs[i] = types.NewTx(logger, b.txHash, "sig", 10, b.feePerGas)
+				}
+				return txs
+			}
+			for _, err := range memPool.AddTxs(toTxs(tc.pooled)) {
+				require.NoError(t, err)
+			}
+
+			errs := memPool.AddTxs(toTxs(tc.batch))
+			require.Len(t, errs, len(tc.wantErrs))
+			for i, wantErr := range tc.wantErrs {
+				if wantErr == nil {
+					assert.NoError(t, errs[i], "transaction %d", i)
+				} else {
+					assert.ErrorIs(t, errs[i], wantErr, "transaction %d", i)
+				}
+			}
+			assert.ElementsMatch(t, tc.wantEvicted, evicted)
+
+			var buf strings.Builder
+			_, err = memPool.ExportTo(&buf, types.ExportOptions{})
+			require.NoError(t, err)
+			var hashes []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				hashes = append(hashes, strings.TrimPrefix(strings.Fields(line)[0], "TxHash="))
+			}
+			assert.Equal(t, tc.wantPool, hashes)
+			assert.Equal(t, uint32(len(tc.wantPool)), memPool.MempoolLen())
+		})
+	}
+}
+
+// TestMempool_DuplicateReservation checks that a hash is reserved exactly once from submission until
+// it is dropped or evicted, whichever pool implementation is used. Run it with -race.
+func TestMempool_DuplicateReservation(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func() (types.Mempool, error)
+	}{
+		{name: "single", new: func() (types.Mempool, error) { return types.NewMempool(1, logger) }},
+		{name: "sharded", new: func() (types.Mempool, error) { return types.NewShardedMempool(1, 4, logger) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			memPool, err := tc.new()
+			require.NoError(t, err)
+			wg := &sync.WaitGroup{}
+			memPool.StartProcessors(wg, 4)
+
+			// Concurrent submissions of the same hash: exactly one is accepted.
+			var accepted int64
+			var submitters sync.WaitGroup
+			for i := 0; i < 32; i++ {
+				submitters.Add(1)
+				go func() {
+					defer submitters.Done()
+					if err := memPool.AddTx(types.NewTx(logger, "same", "sig", 10, 5), wg); err == nil {
+						atomic.AddInt64(&accepted, 1)
+					} else {
+						assert.ErrorIs(t, err, types.ErrDuplicateTx)
+					}
+				}()
+			}
+			submitters.Wait()
+			wg.Wait()
+			assert.Equal(t, int64(1), accepted)
+
+			// Pooled: rejected by AddTx and AddTxs.
+			require.ErrorIs(t, memPool.AddTx(types.NewTx(logger, "same", "sig", 10, 5), wg), types.ErrDuplicateTx)
+			require.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "same", "sig", 10, 5)})[0], types.ErrDuplicateTx)
+
+			// Dropped for a low fee: the reservation is released, so the hash may be submitted again.
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "cheap", "sig", 10, 1), wg))
+			wg.Wait()
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "cheap", "sig", 10, 1), wg))
+			wg.Wait()
+
+			// Evicted: released as well.
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "better", "sig", 10, 9), wg))
+			wg.Wait()
+			_, ok := memPool.GetTx("same")
+			require.False(t, ok)
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "same", "sig", 10, 5), wg))
+			wg.Wait()
+			memPool.CloseTxInsertChan()
+			_, ok = memPool.GetTx("better")
+			assert.True(t, ok)
+			assert.Equal(t, uint32(1), memPool.MempoolLen())
+		})
+	}
+}
+
+func TestMempool_SetMaxMemPoolSize(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func(opts ...types.MempoolOption) (types.Mempool, error)
+	}{
+		{name: "single", new: func(opts ...types.MempoolOption) (types.Mempool, error) { return types.NewMempool(5, logger, opts...) }},
+		{name: "sharded", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewShardedMempool(5, 4, logger, opts...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			evicted := make(map[string]types.DropReason)
+			memPool, err := tc.new(types.WithDropHandler(func(tx *types.Tx, reason types.DropReason) {
+				evicted[tx.TxHash] = reason
+			}))
+			require.NoError(t, err)
+			for i := 1; i <= 5; i++ {
+				require.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 1, float64(i))})[0])
+			}
+
+			_, err = memPool.SetMaxMemPoolSize(0)
+			require.ErrorIs(t, err, types.ErrMempoolSize)
+			assert.Equal(t, uint32(5), memPool.MaxMemPoolSize())
+
+			// Shrinking evicts the lowest-fee transactions.
+			n, err := memPool.SetMaxMemPoolSize(2)
+			require.NoError(t, err)
+			assert.Equal(t, 3, n)
+			assert.Equal(t, uint32(2), memPool.MaxMemPoolSize())
+			assert.Equal(t, uint32(2), memPool.MempoolLen())
+			assert.Equal(t, map[string]types.DropReason{"tx-1": types.DropEvicted, "tx-2": types.DropEvicted, "tx-3": types.DropEvicted}, evicted)
+			_, ok := memPool.GetTx("tx-1")
+			assert.False(t, ok)
+			// The evicted hash is no longer reserved, so it is judged on its fee rather than as a duplicate.
+			assert.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "tx-1", "sig", 1, 1)})[0], types.ErrFeeTooLow)
+
+			// Growing only raises the limit, so new transactions fit without evictions.
+			n, err = memPool.SetMaxMemPoolSize(4)
+			require.NoError(t, err)
+			assert.Zero(t, n)
+			for _, err := range memPool.AddTxs([]*types.Tx{types.NewTx(logger, "a", "sig", 1, 0.5), types.NewTx(logger, "b", "sig", 1, 0.25)}) {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, uint32(4), memPool.MempoolLen())
+			assert.Len(t, evicted, 3)
+		})
+	}
+}
+
+func TestMempool_SetMinFeePerGas(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func(opts ...types.MempoolOption) (types.Mempool, error)
+	}{
+		{name: "single", new: func(opts ...types.MempoolOption) (types.Mempool, error) { return types.NewMempool(5, logger, opts...) }},
+		{name: "sharded", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewShardedMempool(5, 4, logger, opts...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			memPool, err := tc.new(types.WithMinFeePerGas(2))
+			require.NoError(t, err)
+			assert.Equal(t, 2.0, memPool.MinFeePerGas())
+			require.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "a", "sig", 1, 2)})[0])
+
+			memPool.SetMinFeePerGas(3)
+			assert.Equal(t, 3.0, memPool.MinFeePerGas())
+			assert.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "b", "sig", 1, 2.5)})[0], types.ErrBelowMinFee)
+			_, ok := memPool.GetTx("a")
+			assert.True(t, ok, "raising the floor keeps pooled transactions")
+
+			memPool.SetMinFeePerGas(0)
+			assert.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "b", "sig", 1, 0.5)})[0])
+		})
+	}
+}
+
+func TestMempool_PauseResume(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func() (types.Mempool, error)
+	}{
+		{name: "single", new: func() (types.Mempool, error) { return types.NewMempool(10, logger) }},
+		{name: "sharded", new: func() (types.Mempool, error) { return types.NewShardedMempool(10, 4, logger) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			memPool, err := tc.new()
+			require.NoError(t, err)
+			wg := &sync.WaitGroup{}
+			memPool.StartProcessors(wg, 2)
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "before", "sig", 1, 5), wg))
+			assert.False(t, memPool.Paused())
+
+			memPool.Pause()
+			assert.True(t, memPool.Paused())
+			assert.ErrorIs(t, memPool.AddTx(types.NewTx(logger, "during", "sig", 1, 5), wg), types.ErrPaused)
+			for _, err := range memPool.AddTxs([]*types.Tx{types.NewTx(logger, "batch-1", "sig", 1, 5), types.NewTx(logger, "batch-2", "sig", 1, 5)}) {
+				assert.ErrorIs(t, err, types.ErrPaused)
+			}
+			// Transactions accepted before the pause are still processed and reads keep working.
+			wg.Wait()
+			_, ok := memPool.GetTx("before")
+			assert.True(t, ok)
+			assert.Len(t, memPool.ReapMaxTxs(0), 1)
+
+			memPool.Resume()
+			assert.False(t, memPool.Paused())
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "during", "sig", 1, 5), wg), "a paused submission did not reserve its hash")
+			wg.Wait()
+			memPool.CloseTxInsertChan()
+			assert.Equal(t, uint32(2), memPool.MempoolLen())
+		})
+	}
+}
+
+func TestMempool_ReinjectAndUpdate(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func(opts ...types.MempoolOption) (types.Mempool, error)
+	}{
+		{name: "single", new: func(opts ...types.MempoolOption) (types.Mempool, error) { return types.NewMempool(4, logger, opts...) }},
+		{name: "sharded", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewShardedMempool(4, 4, logger, opts...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wal, err := types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			memPool, err := tc.new(types.WithWAL(wal), types.WithMinFeePerGas(2))
+			require.NoError(t, err)
+			errs := memPool.AddTxs([]*types.Tx{
+				types.NewTx(logger, "a", "sig", 1, 5),
+				types.NewTx(logger, "b", "sig", 1, 4),
+				types.NewTx(logger, "c", "sig", 1, 3),
+				types.NewTx(logger, "cheap", "sig", 1, 1),
+			})
+			require.NoError(t, errs[0])
+			require.NoError(t, errs[1])
+			require.NoError(t, errs[2])
+			require.ErrorIs(t, errs[3], types.ErrBelowMinFee)
+
+			// A block built from the pool is committed, then orphaned while admission is paused.
+			block := memPool.ReapMaxTxs(2)
+			arrival, sequence := block[0].ArrivalTime, block[0].Sequence
+			assert.Equal(t, 2, memPool.Update(append(block, types.NewTx(logger, "unknown", "sig", 1, 5))))
+			assert.Equal(t, uint32(1), memPool.MempoolLen())
+			_, ok := memPool.GetTx("a")
+			assert.False(t, ok)
+
+			memPool.Pause()
+			errs = memPool.Reinject(append(block, types.NewTx(logger, "cheap", "sig", 1, 1), types.NewTx(logger, "c", "sig", 1, 3)))
+			require.Len(t, errs, 4)
+			assert.NoError(t, errs[0])
+			assert.NoError(t, errs[1])
+			assert.NoError(t, errs[2], "re-injected transactions bypass the fee floor")
+			assert.ErrorIs(t, errs[3], types.ErrDuplicateTx)
+			assert.Equal(t, uint32(4), memPool.MempoolLen())
+			tx, ok := memPool.GetTx("a")
+			require.True(t, ok)
+			assert.Equal(t, arrival, tx.ArrivalTime)
+			assert.Equal(t, sequence, tx.Sequence)
+
+			// A full pool still only takes re-injected transactions that outrank its minimum.
+			errs = memPool.Reinject([]*types.Tx{types.NewTx(logger, "d", "sig", 1, 0.5)})
+			assert.ErrorIs(t, errs[0], types.ErrFeeTooLow)
+			require.NoError(t, wal.Close())
+
+			wal, err = types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			defer wal.Close()
+			restored, err := tc.new(types.WithWAL(wal))
+			require.NoError(t, err)
+			var hashes []string
+			for _, tx := range restored.ReapMaxTxs(0) {
+				hashes = append(hashes, tx.TxHash)
+			}
+			assert.Equal(t, []string{"a", "b", "c", "cheap"}, hashes, "removals and re-injections are replayed from the WAL")
+		})
+	}
+}
+
+func TestMempool_TxStatus(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func(opts ...types.MempoolOption) (types.Mempool, error)
+	}{
+		{name: "single", new: func(opts ...types.MempoolOption) (types.Mempool, error) { return types.NewMempool(2, logger, opts...) }},
+		{name: "sharded", new: func(opts ...types.MempoolOption) (types.Mempool, error) {
+			return types.NewShardedMempool(2, 4, logger, opts...)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			memPool, err := tc.new(types.WithStatusIndex(3, 0), types.WithMinFeePerGas(1))
+			require.NoError(t, err)
+			status := func(txHash string) types.TxStatus {
+				t.Helper()
+				status, ok := memPool.TxStatus(txHash)
+				require.True(t, ok, "status of %s", txHash)
+				assert.Equal(t, txHash, status.TxHash)
+				return status
+			}
+			_, ok := memPool.TxStatus("unknown")
+			assert.False(t, ok)
+
+			wg := &sync.WaitGroup{}
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "a", "sig", 1, 2), wg))
+			assert.Equal(t, types.StatusQueued, status("a").Status, "no processor has run yet")
+			memPool.StartProcessors(wg, 1)
+			wg.Wait()
+			assert.Equal(t, types.StatusPooled, status("a").Status)
+
+			errs := memPool.AddTxs([]*types.Tx{
+				types.NewTx(logger, "b", "sig", 1, 3), // Evicts "a" once "c" takes the free slot
+				types.NewTx(logger, "c", "sig", 1, 4),
+				types.NewTx(logger, "d", "sig", 1, 1.5),
+				types.NewTx(logger, "e", "sig", 1, 0.5),
+			})
+			require.NoError(t, errs[0])
+			require.NoError(t, errs[1])
+			assert.Equal(t, types.TxStatus{TxHash: "a", Status: types.StatusEvicted, Reason: "outranked by a higher-fee transaction", EvictedBy: "b", Time: status("a").Time}, status("a"))
+			assert.False(t, status("a").Time.IsZero())
+			assert.Equal(t, types.StatusRejected, status("d").Status)
+			assert.Equal(t, types.ErrFeeTooLow.Error(), status("d").Reason)
+			assert.Equal(t, types.ErrBelowMinFee.Error(), status("e").Reason)
+
+			assert.Equal(t, 1, memPool.Update([]*types.Tx{types.NewTx(logger, "c", "sig", 1, 4)}))
+			assert.Equal(t, types.StatusIncluded, status("c").Status)
+			_, ok = memPool.TxStatus("e")
+			assert.False(t, ok, "the index keeps only the 3 most recent statuses")
+
+			// A hash that enters the pool again reports its live state.
+			require.NoError(t, memPool.Reinject([]*types.Tx{types.NewTx(logger, "c", "sig", 1, 4)})[0])
+			assert.Equal(t, types.StatusPooled, status("c").Status)
+			memPool.CloseTxInsertChan()
+		})
+	}
+
+	t.Run("ttl", func(t *testing.T) {
+		memPool, err := types.NewMempool(1, logger, types.WithStatusIndex(10, 50*time.Millisecond))
+		require.NoError(t, err)
+		require.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "a", "sig", 1, 2)})[0])
+		require.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "b", "sig", 1, 1)})[0], types.ErrFeeTooLow)
+		status, ok := memPool.TxStatus("b")
+		require.True(t, ok)
+		recorded := status.Time
+		time.Sleep(70 * time.Millisecond)
+		status, ok = memPool.TxStatus("b")
+		require.True(t, ok, "an expired status is reported for one more TTL")
+		assert.Equal(t, types.StatusExpired, status.Status)
+		assert.Contains(t, status.Reason, "rejected")
+		assert.Equal(t, recorded.Add(50*time.Millisecond), status.Time)
+		time.Sleep(60 * time.Millisecond)
+		_, ok = memPool.TxStatus("b")
+		assert.False(t, ok, "the tombstone is forgotten after a second TTL")
+
+		// A new terminal status replaces the tombstone.
+		require.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "d", "sig", 1, 1)})[0], types.ErrFeeTooLow)
+		time.Sleep(70 * time.Millisecond)
+		status, _ = memPool.TxStatus("d")
+		require.Equal(t, types.StatusExpired, status.Status)
+		require.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "d", "sig", 1, 1)})[0], types.ErrFeeTooLow)
+		status, _ = memPool.TxStatus("d")
+		assert.Equal(t, types.StatusRejected, status.Status)
+
+		// Shortening the TTL at runtime expires entries that are now too old.
+		memPool.SetStatusIndexTTL(time.Hour)
+		require.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "c", "sig", 1, 1)})[0], types.ErrFeeTooLow)
+		time.Sleep(20 * time.Millisecond)
+		_, ok = memPool.TxStatus("c")
+		require.True(t, ok)
+		memPool.SetStatusIndexTTL(time.Millisecond)
+		_, ok = memPool.TxStatus("c")
+		assert.False(t, ok, "expired by the shorter TTL")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		memPool, err := types.NewMempool(1, logger)
+		require.NoError(t, err)
+		require.NoError(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "a", "sig", 1, 2)})[0])
+		require.ErrorIs(t, memPool.AddTxs([]*types.Tx{types.NewTx(logger, "b", "sig", 1, 1)})[0], types.ErrFeeTooLow)
+		status, ok := memPool.TxStatus("a")
+		require.True(t, ok, "live states need no index")
+		assert.Equal(t, types.StatusPooled, status.Status)
+		_, ok = memPool.TxStatus("b")
+		assert.False(t, ok)
+	})
+}
+
 func BenchmarkMempool_AddTx(b *testing.B) {
 	logger, err := logging.Logger()
 	require.NoError(b, err, "Failed to initialize logger for benchmark")
@@ -265,13 +891,14 @@ func BenchmarkMempool_AddTx(b *testing.B) {
 					txs[j] = generateUniqueTx(logger, j) // Generate a unique transaction
 				}
 				wg := &sync.WaitGroup{}
+				memPool.StartProcessors(wg, 2)
 				b.StartTimer() // Restart timer for the actual operation
 
 				for j := 0; j < numTxs; j++ {
 					memPool.AddTx(txs[j], wg)
 				}
-				memPool.CloseTxInsertChan()
 				wg.Wait() // Wait for all processing goroutines to finish their current tasks
+				memPool.CloseTxInsertChan()
 
 				b.StopTimer() // Stop timer after operation
 			}
@@ -279,6 +906,41 @@ func BenchmarkMempool_AddTx(b *testing.B) {
 	}
 }
 
+// BenchmarkMempool_AddTxs adds the same workloads as BenchmarkMempool_AddTx in batches, for comparison
+// with looping AddTx. Batch size 0 adds every transaction in a single batch.
+func BenchmarkMempool_AddTxs(b *testing.B) {
+	logger, err := logging.Logger()
+	require.NoError(b, err, "Failed to initialize logger for benchmark")
+	sizes := []int{100, 1000, 10000, 100000} // Different numbers of transactions to add
+	batchSizes := []int{64, 1024, 0}
+
+	for _, numTxs := range sizes {
+		for _, batchSize := range batchSizes {
+			b.Run(fmt.Sprintf("NumTxs-%d/BatchSize-%d", numTxs, batchSize), func(b *testing.B) {
+				if batchSize == 0 {
+					batchSize = numTxs
+				}
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()                                            // Stop timer for setup
+					memPool, err := types.NewMempool(uint32(numTxs), logger) // Max pool size same as numTxs for this benchmark
+					require.NoError(b, err)
+					txs := make([]*types.Tx, numTxs)
+					for j := 0; j < numTxs; j++ {
+						txs[j] = generateUniqueTx(logger, j) // Generate a unique transaction
+					}
+					b.StartTimer() // Restart timer for the actual operation
+
+					for start := 0; start < numTxs; start += batchSize {
+						memPool.AddTxs(txs[start:min(start+batchSize, numTxs)])
+					}
+
+					b.StopTimer() // Stop timer after operation
+				}
+			})
+		}
+	}
+}
+
 func BenchmarkMempool_ExportToFile(b *testing.B) {
 	logger, err := logging.Logger()
 	require.NoError(b, err, "Failed to initialize logger for benchmark")
@@ -286,20 +948,13 @@ func BenchmarkMempool_ExportToFile(b *testing.B) {
 
 	for _, size := range sizes {
 		b.Run(fmt.Sprintf("PoolSize-%d", size), func(b *testing.B) {
+			b.ReportAllocs()
 			for i := 0; i < b.N; i++ {
 				b.StopTimer() // Stop timer for setup
-				memPool, err := types.NewMempool(uint32(size), logger)
-				require.NoError(b, err)
-				wg := &sync.WaitGroup{}
-				for j := 0; j < size; j++ {
-					tx := generateUniqueTx(logger, j) // Generate a unique transaction
-					memPool.AddTx(tx, wg)
-				}
-				memPool.CloseTxInsertChan()
-				wg.Wait() // Ensure all transactions are processed and inserted
+				memPool := newBenchmarkMempool(b, logger, size)
 
 				b.StartTimer() // Restart timer for the actual operation
-				err = memPool.ExportToFile()
+				_, err = memPool.ExportToFile()
 				if err != nil {
 					b.Fatalf("ExportToFile failed: %v", err)
 				}
@@ -312,6 +967,48 @@ func BenchmarkMempool_ExportToFile(b *testing.B) {
 	}
 }
 
+// BenchmarkMempool_ExportTo streams the pool to io.Discard with different batch sizes. BatchSize-0
+// selects the whole pool in one pass, which is what an unbatched export costs: compare its bytes per
+// op with the bounded batches to see the memory saved, and its time to see the cost of extra passes.
+func BenchmarkMempool_ExportTo(b *testing.B) {
+	logger, err := logging.Logger()
+	require.NoError(b, err, "Failed to initialize logger for benchmark")
+	sizes := []int{10000, 50000}       // Different mempool sizes to export
+	batchSizes := []int{1024, 4096, 0} // 0 selects the whole pool in a single pass
+
+	for _, size := range sizes {
+		memPool := newBenchmarkMempool(b, logger, size)
+		for _, batchSize := range batchSizes {
+			b.Run(fmt.Sprintf("PoolSize-%d/BatchSize-%d", size, batchSize), func(b *testing.B) {
+				if batchSize == 0 {
+					batchSize = size
+				}
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := memPool.ExportTo(io.Discard, types.ExportOptions{BatchSize: batchSize}); err != nil {
+						b.Fatalf("ExportTo failed: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Helper function to build a mempool filled with size unique transactions for benchmarks
+func newBenchmarkMempool(b *testing.B, logger logging.LoggingSystem, size int) types.Mempool {
+	memPool, err := types.NewMempool(uint32(size), logger)
+	require.NoError(b, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 2)
+	for j := 0; j < size; j++ {
+		tx := generateUniqueTx(logger, j) // Generate a unique transaction
+		memPool.AddTx(tx, wg)
+	}
+	wg.Wait() // Ensure all transactions are processed and inserted
+	memPool.CloseTxInsertChan()
+	return memPool
+}
+
 // Helper function to generate a unique transaction for benchmarks
 func generateUniqueTx(logger logging.LoggingSystem, id int) *types.Tx {
 	return types.NewTx(logger, fmt.Sprintf("txHash-%d-%d", id, time.Now().UnixNano()), "signature", rand.Float64()*100, rand.Float64()*10)
@@ -0,0 +1,695 @@
+package types
+
+import (
+	"container/heap"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/logging"
+)
+
+var (
+	ErrShardCount = errors.New("shard count must be greater than 0")
+)
+
+// shardedMempool partitions the pool by transaction hash into independently locked shards, so
+// processors admitting transactions with different hashes rarely contend on the same mutex.
+//
+// Capacity is global. While the pool has room, a processor only locks its own shard and claims a
+// slot with a compare-and-swap on count. Once the pool is full, count only decreases when
+// SetMaxMemPoolSize shrinks the pool or Update removes included transactions, and every admission
+// must evict the lowest-fee transaction across all shards; those evictions, resizes and removals are
+// serialised by evictMu, which keeps the shard minimums stable while an evictor scans them.
+//
+// Duplicates are rejected by each shard's hash reservations (see mempool.reserved) without locking.
+// Lock order is evictMu, then shard locks in index order.
+type shardedMempool struct {
+	shards         []*mempool // Each shard reuses the single-lock pool's map, heap and reservations
+	evictMu        sync.Mutex // Serialises evictions and batch admissions once the pool is full
+	count          int64      // Transactions pooled across all shards, accessed atomically
+	txChan         chan *Tx
+	maxMemPoolSize uint32 // Accessed atomically
+	logger         logging.LoggingSystem
+	hot            hotLoggers
+
+	wal    *WAL        // Optional, shared by all shards
+	seq    uint64      // Last admission sequence number handed out, accessed atomically
+	onDrop DropHandler // Optional, nil when nobody needs to know about dropped transactions
+	paused uint32      // 1 while admission is paused, accessed atomically
+
+	minFeePerGas uint64    // See mempool.minFeePerGas
+	events       observers // Shared by all shards, which have no observers of their own
+
+	exportPath, exportFormat string // See WithExport
+}
+
+var _ Mempool = (*shardedMempool)(nil)
+
+// NewShardedMempool returns a Mempool split into numShards shards with a combined capacity of
+// maxPoolSize. It accepts the same options as NewMempool.
+func NewShardedMempool(maxPoolSize uint32, numShards int, ls logging.LoggingSystem, opts ...MempoolOption) (Mempool, error) {
+	if maxPoolSize <= 0 {
+		return nil, ErrMempoolSize
+	}
+	if numShards <= 0 {
+		return nil, ErrShardCount
+	}
+	config := &mempool{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	s := &shardedMempool{
+		shards:         make([]*mempool, numShards),
+		txChan:         make(chan *Tx, 200000), // Buffered channel to hold transactions before processing
+		maxMemPoolSize: maxPoolSize,
+		logger:         ls,
+		hot:            newHotLoggers(ls),
+		wal:            config.wal,
+		onDrop:         config.onDrop,
+		minFeePerGas:   config.minFeePerGas,
+		events:         config.events,
+		exportPath:     config.exportPath,
+		exportFormat:   config.exportFormat,
+	}
+	s.events.history = &feeHistory{}
+	s.events.stats = newPoolStats()
+	shardSize := maxPoolSize/uint32(numShards) + 1
+	for i := range s.shards {
+		s.shards[i] = &mempool{
+			mu:             &sync.Mutex{},
+			maxMemPoolSize: maxPoolSize,
+			logger:         ls,
+			hot:            s.hot,
+			txMap:          make(map[string]*Tx, shardSize),
+			txHeap:         make(TxHeap, 0, shardSize),
+		}
+	}
+	s.events.start(ls)
+	if s.wal != nil {
+		if err := s.restoreFromWAL(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// restoreFromWAL admits the transactions replayed by the WAL, then compacts the log.
+func (s *shardedMempool) restoreFromWAL() error {
+	recovered := s.wal.Recovered()
+	for _, tx := range recovered {
+		if err := tx.Validate(); err != nil {
+			s.logger.Named("mempool/restoreFromWAL").Warn("discarding invalid transaction from WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+			continue
+		}
+		tx.calculateTotalFees()
+		if _, reserved := s.shards[s.shardFor(tx.TxHash)].claim(tx.TxHash, txQueued); reserved {
+			continue
+		}
+		s.insert(tx, false)
+		if tx.Sequence > s.seq {
+			s.seq = tx.Sequence // No processors are running yet, so no atomic access is needed
+		}
+	}
+	s.logger.Named("mempool/restoreFromWAL").Info("restored transactions from WAL", zap.Int("replayed", len(recovered)), zap.Uint32("restored", s.MempoolLen()))
+	s.lockAll()
+	defer s.unlockAll()
+	return errors.Wrap(s.wal.Compact(s.txsLocked()), "failed to compact WAL after restore")
+}
+
+func (s *shardedMempool) MaxMemPoolSize() uint32 {
+	return atomic.LoadUint32(&s.maxMemPoolSize)
+}
+
+// SetMaxMemPoolSize changes the global capacity at runtime like mempool.SetMaxMemPoolSize,
+// evicting the lowest-priority transactions across all shards when shrinking.
+func (s *shardedMempool) SetMaxMemPoolSize(n uint32) (int, error) {
+	if n == 0 {
+		return 0, ErrMempoolSize
+	}
+	var evicted []*Tx
+	s.evictMu.Lock()
+	s.lockAll()
+	previous := atomic.SwapUint32(&s.maxMemPoolSize, n)
+	for atomic.LoadInt64(&s.count) > int64(n) {
+		minTx := s.popMinLocked(s.shards[s.minShardLocked()], true)
+		evicted = append(evicted, minTx)
+		s.events.evicted(minTx, nil)
+		atomic.AddInt64(&s.count, -1)
+	}
+	s.unlockAll()
+	s.evictMu.Unlock()
+	for _, tx := range evicted {
+		s.drop(tx, DropEvicted)
+	}
+	s.commitWAL()
+	s.logger.Named("mempool/SetMaxMemPoolSize").Info("changed mempool capacity", zap.Uint32("previous", previous), zap.Uint32("maxMemPoolSize", n), zap.Int("evicted", len(evicted)))
+	return len(evicted), nil
+}
+
+// shardFor maps a transaction hash to its shard using FNV-1a.
+func (s *shardedMempool) shardFor(txHash string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(txHash); i++ {
+		h ^= uint32(txHash[i])
+		h *= 16777619
+	}
+	return int(h % uint32(len(s.shards)))
+}
+
+// Pause behaves like mempool.Pause.
+func (s *shardedMempool) Pause() {
+	if atomic.SwapUint32(&s.paused, 1) == 0 {
+		s.logger.Named("mempool/Pause").Info("paused admission")
+	}
+}
+
+// Resume behaves like mempool.Resume.
+func (s *shardedMempool) Resume() {
+	if atomic.SwapUint32(&s.paused, 0) == 1 {
+		s.logger.Named("mempool/Resume").Info("resumed admission")
+	}
+}
+
+func (s *shardedMempool) MinFeePerGas() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.minFeePerGas))
+}
+
+// SetMinFeePerGas changes the fee floor at runtime like mempool.SetMinFeePerGas.
+func (s *shardedMempool) SetMinFeePerGas(min float64) {
+	previous := math.Float64frombits(atomic.SwapUint64(&s.minFeePerGas, math.Float64bits(min)))
+	s.logger.Named("mempool/SetMinFeePerGas").Info("changed minimum fee per gas", zap.Float64("previous", previous), zap.Float64("minFeePerGas", min))
+}
+
+// SetStatusIndexTTL changes the status index TTL like mempool.SetStatusIndexTTL.
+func (s *shardedMempool) SetStatusIndexTTL(ttl time.Duration) {
+	s.events.status.setTTL(ttl)
+}
+
+func (s *shardedMempool) Paused() bool {
+	return atomic.LoadUint32(&s.paused) == 1
+}
+
+func (s *shardedMempool) AddTx(tx *Tx, group *sync.WaitGroup) (err error) {
+	defer func() {
+		if err != nil {
+			s.events.rejected(tx, err)
+		}
+	}()
+	if s.Paused() {
+		return errPaused(tx)
+	}
+	if err = tx.Validate(); err != nil {
+		return err
+	}
+	if err = checkMinFee(tx, s.MinFeePerGas()); err != nil {
+		return err
+	}
+	if err = s.shards[s.shardFor(tx.TxHash)].reserve(tx); err != nil {
+		return err
+	}
+	tx.calculateTotalFees()
+	if tx.ArrivalTime.IsZero() {
+		tx.ArrivalTime = time.Now()
+	}
+	tx.Sequence = atomic.AddUint64(&s.seq, 1)
+
+	group.Add(1)
+	s.txChan <- tx
+	s.hot.addTx.Debug("Transaction with hash accepted and sent to processing channel", zap.String("txHash", tx.TxHash))
+	return nil
+}
+
+// StartProcessors starts a specified number of goroutines to process transactions from the mempool.
+func (s *shardedMempool) StartProcessors(wg *sync.WaitGroup, numProcessors uint8) {
+	for i := uint8(0); i < numProcessors; i++ {
+		go s.processTx(wg)
+	}
+}
+
+func (s *shardedMempool) processTx(wg *sync.WaitGroup) {
+	for tx := range s.txChan {
+		evicted, inserted := s.insert(tx, true)
+		if !inserted {
+			s.drop(tx, DropFeeTooLow)
+		} else if evicted != nil {
+			s.drop(evicted, DropEvicted)
+		}
+		s.commitWAL()
+		wg.Done()
+	}
+	s.hot.processTx.Info("Channel closed, processor shutting down.")
+}
+
+func (s *shardedMempool) drop(tx *Tx, reason DropReason) {
+	if s.onDrop != nil {
+		s.onDrop(tx, reason)
+	}
+}
+
+// insert admits tx, evicting the lowest-fee transaction across all shards when the pool is full,
+// and returns the evicted transaction, if any. It reports false when tx does not fit, releasing its
+// claim. The caller must have claimed tx.TxHash in its shard and may not hold any lock.
+func (s *shardedMempool) insert(tx *Tx, logToWAL bool) (evicted *Tx, inserted bool) {
+	index := s.shardFor(tx.TxHash)
+	shard := s.shards[index]
+	shard.mu.Lock()
+	if s.claimSlot() {
+		s.pushLocked(shard, tx, logToWAL)
+		shard.mu.Unlock()
+		return nil, true
+	}
+	shard.mu.Unlock()
+
+	// The pool is full and stays full, so only evictors change shard minimums from here on.
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+	shard.mu.Lock()
+	if s.claimSlot() { // SetMaxMemPoolSize or Update made room while we waited
+		s.pushLocked(shard, tx, logToWAL)
+		shard.mu.Unlock()
+		return nil, true
+	}
+	shard.mu.Unlock()
+	minIndex := s.minShard()
+	first, second := min(index, minIndex), max(index, minIndex)
+	s.shards[first].mu.Lock()
+	defer s.shards[first].mu.Unlock()
+	if second != first {
+		s.shards[second].mu.Lock()
+		defer s.shards[second].mu.Unlock()
+	}
+	if tx.TotalFee <= s.shards[minIndex].txHeap[0].TotalFee {
+		shard.reserved.Delete(tx.TxHash)
+		s.events.rejected(tx, errFeeTooLow(tx))
+		return nil, false
+	}
+	evicted = s.popMinLocked(s.shards[minIndex], logToWAL)
+	s.events.evicted(evicted, tx)
+	s.pushLocked(shard, tx, logToWAL)
+	return evicted, true
+}
+
+// claimSlot reserves room for one more transaction, reporting false when the pool is full.
+func (s *shardedMempool) claimSlot() bool {
+	for {
+		n := atomic.LoadInt64(&s.count)
+		if n >= int64(s.MaxMemPoolSize()) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&s.count, n, n+1) {
+			return true
+		}
+	}
+}
+
+// minShard returns the index of the shard holding the lowest-fee transaction. The pool must be full.
+func (s *shardedMempool) minShard() int {
+	minIndex := -1
+	var minFee float64
+	for i, shard := range s.shards {
+		shard.mu.Lock()
+		if len(shard.txHeap) > 0 && (minIndex < 0 || shard.txHeap[0].TotalFee < minFee) {
+			minIndex, minFee = i, shard.txHeap[0].TotalFee
+		}
+		shard.mu.Unlock()
+	}
+	return minIndex
+}
+
+// minShardLocked is minShard for callers already holding every shard lock.
+func (s *shardedMempool) minShardLocked() int {
+	minIndex := -1
+	for i, shard := range s.shards {
+		if len(shard.txHeap) > 0 && (minIndex < 0 || shard.txHeap[0].TotalFee < s.shards[minIndex].txHeap[0].TotalFee) {
+			minIndex = i
+		}
+	}
+	return minIndex
+}
+
+// pushLocked adds tx to shard without touching count. shard.mu must be held.
+func (s *shardedMempool) pushLocked(shard *mempool, tx *Tx, logToWAL bool) {
+	heap.Push(&shard.txHeap, tx)
+	shard.txMap[tx.TxHash] = tx
+	shard.reserved.Store(tx.TxHash, txPooled)
+	s.events.added(tx)
+	if logToWAL && s.wal != nil {
+		if err := s.wal.logAdd(tx); err != nil {
+			s.logger.Named("mempool/insert").Error("failed to log admission to WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+		}
+	}
+}
+
+// popMinLocked removes the lowest-fee transaction of shard without touching count. shard.mu must be held.
+func (s *shardedMempool) popMinLocked(shard *mempool, logToWAL bool) *Tx {
+	minTx := heap.Pop(&shard.txHeap).(*Tx)
+	delete(shard.txMap, minTx.TxHash)
+	shard.reserved.Delete(minTx.TxHash)
+	if logToWAL && s.wal != nil {
+		if err := s.wal.logRemove(minTx.TxHash); err != nil {
+			s.logger.Named("mempool/popMinLocked").Error("failed to log eviction to WAL", zap.String("txHash", minTx.TxHash), zap.Error(err))
+		}
+	}
+	return minTx
+}
+
+// commitWAL is mempool.commitWAL across shards. Holding every shard lock while the pointers are
+// copied and the log is switched keeps appends out, so the snapshot matches the records it replaces.
+func (s *shardedMempool) commitWAL() {
+	if s.wal == nil {
+		return
+	}
+	if err := s.wal.syncAppended(); err != nil {
+		s.logger.Named("mempool/commitWAL").Error("failed to sync WAL", zap.Error(err))
+	}
+	if !s.wal.ShouldCompact() {
+		return
+	}
+	s.lockAll()
+	defer s.unlockAll()
+	if err := s.wal.startCompaction(s.txsLocked(), s.compacted); err != nil {
+		s.logger.Named("mempool/commitWAL").Error("failed to compact WAL", zap.Error(err))
+	}
+}
+
+// compacted logs the outcome of a background compaction.
+func (s *shardedMempool) compacted(err error) {
+	if err != nil {
+		s.logger.Named("mempool/commitWAL").Error("failed to compact WAL, compaction suspended", zap.Error(err))
+	}
+}
+
+func (s *shardedMempool) lockAll() {
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+	}
+}
+
+func (s *shardedMempool) unlockAll() {
+	for _, shard := range s.shards {
+		shard.mu.Unlock()
+	}
+}
+
+// txsLocked returns every pooled transaction. All shard locks must be held.
+func (s *shardedMempool) txsLocked() []*Tx {
+	txs := make([]*Tx, 0, atomic.LoadInt64(&s.count))
+	for _, shard := range s.shards {
+		txs = append(txs, shard.txHeap...)
+	}
+	return txs
+}
+
+// AddTxs admits a batch of transactions synchronously with the same results as mempool.AddTxs.
+// The whole batch is admitted under every shard lock, which costs one acquisition per shard
+// regardless of the batch size.
+func (s *shardedMempool) AddTxs(txs []*Tx) []error {
+	if s.Paused() {
+		return pausedErrors(txs, &s.events)
+	}
+	return s.addBatch(txs, false)
+}
+
+// Reinject returns the transactions of an orphaned block to the pool, like mempool.Reinject.
+func (s *shardedMempool) Reinject(txs []*Tx) []error {
+	return s.addBatch(txs, true)
+}
+
+// addBatch admits txs under every shard lock for AddTxs and Reinject.
+func (s *shardedMempool) addBatch(txs []*Tx, reinject bool) []error {
+	results := make([]error, len(txs))
+	candidates := make([]*Tx, 0, len(txs))
+	seen := make(map[string]struct{}, len(txs))
+	now := time.Now()
+
+	s.evictMu.Lock()
+	s.lockAll()
+	for i, tx := range txs {
+		if _, dup := seen[tx.TxHash]; dup {
+			results[i] = errors.Wrapf(ErrDuplicateTx, "Transaction with hash [%s] appears more than once in the batch", tx.TxHash)
+			s.events.rejected(tx, results[i])
+			continue
+		}
+		seen[tx.TxHash] = struct{}{}
+		if results[i] = tx.Validate(); results[i] != nil {
+			s.events.rejected(tx, results[i])
+			continue
+		}
+		if !reinject {
+			if results[i] = checkMinFee(tx, s.MinFeePerGas()); results[i] != nil {
+				s.events.rejected(tx, results[i])
+				continue
+			}
+		}
+		if results[i] = s.shards[s.shardFor(tx.TxHash)].claimForBatch(tx.TxHash); results[i] != nil {
+			s.events.rejected(tx, results[i])
+			continue
+		}
+		tx.calculateTotalFees()
+		if tx.ArrivalTime.IsZero() {
+			tx.ArrivalTime = now
+		}
+		if !reinject || tx.Sequence == 0 {
+			tx.Sequence = atomic.AddUint64(&s.seq, 1)
+		}
+		candidates = append(candidates, tx)
+	}
+	evicted := s.insertBatchLocked(candidates)
+	var rejected int
+	for i, tx := range txs {
+		if results[i] == nil && s.shards[s.shardFor(tx.TxHash)].txMap[tx.TxHash] != tx {
+			results[i] = errFeeTooLow(tx)
+			s.events.rejected(tx, results[i])
+		}
+		if results[i] != nil {
+			rejected++
+		}
+	}
+	s.unlockAll()
+	s.evictMu.Unlock()
+
+	for _, tx := range evicted {
+		s.drop(tx, DropEvicted)
+	}
+	s.commitWAL()
+	if reinject {
+		s.logger.Named("mempool/Reinject").Info("re-injected transactions", zap.Int("count", len(txs)), zap.Int("rejected", rejected), zap.Int("evicted", len(evicted)))
+	} else {
+		s.logger.Named("mempool/AddTxs").Debug("added transaction batch", zap.Int("count", len(txs)), zap.Int("rejected", rejected), zap.Int("evicted", len(evicted)))
+	}
+	return results
+}
+
+// Update removes the transactions of a newly committed block from their shards, like
+// mempool.Update. It takes evictMu because a shrinking pool would otherwise break an evictor's
+// assumption that the pool stays full.
+func (s *shardedMempool) Update(included []*Tx) int {
+	perShard := make([][]*Tx, len(s.shards))
+	for _, tx := range included {
+		index := s.shardFor(tx.TxHash)
+		perShard[index] = append(perShard[index], tx)
+	}
+	var removed []*Tx
+	s.evictMu.Lock()
+	s.lockAll()
+	for i, txs := range perShard {
+		removed = append(removed, s.shards[i].removeLocked(txs)...) // Shards have no WAL of their own
+	}
+	atomic.AddInt64(&s.count, -int64(len(removed)))
+	for _, tx := range removed {
+		s.events.removed(tx)
+	}
+	if s.wal != nil {
+		for _, tx := range removed {
+			if err := s.wal.logRemove(tx.TxHash); err != nil {
+				s.logger.Named("mempool/Update").Error("failed to log removal to WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+			}
+		}
+	}
+	s.unlockAll()
+	s.evictMu.Unlock()
+	s.commitWAL()
+	s.logger.Named("mempool/Update").Info("removed included transactions", zap.Int("included", len(included)), zap.Int("removed", len(removed)))
+	return len(removed)
+}
+
+// insertBatchLocked is mempool.insertBatchLocked across shards: candidates that fit are grouped by
+// shard and heapified per shard, the rest replace the global minimum best first. evictMu and every
+// shard lock must be held.
+func (s *shardedMempool) insertBatchLocked(candidates []*Tx) (evicted []*Tx) {
+	if len(candidates) == 0 {
+		return nil
+	}
+	space := int(s.MaxMemPoolSize()) - int(atomic.LoadInt64(&s.count))
+	if space < len(candidates) {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].outranks(candidates[j]) })
+	}
+	n := min(max(space, 0), len(candidates))
+	perShard := make([][]*Tx, len(s.shards))
+	for _, tx := range candidates[:n] {
+		index := s.shardFor(tx.TxHash)
+		perShard[index] = append(perShard[index], tx)
+	}
+	for i, txs := range perShard {
+		shard := s.shards[i]
+		if len(txs) > len(shard.txHeap) {
+			shard.txHeap = append(shard.txHeap, txs...)
+			heap.Init(&shard.txHeap)
+		} else {
+			for _, tx := range txs {
+				heap.Push(&shard.txHeap, tx)
+			}
+		}
+		for _, tx := range txs {
+			shard.txMap[tx.TxHash] = tx
+			shard.reserved.Store(tx.TxHash, txPooled)
+			s.events.added(tx)
+		}
+	}
+	atomic.AddInt64(&s.count, int64(n))
+	if s.wal != nil {
+		for _, tx := range candidates[:n] {
+			if err := s.wal.logAdd(tx); err != nil {
+				s.logger.Named("mempool/insertBatchLocked").Error("failed to log admission to WAL", zap.String("txHash", tx.TxHash), zap.Error(err))
+			}
+		}
+	}
+
+	admitted := n
+	for _, tx := range candidates[n:] {
+		minIndex := s.minShardLocked()
+		if tx.TotalFee <= s.shards[minIndex].txHeap[0].TotalFee {
+			break // Candidates are sorted, so no later one can beat the minimum either
+		}
+		minTx := s.popMinLocked(s.shards[minIndex], true)
+		evicted = append(evicted, minTx)
+		s.events.evicted(minTx, tx)
+		s.pushLocked(s.shards[s.shardFor(tx.TxHash)], tx, true)
+		admitted++
+	}
+	for _, tx := range candidates[admitted:] {
+		s.shards[s.shardFor(tx.TxHash)].reserved.Delete(tx.TxHash)
+	}
+	return evicted
+}
+
+// CloseTxInsertChan closes the transaction insertion channel and stops the listeners, like
+// mempool.CloseTxInsertChan.
+func (s *shardedMempool) CloseTxInsertChan() {
+	close(s.txChan)
+	s.events.close()
+}
+
+// TxStatus reports the lifecycle state of txHash, like mempool.TxStatus.
+func (s *shardedMempool) TxStatus(txHash string) (TxStatus, bool) {
+	if status, ok := liveStatus(&s.shards[s.shardFor(txHash)].reserved, txHash); ok {
+		return status, true
+	}
+	return s.events.status.lookup(txHash)
+}
+
+// EstimateFee returns a total fee estimate across all shards, like mempool.EstimateFee.
+func (s *shardedMempool) EstimateFee(targetPercentile float64) (float64, error) {
+	return estimateFee(s.feeSnapshot(), targetPercentile)
+}
+
+// SuggestFeeForInclusion suggests a fee per gas across all shards, like mempool.SuggestFeeForInclusion.
+func (s *shardedMempool) SuggestFeeForInclusion(gas, blockGasLimit float64, blocks int) (float64, error) {
+	return suggestFeeForInclusion(s.feeSnapshot(), gas, blockGasLimit, blocks)
+}
+
+func (s *shardedMempool) feeSnapshot() feeSnapshot {
+	s.lockAll()
+	txs := s.txsLocked()
+	s.unlockAll()
+	return feeSnapshot{
+		txs:          txs,
+		full:         uint32(len(txs)) >= s.MaxMemPoolSize(),
+		evictedFloor: s.events.history.floor(time.Now()),
+		minFeePerGas: s.MinFeePerGas(),
+	}
+}
+
+// Stats summarises the transactions of every shard, like mempool.Stats.
+func (s *shardedMempool) Stats(percentiles ...float64) (PoolStats, error) {
+	return s.events.stats.snapshot(percentiles, func(fn func(txs []*Tx)) {
+		s.lockAll()
+		defer s.unlockAll()
+		fn(s.txsLocked())
+	})
+}
+
+// GetTx retrieves a transaction from its shard.
+func (s *shardedMempool) GetTx(txHash string) (*Tx, bool) {
+	return s.shards[s.shardFor(txHash)].GetTx(txHash)
+}
+
+// MempoolLen returns the number of transactions across all shards.
+func (s *shardedMempool) MempoolLen() uint32 {
+	return uint32(atomic.LoadInt64(&s.count))
+}
+
+// ExportTo streams pooled transactions to w in priority order, merging the shards pass by pass.
+// Each pass locks one shard at a time, so the same consistency notes as mempool.ExportTo apply.
+func (s *shardedMempool) ExportTo(w io.Writer, opts ExportOptions) (int, error) {
+	return exportTo(w, opts, s.nextExportBatch)
+}
+
+// ExportToFile exports the merged contents of all shards like mempool.ExportToFile.
+func (s *shardedMempool) ExportToFile() (ExportResult, error) {
+	return ExportToPath(s, s.exportPath, s.exportFormat, s.logger)
+}
+
+// ReapMaxTxs returns up to max of the highest priority transactions across all shards, best first.
+func (s *shardedMempool) ReapMaxTxs(max int) []*Tx {
+	return reapMaxTxs(max, s.MempoolLen(), s.nextExportBatch)
+}
+
+// nextExportBatch merges the top of every shard into batch; see mempool.nextExportBatch.
+func (s *shardedMempool) nextExportBatch(batch rankHeap, cursor *Tx) rankHeap {
+	for _, shard := range s.shards {
+		batch = shard.nextExportBatch(batch, cursor)
+	}
+	return batch
+}
+
+// SaveSnapshot writes a consistent snapshot of every shard to path, like mempool.SaveSnapshot.
+func (s *shardedMempool) SaveSnapshot(path string) error {
+	s.lockAll()
+	txs := s.txsLocked()
+	seq := atomic.LoadUint64(&s.seq)
+	s.unlockAll()
+	return saveSnapshot(path, s.MaxMemPoolSize(), seq, txs, s.logger)
+}
+
+// LoadSnapshot admits the transactions of a snapshot, like mempool.LoadSnapshot.
+func (s *shardedMempool) LoadSnapshot(path string) error {
+	meta, txs, err := readSnapshotFile(path, s.MaxMemPoolSize(), s.logger)
+	if err != nil {
+		return err
+	}
+	var loaded int
+	for _, tx := range txs {
+		if _, reserved := s.shards[s.shardFor(tx.TxHash)].claim(tx.TxHash, txQueued); reserved {
+			continue
+		}
+		evicted, inserted := s.insert(tx, true)
+		if inserted {
+			loaded++
+		}
+		if evicted != nil {
+			s.drop(evicted, DropEvicted)
+		}
+	}
+	s.commitWAL()
+	advanceSequence(&s.seq, meta.NextSequence)
+	s.logger.Named("mempool/LoadSnapshot").Info("loaded snapshot", zap.String("path", path), zap.Uint64("count", meta.TxCount), zap.Int("loaded", loaded))
+	return nil
+}
@@ -0,0 +1,265 @@
+package types_test
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+func TestNewShardedMempool(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name        string
+		maxPoolSize uint32
+		numShards   int
+		wantErr     error
+	}{
+		{name: "success", maxPoolSize: 100, numShards: 8},
+		{name: "success_more_shards_than_capacity", maxPoolSize: 2, numShards: 16},
+		{name: "failure_pool_size", maxPoolSize: 0, numShards: 8, wantErr: types.ErrMempoolSize},
+		{name: "failure_shard_count", maxPoolSize: 100, numShards: 0, wantErr: types.ErrShardCount},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			memPool, err := types.NewShardedMempool(tc.maxPoolSize, tc.numShards, logger)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.maxPoolSize, memPool.MaxMemPoolSize())
+			assert.Equal(t, uint32(0), memPool.MempoolLen())
+		})
+	}
+}
+
+// TestShardedMempool_MatchesMempool feeds the same transactions through a single-lock pool and
+// sharded pools with one processor each, which must end up holding exactly the same transactions.
+func TestShardedMempool_MatchesMempool(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	const numTxs, maxPoolSize = 500, 100
+	fees := rand.New(rand.NewSource(1)).Perm(numTxs) // Distinct fees make the expected pool unambiguous
+
+	run := func(memPool types.Mempool) []string {
+		wg := &sync.WaitGroup{}
+		memPool.StartProcessors(wg, 1)
+		for i, fee := range fees {
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 1, float64(fee+1)), wg))
+		}
+		wg.Wait()
+		memPool.CloseTxInsertChan()
+		var hashes []string
+		for _, tx := range memPool.ReapMaxTxs(0) {
+			hashes = append(hashes, tx.TxHash)
+		}
+		return hashes
+	}
+
+	single, err := types.NewMempool(maxPoolSize, logger)
+	require.NoError(t, err)
+	want := run(single)
+	require.Len(t, want, maxPoolSize)
+	for _, numShards := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("Shards-%d", numShards), func(t *testing.T) {
+			sharded, err := types.NewShardedMempool(maxPoolSize, numShards, logger)
+			require.NoError(t, err)
+			assert.Equal(t, want, run(sharded))
+		})
+	}
+}
+
+func TestShardedMempool_ExportToFile(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	fileName := filepath.Join(t.TempDir(), "prioritized.jsonl")
+	memPool, err := types.NewShardedMempool(4, 2, logger, types.WithExport(fileName, types.FormatJSONL))
+	require.NoError(t, err)
+	memPool.AddTxs([]*types.Tx{types.NewTx(logger, "low", "sig", 10, 1), types.NewTx(logger, "high", "sig", 10, 3)})
+
+	result, err := memPool.ExportToFile()
+	require.NoError(t, err)
+	assert.Equal(t, fileName, result.Path)
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), `{"txHash":"high"`), "export should use the configured format")
+}
+
+func TestShardedMempool_Concurrent(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	const numTxs, maxPoolSize = 5000, 1000
+	var dropped int64
+	memPool, err := types.NewShardedMempool(maxPoolSize, 16, logger, types.WithDropHandler(func(tx *types.Tx, reason types.DropReason) {
+		atomic.AddInt64(&dropped, 1)
+	}))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 64)
+
+	fees := rand.New(rand.NewSource(2)).Perm(numTxs)
+	var submitters sync.WaitGroup
+	for s := 0; s < 4; s++ {
+		submitters.Add(1)
+		go func(s int) {
+			defer submitters.Done()
+			for i := s; i < numTxs; i += 4 {
+				assert.NoError(t, memPool.AddTx(types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 1, float64(fees[i]+1)), wg))
+			}
+		}(s)
+	}
+	submitters.Wait()
+	wg.Wait()
+	memPool.CloseTxInsertChan()
+
+	// Once full, a pool only ever raises its minimum, so it must end up with exactly the top fees.
+	assert.Equal(t, uint32(maxPoolSize), memPool.MempoolLen())
+	assert.Equal(t, int64(numTxs-maxPoolSize), atomic.LoadInt64(&dropped))
+	reaped := memPool.ReapMaxTxs(0)
+	require.Len(t, reaped, maxPoolSize)
+	for i, tx := range reaped {
+		assert.Equal(t, float64(numTxs-i), tx.TotalFee, "position %d", i)
+	}
+}
+
+func TestShardedMempool_AddTxs(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	var evicted []string
+	memPool, err := types.NewShardedMempool(3, 4, logger, types.WithDropHandler(func(tx *types.Tx, reason types.DropReason) {
+		evicted = append(evicted, tx.TxHash)
+	}))
+	require.NoError(t, err)
+	for _, err := range memPool.AddTxs([]*types.Tx{
+		types.NewTx(logger, "p1", "sig", 10, 1),
+		types.NewTx(logger, "p2", "sig", 10, 2),
+		types.NewTx(logger, "p3", "sig", 10, 3),
+	}) {
+		require.NoError(t, err)
+	}
+
+	errs := memPool.AddTxs([]*types.Tx{
+		types.NewTx(logger, "a", "sig", 10, 5),
+		types.NewTx(logger, "b", "sig", 10, 0.5),
+		types.NewTx(logger, "p3", "sig", 10, 3),
+		types.NewTx(logger, "c", "sig", 10, 4),
+		types.NewTx(logger, "a", "sig", 10, 5),
+	})
+	require.Len(t, errs, 5)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], types.ErrFeeTooLow)
+	assert.ErrorIs(t, errs[2], types.ErrDuplicateTx)
+	assert.NoError(t, errs[3])
+	assert.ErrorIs(t, errs[4], types.ErrDuplicateTx)
+	assert.ElementsMatch(t, []string{"p1", "p2"}, evicted)
+
+	var hashes []string
+	for _, tx := range memPool.ReapMaxTxs(2) {
+		hashes = append(hashes, tx.TxHash)
+	}
+	assert.Equal(t, []string{"a", "c"}, hashes)
+	assert.Equal(t, uint32(3), memPool.MempoolLen())
+}
+
+func TestShardedMempool_SnapshotAndWAL(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	dir := t.TempDir()
+
+	wal, err := types.OpenWAL(dir, types.WALOptions{CompactEvery: 2}) // Compacts while processors run
+	require.NoError(t, err)
+	memPool, err := types.NewShardedMempool(3, 4, logger, types.WithWAL(wal))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 4)
+	for i := 1; i <= 6; i++ {
+		require.NoError(t, memPool.AddTx(types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 10, float64(i)), wg))
+	}
+	wg.Wait()
+	memPool.CloseTxInsertChan()
+	path := filepath.Join(t.TempDir(), "pool.snapshot")
+	require.NoError(t, memPool.SaveSnapshot(path))
+	require.NoError(t, wal.Close())
+
+	wantHashes := []string{"tx-4", "tx-5", "tx-6"}
+	poolHashes := func(memPool types.Mempool) []string {
+		var hashes []string
+		for _, tx := range memPool.ReapMaxTxs(0) {
+			hashes = append(hashes, tx.TxHash)
+		}
+		sort.Strings(hashes)
+		return hashes
+	}
+
+	wal, err = types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	defer wal.Close()
+	restored, err := types.NewShardedMempool(3, 2, logger, types.WithWAL(wal))
+	require.NoError(t, err)
+	assert.Equal(t, wantHashes, poolHashes(restored), "restart from the WAL")
+
+	single, err := types.NewMempool(3, logger)
+	require.NoError(t, err)
+	require.NoError(t, single.LoadSnapshot(path))
+	assert.Equal(t, wantHashes, poolHashes(single), "sharded snapshot loaded into a single-lock pool")
+
+	resharded, err := types.NewShardedMempool(2, 8, logger)
+	require.NoError(t, err)
+	require.NoError(t, resharded.LoadSnapshot(path))
+	assert.Equal(t, wantHashes[1:], poolHashes(resharded), "snapshot loaded into a smaller sharded pool")
+}
+
+// BenchmarkMempool_Contention admits the same transactions through the single-lock and sharded pools
+// with a growing number of processors, so the effect of lock contention can be compared directly.
+func BenchmarkMempool_Contention(b *testing.B) {
+	logger, err := logging.Logger()
+	require.NoError(b, err, "Failed to initialize logger for benchmark")
+	const numTxs = 20000
+	pools := []struct {
+		name string
+		new  func() (types.Mempool, error)
+	}{
+		{name: "Single", new: func() (types.Mempool, error) { return types.NewMempool(numTxs/2, logger) }},
+		{name: "Sharded-16", new: func() (types.Mempool, error) { return types.NewShardedMempool(numTxs/2, 16, logger) }},
+		{name: "Sharded-64", new: func() (types.Mempool, error) { return types.NewShardedMempool(numTxs/2, 64, logger) }},
+	}
+
+	for _, pool := range pools {
+		for _, processors := range []uint8{1, 4, 16, 64} {
+			b.Run(fmt.Sprintf("%s/Processors-%d", pool.name, processors), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer() // Stop timer for setup
+					memPool, err := pool.new()
+					require.NoError(b, err)
+					txs := make([]*types.Tx, numTxs)
+					for j := range txs {
+						txs[j] = generateUniqueTx(logger, j)
+					}
+					wg := &sync.WaitGroup{}
+					memPool.StartProcessors(wg, processors)
+					b.StartTimer() // Restart timer for the actual operation
+
+					for _, tx := range txs {
+						memPool.AddTx(tx, wg)
+					}
+					wg.Wait()
+
+					b.StopTimer() // Stop timer after operation
+					memPool.CloseTxInsertChan()
+				}
+			})
+		}
+	}
+}
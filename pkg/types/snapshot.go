@@ -0,0 +1,235 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"mempool/pkg/logging"
+)
+
+// Snapshot layout (all integers little endian):
+//
+//	magic "MPSN" | uint16 version | int64 created (unix nanoseconds) | uint32 maxMemPoolSize
+//	uint64 next sequence | uint64 transaction count | transactions (see appendTxBinary) | uint32 CRC32
+//
+// The trailing checksum covers every byte before it.
+const (
+	snapshotVersion uint16 = 1
+)
+
+var (
+	snapshotMagic = []byte("MPSN")
+
+	ErrSnapshotFormat   = errors.New("file is not a mempool snapshot")
+	ErrSnapshotVersion  = errors.New("unsupported mempool snapshot version")
+	ErrSnapshotChecksum = errors.New("mempool snapshot checksum mismatch")
+)
+
+// SnapshotMeta describes the pool a snapshot was taken from.
+type SnapshotMeta struct {
+	Version        uint16
+	Created        time.Time
+	MaxMemPoolSize uint32
+	NextSequence   uint64
+	TxCount        uint64
+}
+
+// SaveSnapshot writes every pooled transaction and its metadata to path. The snapshot is written to
+// a temporary file in the same directory and renamed into place, so readers never observe a partial file.
+func (mp *mempool) SaveSnapshot(path string) error {
+	mp.mu.Lock()
+	txs := make([]*Tx, len(mp.txHeap))
+	copy(txs, mp.txHeap)
+	seq := atomic.LoadUint64(&mp.seq)
+	mp.mu.Unlock()
+	return saveSnapshot(path, mp.MaxMemPoolSize(), seq, txs, mp.logger)
+}
+
+func saveSnapshot(path string, maxMemPoolSize uint32, seq uint64, txs []*Tx, logger logging.LoggingSystem) error {
+	meta := SnapshotMeta{
+		Version:        snapshotVersion,
+		Created:        time.Now(),
+		MaxMemPoolSize: maxMemPoolSize,
+		NextSequence:   seq,
+		TxCount:        uint64(len(txs)),
+	}
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create snapshot temp file in %s", dir)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err = writeSnapshot(tmp, meta, txs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync snapshot")
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close snapshot")
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrapf(err, "failed to move snapshot into place at %s", path)
+	}
+	syncDir(dir)
+	logger.Named("mempool/SaveSnapshot").Info("saved snapshot", zap.String("path", path), zap.Uint64("count", meta.TxCount))
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot and admits its transactions into the pool.
+// Transactions are re-validated, duplicates of pooled transactions are skipped and the pool's own
+// capacity applies, so a snapshot from a larger pool keeps only its highest-fee transactions.
+func (mp *mempool) LoadSnapshot(path string) error {
+	meta, txs, err := readSnapshotFile(path, mp.MaxMemPoolSize(), mp.logger)
+	if err != nil {
+		return err
+	}
+
+	var loaded int
+	var dropped []*Tx
+	mp.mu.Lock()
+	for _, tx := range txs {
+		if _, reserved := mp.claim(tx.TxHash, txQueued); reserved {
+			continue
+		}
+		inserted, evicted := mp.insertLocked(tx, true)
+		if inserted {
+			loaded++
+		}
+		if evicted != nil {
+			dropped = append(dropped, evicted)
+		}
+	}
+	mp.mu.Unlock()
+	mp.commitWAL()
+	for _, tx := range dropped {
+		mp.drop(tx, DropEvicted)
+	}
+
+	advanceSequence(&mp.seq, meta.NextSequence)
+	mp.logger.Named("mempool/LoadSnapshot").Info("loaded snapshot", zap.String("path", path), zap.Uint64("count", meta.TxCount), zap.Int("loaded", loaded))
+	return nil
+}
+
+// readSnapshotFile reads the snapshot at path and returns its valid transactions with fees computed.
+func readSnapshotFile(path string, maxMemPoolSize uint32, logger logging.LoggingSystem) (SnapshotMeta, []*Tx, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return SnapshotMeta{}, nil, errors.Wrapf(err, "failed to open snapshot %s", path)
+	}
+	defer file.Close()
+	meta, txs, err := ReadSnapshot(bufio.NewReader(file))
+	if err != nil {
+		return meta, nil, errors.Wrapf(err, "failed to read snapshot %s", path)
+	}
+	if meta.MaxMemPoolSize != maxMemPoolSize {
+		logger.Named("mempool/LoadSnapshot").Warn("snapshot was taken from a pool with a different capacity",
+			zap.Uint32("snapshotMaxMemPoolSize", meta.MaxMemPoolSize), zap.Uint32("maxMemPoolSize", maxMemPoolSize))
+	}
+	valid := txs[:0]
+	for _, tx := range txs {
+		if err := tx.Validate(); err != nil {
+			logger.Named("mempool/LoadSnapshot").Warn("discarding invalid transaction from snapshot", zap.String("txHash", tx.TxHash), zap.Error(err))
+			continue
+		}
+		tx.calculateTotalFees()
+		valid = append(valid, tx)
+	}
+	return meta, valid, nil
+}
+
+// advanceSequence raises *seq to next unless it is already higher. Keeping sequence numbers monotonic
+// across hosts makes new admissions sort after restored ones.
+func advanceSequence(seq *uint64, next uint64) {
+	for {
+		current := atomic.LoadUint64(seq)
+		if current >= next || atomic.CompareAndSwapUint64(seq, current, next) {
+			return
+		}
+	}
+}
+
+// ReadSnapshot decodes a snapshot and verifies its version and checksum.
+func ReadSnapshot(r io.Reader) (SnapshotMeta, []*Tx, error) {
+	var meta SnapshotMeta
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return meta, nil, errors.Wrap(err, "failed to read snapshot")
+	}
+	const headerSize = 4 + 2 + 8 + 4 + 8 + 8
+	if len(data) < headerSize+4 || !bytes.Equal(data[:4], snapshotMagic) {
+		return meta, nil, ErrSnapshotFormat
+	}
+	body, trailer := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(trailer) {
+		return meta, nil, ErrSnapshotChecksum
+	}
+
+	meta.Version = binary.LittleEndian.Uint16(body[4:6])
+	if meta.Version != snapshotVersion {
+		return meta, nil, errors.Wrapf(ErrSnapshotVersion, "version %d", meta.Version)
+	}
+	meta.Created = time.Unix(0, int64(binary.LittleEndian.Uint64(body[6:14])))
+	meta.MaxMemPoolSize = binary.LittleEndian.Uint32(body[14:18])
+	meta.NextSequence = binary.LittleEndian.Uint64(body[18:26])
+	meta.TxCount = binary.LittleEndian.Uint64(body[26:34])
+
+	txs := make([]*Tx, 0, min(meta.TxCount, uint64(len(body))))
+	offset := headerSize
+	for i := uint64(0); i < meta.TxCount; i++ {
+		tx, n, err := decodeTxBinary(body[offset:])
+		if err != nil {
+			return meta, nil, errors.Wrapf(err, "transaction %d", i)
+		}
+		txs = append(txs, tx)
+		offset += n
+	}
+	if offset != len(body) {
+		return meta, nil, errors.Wrap(ErrSnapshotFormat, "trailing bytes after last transaction")
+	}
+	return meta, txs, nil
+}
+
+func writeSnapshot(w io.Writer, meta SnapshotMeta, txs []*Tx) error {
+	checksum := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, checksum))
+
+	header := make([]byte, 0, 34)
+	header = append(header, snapshotMagic...)
+	header = binary.LittleEndian.AppendUint16(header, meta.Version)
+	header = binary.LittleEndian.AppendUint64(header, uint64(meta.Created.UnixNano()))
+	header = binary.LittleEndian.AppendUint32(header, meta.MaxMemPoolSize)
+	header = binary.LittleEndian.AppendUint64(header, meta.NextSequence)
+	header = binary.LittleEndian.AppendUint64(header, meta.TxCount)
+	if _, err := bw.Write(header); err != nil {
+		return errors.Wrap(err, "failed to write snapshot header")
+	}
+
+	var buf []byte
+	for _, tx := range txs {
+		buf = appendTxBinary(buf[:0], tx)
+		if _, err := bw.Write(buf); err != nil {
+			return errors.Wrap(err, "failed to write snapshot transaction")
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush snapshot")
+	}
+	if _, err := w.Write(binary.LittleEndian.AppendUint32(nil, checksum.Sum32())); err != nil {
+		return errors.Wrap(err, "failed to write snapshot checksum")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package types_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+func TestMempool_SaveLoadSnapshot(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	path := filepath.Join(t.TempDir(), "pool.snapshot")
+
+	source, err := types.NewMempool(3, logger)
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	source.StartProcessors(wg, 1)
+	for _, tx := range []*types.Tx{
+		types.NewTx(logger, "a", "sigA", 10, 1),
+		types.NewTx(logger, "b", "sigB", 10, 2),
+		types.NewTx(logger, "c", "sigC", 10, 3),
+	} {
+		require.NoError(t, source.AddTx(tx, wg))
+	}
+	wg.Wait()
+	source.CloseTxInsertChan()
+	require.NoError(t, source.SaveSnapshot(path))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	meta, txs, err := types.ReadSnapshot(file)
+	file.Close()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), meta.MaxMemPoolSize)
+	assert.Equal(t, uint64(3), meta.TxCount)
+	assert.Equal(t, uint64(3), meta.NextSequence)
+	require.Len(t, txs, 3)
+	for _, tx := range txs {
+		original, ok := source.GetTx(tx.TxHash)
+		require.True(t, ok)
+		assert.Equal(t, original.Sequence, tx.Sequence)
+		assert.True(t, original.ArrivalTime.Equal(tx.ArrivalTime), "arrival time should round-trip")
+	}
+
+	// A smaller destination pool keeps only the highest-fee transactions.
+	destination, err := types.NewMempool(2, logger)
+	require.NoError(t, err)
+	require.NoError(t, destination.LoadSnapshot(path))
+	assert.Equal(t, uint32(2), destination.MempoolLen())
+	_, ok := destination.GetTx("a")
+	assert.False(t, ok, "lowest fee transaction should not fit")
+	restored, ok := destination.GetTx("c")
+	require.True(t, ok)
+	assert.Equal(t, 30.0, restored.TotalFee)
+
+	// New admissions continue the sequence of the source pool.
+	wg = &sync.WaitGroup{}
+	destination.StartProcessors(wg, 1)
+	next := types.NewTx(logger, "d", "sigD", 10, 4)
+	require.NoError(t, destination.AddTx(next, wg))
+	wg.Wait()
+	destination.CloseTxInsertChan()
+	assert.Equal(t, uint64(4), next.Sequence)
+}
+
+func TestReadSnapshot_Errors(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	path := filepath.Join(t.TempDir(), "pool.snapshot")
+	memPool, err := types.NewMempool(1, logger)
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 1)
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "a", "sigA", 10, 1), wg))
+	wg.Wait()
+	memPool.CloseTxInsertChan()
+	require.NoError(t, memPool.SaveSnapshot(path))
+	valid, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		mutate func([]byte) []byte
+		want   error
+	}{
+		{name: "not_a_snapshot", mutate: func(b []byte) []byte { return []byte("TxHash=abc") }, want: types.ErrSnapshotFormat},
+		{name: "checksum", mutate: func(b []byte) []byte { b[10] ^= 0xFF; return b }, want: types.ErrSnapshotChecksum},
+		{name: "truncated", mutate: func(b []byte) []byte { return b[:len(b)-1] }, want: types.ErrSnapshotChecksum},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := tc.mutate(append([]byte(nil), valid...))
+			_, _, err := types.ReadSnapshot(bytes.NewReader(data))
+			require.ErrorIs(t, err, tc.want)
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package types
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// statsBucketGrowth is the ratio between the bounds of consecutive histogram buckets, which bounds
+// the error of reported percentiles and medians to about 1%.
+const statsBucketGrowth = 1.01
+
+// DefaultStatsPercentiles are reported by Stats when no percentiles are given.
+var DefaultStatsPercentiles = []float64{10, 25, 50, 75, 90, 99}
+
+var ErrInvalidPercentile = errors.New("percentile must be between 0 and 100")
+
+// PoolStats summarises the transactions in a pool.
+type PoolStats struct {
+	Count     int             `json:"count"`
+	TotalGas  float64         `json:"totalGas"`
+	TotalFee  FeeDistribution `json:"totalFee"`
+	FeePerGas FeeDistribution `json:"feePerGas"`
+	Senders   map[string]int  `json:"senders,omitempty"` // Pooled transactions per sender, for transactions that name one
+}
+
+// FeeDistribution describes the spread of one fee measure across the pool. Min and Max are exact.
+// Mean and the totals of PoolStats are running sums, so they may differ from a fresh sum by
+// floating-point rounding. Median and Percentiles are read from a histogram and are exact when the
+// values in a bucket are equal, and otherwise within about 1%.
+type FeeDistribution struct {
+	Min         float64           `json:"min"`
+	Max         float64           `json:"max"`
+	Mean        float64           `json:"mean"`
+	Median      float64           `json:"median"`
+	Percentiles []PercentileValue `json:"percentiles"`
+}
+
+// PercentileValue is the nearest-rank value at Percentile.
+type PercentileValue struct {
+	Percentile float64 `json:"percentile"`
+	Value      float64 `json:"value"`
+}
+
+// poolStats keeps running totals of the pooled transactions. It is updated by the pool's observers
+// on every admission, eviction and removal, so reading it usually costs a pass over the histogram
+// buckets rather than over the pool. A nil *poolStats records nothing.
+type poolStats struct {
+	mu        sync.Mutex
+	count     int
+	gas       float64
+	totalFee  feeHistogram
+	feePerGas feeHistogram
+	senders   map[string]int
+}
+
+func newPoolStats() *poolStats {
+	return &poolStats{totalFee: newFeeHistogram(), feePerGas: newFeeHistogram(), senders: make(map[string]int)}
+}
+
+// added records that tx entered the pool.
+func (ps *poolStats) added(tx *Tx) {
+	ps.record(tx, 1)
+}
+
+// removed records that tx left the pool, whether evicted or included.
+func (ps *poolStats) removed(tx *Tx) {
+	ps.record(tx, -1)
+}
+
+func (ps *poolStats) record(tx *Tx, delta int) {
+	if ps == nil {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.count += delta
+	ps.gas += float64(delta) * tx.Gas
+	ps.totalFee.record(tx.TotalFee, delta)
+	ps.feePerGas.record(tx.FeePerGas, delta)
+	if tx.Sender != "" {
+		if ps.senders[tx.Sender] += delta; ps.senders[tx.Sender] == 0 {
+			delete(ps.senders, tx.Sender)
+		}
+	}
+}
+
+// snapshot returns the current statistics with the given percentiles. When the last copy of the
+// smallest or largest fee has left the pool, the extremes are recomputed from the pooled
+// transactions, which withPool passes to its argument while holding the pool's lock.
+func (ps *poolStats) snapshot(percentiles []float64, withPool func(func(txs []*Tx))) (stats PoolStats, err error) {
+	if len(percentiles) == 0 {
+		percentiles = DefaultStatsPercentiles
+	}
+	for _, p := range percentiles {
+		if p < 0 || p > 100 || math.IsNaN(p) {
+			return PoolStats{}, errors.Wrapf(ErrInvalidPercentile, "%v", p)
+		}
+	}
+	ps.mu.Lock()
+	if !ps.totalFee.extremesStale() && !ps.feePerGas.extremesStale() {
+		defer ps.mu.Unlock()
+		return ps.statsLocked(percentiles), nil
+	}
+	ps.mu.Unlock()
+	// The pool lock is taken first, as by the observers that call record.
+	withPool(func(txs []*Tx) {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		ps.totalFee.rescan(txs, func(tx *Tx) float64 { return tx.TotalFee })
+		ps.feePerGas.rescan(txs, func(tx *Tx) float64 { return tx.FeePerGas })
+		stats = ps.statsLocked(percentiles)
+	})
+	return stats, nil
+}
+
+func (ps *poolStats) statsLocked(percentiles []float64) PoolStats {
+	stats := PoolStats{
+		Count:     ps.count,
+		TotalGas:  ps.gas,
+		TotalFee:  ps.totalFee.distribution(ps.count, percentiles),
+		FeePerGas: ps.feePerGas.distribution(ps.count, percentiles),
+	}
+	if len(ps.senders) > 0 {
+		stats.Senders = make(map[string]int, len(ps.senders))
+		for sender, n := range ps.senders {
+			stats.Senders[sender] = n
+		}
+	}
+	return stats
+}
+
+// feeHistogram counts values in logarithmic buckets. Each bucket also keeps the sum of its values
+// and its smallest and largest values with how many copies of each it holds, so a bucket holding
+// equal values reports them exactly. Once the last copy of a bucket's extreme is removed the
+// extreme is stale until rescan recomputes it.
+type feeHistogram struct {
+	buckets map[int]*histogramBucket
+	sum     float64
+}
+
+type histogramBucket struct {
+	count    int
+	sum      float64
+	min, max float64
+	minCount int // Copies of min in the bucket; 0 when min is stale
+	maxCount int // Copies of max in the bucket; 0 when max is stale
+}
+
+func newFeeHistogram() feeHistogram {
+	return feeHistogram{buckets: make(map[int]*histogramBucket)}
+}
+
+func (h *feeHistogram) record(value float64, delta int) {
+	index := bucketIndex(value)
+	bucket, ok := h.buckets[index]
+	if !ok {
+		bucket = &histogramBucket{min: value, max: value}
+		h.buckets[index] = bucket
+	}
+	bucket.count += delta
+	bucket.sum += float64(delta) * value
+	h.sum += float64(delta) * value
+	if bucket.count == 0 {
+		delete(h.buckets, index) // Also discards rounding left in the bucket's sum
+		return
+	}
+	bucket.track(value, delta)
+}
+
+// track updates the bucket's extremes for delta copies of value. A stale min or max is still a
+// bound on the values held, so a value beyond it is the new extreme.
+func (b *histogramBucket) track(value float64, delta int) {
+	switch {
+	case value == b.min:
+		b.minCount = max(b.minCount+delta, 0)
+	case value < b.min && delta > 0:
+		b.min, b.minCount = value, delta
+	}
+	switch {
+	case value == b.max:
+		b.maxCount = max(b.maxCount+delta, 0)
+	case value > b.max && delta > 0:
+		b.max, b.maxCount = value, delta
+	}
+}
+
+// exact reports whether every value in the bucket is min.
+func (b *histogramBucket) exact() bool {
+	return b.minCount == b.count
+}
+
+// extremesStale reports whether the minimum or maximum of the values held by h is unknown.
+func (h *feeHistogram) extremesStale() bool {
+	lowest, highest := math.MaxInt, math.MinInt
+	for index := range h.buckets {
+		lowest, highest = min(lowest, index), max(highest, index)
+	}
+	return len(h.buckets) > 0 && (h.buckets[lowest].minCount == 0 || h.buckets[highest].maxCount == 0)
+}
+
+// rescan recomputes the extremes of every bucket from the values of txs, which must be the values
+// h counts.
+func (h *feeHistogram) rescan(txs []*Tx, valueOf func(tx *Tx) float64) {
+	for _, bucket := range h.buckets {
+		bucket.minCount, bucket.maxCount = 0, 0
+	}
+	for _, tx := range txs {
+		value := valueOf(tx)
+		bucket, ok := h.buckets[bucketIndex(value)]
+		if !ok {
+			continue
+		}
+		if bucket.minCount == 0 || value < bucket.min {
+			bucket.min, bucket.minCount = value, 0
+		}
+		if value == bucket.min {
+			bucket.minCount++
+		}
+		if bucket.maxCount == 0 || value > bucket.max {
+			bucket.max, bucket.maxCount = value, 0
+		}
+		if value == bucket.max {
+			bucket.maxCount++
+		}
+	}
+}
+
+// bucketIndex returns the bucket of value. Fees are validated to be positive; anything else shares
+// the lowest bucket.
+func bucketIndex(value float64) int {
+	if value <= 0 {
+		return math.MinInt32
+	}
+	return int(math.Floor(math.Log(value) / math.Log(statsBucketGrowth)))
+}
+
+// distribution summarises the count values held by h, whose extremes must not be stale.
+func (h *feeHistogram) distribution(count int, percentiles []float64) FeeDistribution {
+	dist := FeeDistribution{Percentiles: make([]PercentileValue, len(percentiles))}
+	for i, p := range percentiles {
+		dist.Percentiles[i].Percentile = p
+	}
+	if count == 0 {
+		return dist
+	}
+	indexes := make([]int, 0, len(h.buckets))
+	for index := range h.buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	cumulative := make([]int, len(indexes))
+	var seen int
+	for i, index := range indexes {
+		seen += h.buckets[index].count
+		cumulative[i] = seen
+	}
+	// Nearest rank, as in EstimateFee: the value at p is the ceil(p% of count)-th smallest.
+	valueAt := func(p float64) float64 {
+		rank := max(int(math.Ceil(p/100*float64(count))), 1)
+		bucket := h.buckets[indexes[sort.SearchInts(cumulative, rank)]]
+		if bucket.exact() {
+			return bucket.min
+		}
+		return bucket.sum / float64(bucket.count)
+	}
+	dist.Min = h.buckets[indexes[0]].min
+	dist.Max = h.buckets[indexes[len(indexes)-1]].max
+	dist.Mean = h.sum / float64(count)
+	dist.Median = valueAt(50)
+	for i, p := range percentiles {
+		dist.Percentiles[i].Value = valueAt(p)
+	}
+	return dist
+}
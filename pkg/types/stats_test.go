@@ -0,0 +1,207 @@
+package types_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+func TestMempool_Stats(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	// Ten transactions of gas 10 with fees per gas 1 to 10, alternately sent by alice and bob.
+	senderTxs := func() []*types.Tx {
+		txs := make([]*types.Tx, 10)
+		for i := range txs {
+			txs[i] = types.NewTx(logger, fmt.Sprintf("tx-%d", i+1), "sig", 10, float64(i+1))
+			txs[i].Sender = []string{"alice", "bob"}[i%2]
+		}
+		return txs
+	}
+	for _, pool := range []struct {
+		name string
+		new  func(maxSize uint32) (types.Mempool, error)
+	}{
+		{name: "single", new: func(maxSize uint32) (types.Mempool, error) { return types.NewMempool(maxSize, logger) }},
+		{name: "sharded", new: func(maxSize uint32) (types.Mempool, error) { return types.NewShardedMempool(maxSize, 4, logger) }},
+	} {
+		for _, tc := range []struct {
+			name        string
+			maxSize     uint32
+			change      func(t *testing.T, memPool types.Mempool)
+			percentiles []float64
+			want        types.PoolStats
+			wantErr     error
+		}{
+			{
+				name:    "empty",
+				maxSize: 10,
+				change:  func(t *testing.T, memPool types.Mempool) {},
+				want: types.PoolStats{
+					TotalFee:  types.FeeDistribution{Percentiles: percentiles(types.DefaultStatsPercentiles, nil)},
+					FeePerGas: types.FeeDistribution{Percentiles: percentiles(types.DefaultStatsPercentiles, nil)},
+				},
+			},
+			{
+				name:    "default_percentiles",
+				maxSize: 10,
+				change: func(t *testing.T, memPool types.Mempool) {
+					for _, err := range memPool.AddTxs(senderTxs()) {
+						require.NoError(t, err)
+					}
+				},
+				want: types.PoolStats{
+					Count:     10,
+					TotalGas:  100,
+					TotalFee:  types.FeeDistribution{Min: 10, Max: 100, Mean: 55, Median: 50, Percentiles: percentiles(types.DefaultStatsPercentiles, []float64{10, 30, 50, 80, 90, 100})},
+					FeePerGas: types.FeeDistribution{Min: 1, Max: 10, Mean: 5.5, Median: 5, Percentiles: percentiles(types.DefaultStatsPercentiles, []float64{1, 3, 5, 8, 9, 10})},
+					Senders:   map[string]int{"alice": 5, "bob": 5},
+				},
+			},
+			{
+				// tx-11 evicts tx-1 and a block includes tx-10, leaving fees per gas 2 to 9 and 11.
+				name:    "after_eviction_and_update",
+				maxSize: 10,
+				change: func(t *testing.T, memPool types.Mempool) {
+					txs := senderTxs()
+					for _, err := range memPool.AddTxs(txs) {
+						require.NoError(t, err)
+					}
+					carol := types.NewTx(logger, "tx-11", "sig", 10, 11)
+					carol.Sender = "carol"
+					require.NoError(t, memPool.AddTxs([]*types.Tx{carol})[0])
+					require.Equal(t, 1, memPool.Update([]*types.Tx{txs[9]}))
+				},
+				percentiles: []float64{0, 50, 100},
+				want: types.PoolStats{
+					Count:     9,
+					TotalGas:  90,
+					TotalFee:  types.FeeDistribution{Min: 20, Max: 110, Mean: 550.0 / 9, Median: 60, Percentiles: percentiles([]float64{0, 50, 100}, []float64{20, 60, 110})},
+					FeePerGas: types.FeeDistribution{Min: 2, Max: 11, Mean: 55.0 / 9, Median: 6, Percentiles: percentiles([]float64{0, 50, 100}, []float64{2, 6, 11})},
+					Senders:   map[string]int{"alice": 4, "bob": 4, "carol": 1},
+				},
+			},
+			{
+				name:        "invalid_percentile",
+				maxSize:     10,
+				change:      func(t *testing.T, memPool types.Mempool) {},
+				percentiles: []float64{50, 101},
+				wantErr:     types.ErrInvalidPercentile,
+			},
+		} {
+			t.Run(pool.name+"/"+tc.name, func(t *testing.T) {
+				memPool, err := pool.new(tc.maxSize)
+				require.NoError(t, err)
+				tc.change(t, memPool)
+				stats, err := memPool.Stats(tc.percentiles...)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tc.want.Count, stats.Count)
+				assert.InDelta(t, tc.want.TotalGas, stats.TotalGas, 1e-9)
+				assert.Equal(t, tc.want.Senders, stats.Senders)
+				assertDistribution(t, tc.want.TotalFee, stats.TotalFee)
+				assertDistribution(t, tc.want.FeePerGas, stats.FeePerGas)
+			})
+		}
+	}
+}
+
+// TestMempool_StatsApproximation checks that percentiles of fees sharing a histogram bucket are
+// reported within 1%, while the extremes stay exact as they are admitted and removed.
+func TestMempool_StatsApproximation(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, pool := range []struct {
+		name string
+		new  func() (types.Mempool, error)
+	}{
+		{name: "single", new: func() (types.Mempool, error) { return types.NewMempool(10, logger) }},
+		{name: "sharded", new: func() (types.Mempool, error) { return types.NewShardedMempool(10, 4, logger) }},
+	} {
+		t.Run(pool.name, func(t *testing.T) {
+			memPool, err := pool.new()
+			require.NoError(t, err)
+			var txs []*types.Tx
+			for i, feePerGas := range []float64{100, 100, 100.2, 100.4, 5000, 5000.5} {
+				txs = append(txs, types.NewTx(logger, fmt.Sprintf("tx-%d", i), "sig", 1, feePerGas))
+				require.NoError(t, memPool.AddTxs(txs[i:])[0])
+			}
+			stats, err := memPool.Stats(50)
+			require.NoError(t, err)
+			assert.Equal(t, 100.0, stats.FeePerGas.Min)
+			assert.InEpsilon(t, 100.2, stats.FeePerGas.Median, 0.01)
+			assert.Equal(t, 5000.5, stats.FeePerGas.Max)
+			assert.InDelta(t, 10401.1/6, stats.FeePerGas.Mean, 1e-9)
+
+			require.Equal(t, 2, memPool.Update([]*types.Tx{txs[0], txs[5]}))
+			stats, err = memPool.Stats(50)
+			require.NoError(t, err)
+			assert.Equal(t, 100.0, stats.FeePerGas.Min, "another transaction pays the smallest fee")
+			assert.Equal(t, 5000.0, stats.FeePerGas.Max, "the next largest fee in the bucket")
+
+			require.Equal(t, 1, memPool.Update([]*types.Tx{txs[1]}))
+			stats, err = memPool.Stats(50)
+			require.NoError(t, err)
+			assert.Equal(t, 100.2, stats.FeePerGas.Min, "the next smallest fee in the bucket")
+			assert.Equal(t, 100.2, stats.TotalFee.Min)
+			assert.Equal(t, 5000.0, stats.TotalFee.Max)
+		})
+	}
+}
+
+// TestMempool_StatsEqualFees checks that a bucket of equal fees reports them exactly however many
+// times the fee has been added and removed, since rounding accumulates in the bucket's running sum.
+func TestMempool_StatsEqualFees(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	memPool, err := types.NewMempool(1000, logger)
+	require.NoError(t, err)
+	for round := range 20 {
+		var txs []*types.Tx
+		for i := range 10 {
+			txs = append(txs, types.NewTx(logger, fmt.Sprintf("tx-%d-%d", round, i), "sig", 1, 0.3))
+		}
+		for _, err := range memPool.AddTxs(txs) {
+			require.NoError(t, err)
+		}
+		require.Equal(t, 9, memPool.Update(txs[1:]))
+	}
+	stats, err := memPool.Stats(10, 90)
+	require.NoError(t, err)
+	assert.Equal(t, 20, stats.Count)
+	assert.Equal(t, 0.3, stats.FeePerGas.Median)
+	assert.Equal(t, []types.PercentileValue{{Percentile: 10, Value: 0.3}, {Percentile: 90, Value: 0.3}}, stats.FeePerGas.Percentiles)
+}
+
+func percentiles(at, values []float64) []types.PercentileValue {
+	result := make([]types.PercentileValue, len(at))
+	for i, p := range at {
+		result[i].Percentile = p
+		if values != nil {
+			result[i].Value = values[i]
+		}
+	}
+	return result
+}
+
+func assertDistribution(t *testing.T, want, got types.FeeDistribution) {
+	t.Helper()
+	const delta = 1e-9
+	assert.InDelta(t, want.Min, got.Min, delta, "min")
+	assert.InDelta(t, want.Max, got.Max, delta, "max")
+	assert.InDelta(t, want.Mean, got.Mean, delta, "mean")
+	assert.InDelta(t, want.Median, got.Median, delta, "median")
+	require.Len(t, got.Percentiles, len(want.Percentiles))
+	for i := range want.Percentiles {
+		assert.Equal(t, want.Percentiles[i].Percentile, got.Percentiles[i].Percentile)
+		assert.InDelta(t, want.Percentiles[i].Value, got.Percentiles[i].Value, delta, "p%v", want.Percentiles[i].Percentile)
+	}
+}
@@ -0,0 +1,174 @@
+package types
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StatusCode is the lifecycle state of a transaction hash as reported by Mempool.TxStatus.
+type StatusCode string
+
+const (
+	StatusQueued   StatusCode = "queued"   // Accepted by AddTx and waiting for a processor
+	StatusPooled   StatusCode = "pooled"   // In the pool
+	StatusEvicted  StatusCode = "evicted"  // Replaced by a higher-fee transaction or removed when the pool shrank
+	StatusRejected StatusCode = "rejected" // Refused by AddTx, AddTxs or a processor; Reason says why
+	StatusIncluded StatusCode = "included" // Removed by Update because a block included it
+	StatusExpired  StatusCode = "expired"  // Discarded, but its status aged out of the index; Reason says what it was
+)
+
+// TxStatus describes what happened to a transaction hash.
+type TxStatus struct {
+	TxHash    string     `json:"txHash"`
+	Status    StatusCode `json:"status"`
+	Reason    string     `json:"reason,omitempty"`    // Why the transaction was rejected or evicted
+	EvictedBy string     `json:"evictedBy,omitempty"` // Hash of the transaction that took its place
+	Time      time.Time  `json:"time,omitzero"`       // When a terminal status was recorded, zero for queued and pooled
+}
+
+// statusIndex remembers the terminal status of recently discarded transactions. It holds at most
+// capacity entries, dropping the oldest first. An entry older than ttl is replaced by an expired
+// tombstone, which is kept for one more ttl so callers learn that the status aged out rather than
+// that the hash is unknown. Queued and pooled transactions are not recorded because the pool's hash
+// reservations already know them. A nil *statusIndex records nothing, so callers need not check
+// whether the index is enabled.
+type statusIndex struct {
+	mu       sync.Mutex
+	entries  map[string]*list.Element // Hash -> element of order holding a TxStatus
+	order    *list.List               // Oldest first
+	expired  map[string]*list.Element // Hash -> element of tombstones holding a StatusExpired TxStatus
+	tombs    *list.List               // Oldest first
+	capacity int                      // Of entries and of tombstones, each
+	ttl      time.Duration            // 0 keeps entries until capacity forces them out
+}
+
+func newStatusIndex(capacity int, ttl time.Duration) *statusIndex {
+	return &statusIndex{
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		expired:  make(map[string]*list.Element),
+		tombs:    list.New(),
+		capacity: capacity,
+		ttl:      ttl,
+	}
+}
+
+// evicted records that tx was evicted by evictedBy, which is nil when the pool shrank.
+func (idx *statusIndex) evicted(tx, evictedBy *Tx) {
+	status := TxStatus{TxHash: tx.TxHash, Status: StatusEvicted}
+	if evictedBy != nil {
+		status.EvictedBy = evictedBy.TxHash
+		status.Reason = "outranked by a higher-fee transaction"
+	} else {
+		status.Reason = "pool capacity reduced"
+	}
+	idx.record(status)
+}
+
+// rejected records that txHash was refused because of err.
+func (idx *statusIndex) rejected(txHash string, err error) {
+	idx.record(TxStatus{TxHash: txHash, Status: StatusRejected, Reason: err.Error()})
+}
+
+// included records that tx was removed because a block included it.
+func (idx *statusIndex) included(tx *Tx) {
+	idx.record(TxStatus{TxHash: tx.TxHash, Status: StatusIncluded})
+}
+
+func (idx *statusIndex) record(status TxStatus) {
+	if idx == nil {
+		return
+	}
+	status.Time = time.Now()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if element, ok := idx.entries[status.TxHash]; ok {
+		idx.order.Remove(element)
+	}
+	if tomb, ok := idx.expired[status.TxHash]; ok {
+		idx.removeTombLocked(tomb)
+	}
+	idx.entries[status.TxHash] = idx.order.PushBack(status)
+	for idx.order.Len() > idx.capacity {
+		idx.removeLocked(idx.order.Front())
+	}
+	idx.expireLocked(status.Time)
+}
+
+// setTTL changes the TTL, expiring entries that are now too old.
+func (idx *statusIndex) setTTL(ttl time.Duration) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.ttl = ttl
+	idx.expireLocked(time.Now())
+}
+
+// lookup returns the recorded status of txHash, or StatusExpired if it aged out within the last ttl.
+func (idx *statusIndex) lookup(txHash string) (TxStatus, bool) {
+	if idx == nil {
+		return TxStatus{}, false
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.expireLocked(time.Now())
+	if element, ok := idx.entries[txHash]; ok {
+		return element.Value.(TxStatus), true
+	}
+	if tomb, ok := idx.expired[txHash]; ok {
+		return tomb.Value.(TxStatus), true
+	}
+	return TxStatus{}, false
+}
+
+// expireLocked replaces entries recorded more than ttl before now with tombstones, and forgets
+// tombstones more than ttl old. Both lists are kept in time order, so only their fronts need
+// checking. idx.mu must be held.
+func (idx *statusIndex) expireLocked(now time.Time) {
+	if idx.ttl <= 0 {
+		return
+	}
+	for front := idx.order.Front(); front != nil && now.Sub(front.Value.(TxStatus).Time) > idx.ttl; front = idx.order.Front() {
+		status := front.Value.(TxStatus)
+		idx.removeLocked(front)
+		// Dated when it expired, so tombstones stay in time order whenever expireLocked runs.
+		idx.expired[status.TxHash] = idx.tombs.PushBack(TxStatus{
+			TxHash: status.TxHash,
+			Status: StatusExpired,
+			Reason: fmt.Sprintf("%s status older than %v is no longer kept", status.Status, idx.ttl),
+			Time:   status.Time.Add(idx.ttl),
+		})
+		if idx.tombs.Len() > idx.capacity {
+			idx.removeTombLocked(idx.tombs.Front())
+		}
+	}
+	for front := idx.tombs.Front(); front != nil && now.Sub(front.Value.(TxStatus).Time) > idx.ttl; front = idx.tombs.Front() {
+		idx.removeTombLocked(front)
+	}
+}
+
+func (idx *statusIndex) removeLocked(element *list.Element) {
+	delete(idx.entries, element.Value.(TxStatus).TxHash)
+	idx.order.Remove(element)
+}
+
+func (idx *statusIndex) removeTombLocked(element *list.Element) {
+	delete(idx.expired, element.Value.(TxStatus).TxHash)
+	idx.tombs.Remove(element)
+}
+
+// liveStatus returns the status of txHash from its reservation in reserved, if it is queued or pooled.
+func liveStatus(reserved *sync.Map, txHash string) (TxStatus, bool) {
+	state, ok := reserved.Load(txHash)
+	if !ok {
+		return TxStatus{}, false
+	}
+	if state.(txState) == txPooled {
+		return TxStatus{TxHash: txHash, Status: StatusPooled}, true
+	}
+	return TxStatus{TxHash: txHash, Status: StatusQueued}, true
+}
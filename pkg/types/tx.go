@@ -1,15 +1,24 @@
 package types
 
 import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
 	"mempool/pkg/logging"
 )
 
 type Tx struct {
-	TxHash    string
-	Gas       float64
-	FeePerGas float64
-	TotalFee  float64
-	Signature string
+	TxHash      string
+	Gas         float64
+	FeePerGas   float64
+	TotalFee    float64
+	Signature   string
+	ArrivalTime time.Time // Set when the transaction is first accepted by a mempool
+	Sequence    uint64    // Monotonic admission order assigned by the mempool
+	Sender      string    // Optional account that submitted the transaction, counted by Stats
+	SourceLine  int       // Input line the transaction was read from, reported if it is later dropped; 0 when unknown
 }
 
 type TxI interface {
@@ -20,6 +29,10 @@ const (
 	WARN_BAD_DATA = "encountered one or more missing parameters while creating transaction"
 )
 
+var (
+	ErrInvalidTx = errors.New("transaction is missing a hash or signature or has a non-positive gas or fee per gas")
+)
+
 func NewTx(logger logging.LoggingSystem, txHash, signature string, gas, feePerGas float64) *Tx {
 	if txHash == " " || signature == " " || gas == 0.0 || feePerGas == 0.0 {
 		logger.Warn(WARN_BAD_DATA)
@@ -32,6 +45,27 @@ func NewTx(logger logging.LoggingSystem, txHash, signature string, gas, feePerGa
 	}
 }
 
+// outranks reports whether tx has a strictly higher priority than other: a higher TotalFee wins,
+// then the earlier admission (lower Sequence), then the lexically smaller hash so the order is total.
+func (tx *Tx) outranks(other *Tx) bool {
+	if tx.TotalFee != other.TotalFee {
+		return tx.TotalFee > other.TotalFee
+	}
+	if tx.Sequence != other.Sequence {
+		return tx.Sequence < other.Sequence
+	}
+	return tx.TxHash < other.TxHash
+}
+
 func (tx *Tx) calculateTotalFees() {
 	tx.TotalFee = tx.FeePerGas * tx.Gas
 }
+
+// Validate reports whether the transaction carries the fields required to be admitted into the pool,
+// returning an error wrapping ErrInvalidTx if not. Admission and restore apply the same check.
+func (tx *Tx) Validate() error {
+	if strings.TrimSpace(tx.TxHash) == "" || strings.TrimSpace(tx.Signature) == "" || tx.Gas <= 0 || tx.FeePerGas <= 0 {
+		return errors.Wrapf(ErrInvalidTx, "txHash [%s]", tx.TxHash)
+	}
+	return nil
+}
@@ -0,0 +1,525 @@
+package types
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WAL record layout: [uint32 payload length][uint32 CRC32 of payload][payload]
+// The payload starts with a one byte op code followed by the op specific body. Add records carry a
+// version byte before the transaction so its encoding can change without breaking older logs.
+const (
+	walLogFileName        = "mempool.wal"
+	walCompactingFileName = "mempool.wal.compacting" // The log being folded into a snapshot in the background
+	walSnapshotFileName   = "mempool.snapshot"
+	walHeaderSize         = 8
+	walMaxRecordSize      = 1 << 20 // Anything larger is treated as a corrupt length prefix
+
+	walOpAdd    byte = 1 // Followed by walTxVersion and the transaction in that version's encoding
+	walOpRemove byte = 2
+
+	walTxVersion byte = 1 // appendTxBinary
+)
+
+var (
+	ErrWALClosed        = errors.New("write-ahead log is closed")
+	ErrWALCorruptRecord = errors.New("write-ahead log record is corrupt")
+	ErrWALIncompatible  = errors.New("write-ahead log record was written by an incompatible version")
+)
+
+// WALSyncPolicy controls when appended records are fsynced to stable storage.
+type WALSyncPolicy uint8
+
+const (
+	WALSyncAlways   WALSyncPolicy = iota // fsync every record before the pool operation returns (safest, slowest)
+	WALSyncInterval                      // fsync from a background ticker every SyncInterval
+	WALSyncNever                         // leave flushing to the operating system
+)
+
+// ParseWALSyncPolicy converts a configuration value ("always", "interval", "never") into a WALSyncPolicy.
+func ParseWALSyncPolicy(s string) (WALSyncPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "always":
+		return WALSyncAlways, nil
+	case "interval":
+		return WALSyncInterval, nil
+	case "never":
+		return WALSyncNever, nil
+	}
+	return 0, errors.Errorf("unknown WAL sync policy %q", s)
+}
+
+type WALOptions struct {
+	Sync         WALSyncPolicy
+	SyncInterval time.Duration // Used with WALSyncInterval, defaults to one second
+	CompactEvery uint32        // Compact into a snapshot after this many appended records, 0 disables compaction
+}
+
+// WAL is an append-only log of mempool admissions and removals. Opening a WAL replays the
+// latest snapshot followed by the log so the mempool can be rebuilt after a restart.
+type WAL struct {
+	mu         sync.Mutex
+	syncMu     sync.Mutex // Serialises syncAppended so a caller returns only once its records are synced
+	dir        string
+	file       *os.File
+	opts       WALOptions
+	records    uint32 // Records appended since the last compaction
+	pending    bool   // Records appended by logAdd or logRemove under WALSyncAlways and not yet synced
+	recovered  []*Tx
+	closed     bool
+	compacting chan struct{} // Closed when the background compaction finishes, nil when none runs
+	suspended  bool          // A background compaction failed; compaction resumes with Compact or a reopen
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// OpenWAL opens (or creates) the write-ahead log in dir and replays any existing state.
+// A torn or corrupt record at the tail of the log is truncated away; everything before it is kept.
+// A record with a valid checksum that this version cannot read fails with ErrWALIncompatible and
+// leaves the log untouched.
+func OpenWAL(dir string, opts WALOptions) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create WAL directory %s", dir)
+	}
+	w := &WAL{dir: dir, opts: opts}
+
+	state := newWALState()
+	if err := replayWALFile(filepath.Join(dir, walSnapshotFileName), state, false); err != nil {
+		return nil, err
+	}
+	// A compaction interrupted by a crash leaves the log it was folding in place; it precedes the log.
+	if err := replayWALFile(filepath.Join(dir, walCompactingFileName), state, false); err != nil {
+		return nil, err
+	}
+	logPath := filepath.Join(dir, walLogFileName)
+	if err := replayWALFile(logPath, state, true); err != nil {
+		return nil, err
+	}
+	w.recovered = state.txs()
+
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open WAL file %s", logPath)
+	}
+	w.file = file
+
+	if opts.Sync == WALSyncInterval {
+		if w.opts.SyncInterval <= 0 {
+			w.opts.SyncInterval = time.Second
+		}
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		go w.syncLoop()
+	}
+	return w, nil
+}
+
+// Recovered returns the transactions rebuilt from the snapshot and log when the WAL was opened, in arrival order.
+func (w *WAL) Recovered() []*Tx {
+	return w.recovered
+}
+
+// AppendAdd records the admission of tx into the pool.
+func (w *WAL) AppendAdd(tx *Tx) error {
+	return w.append(encodeWALAdd(tx), true)
+}
+
+// AppendRemove records the removal of the transaction with txHash from the pool.
+func (w *WAL) AppendRemove(txHash string) error {
+	return w.append(encodeWALRemove(txHash), true)
+}
+
+// logAdd is AppendAdd for pools, which append while holding their lock: the fsync WALSyncAlways
+// asks for is left to syncAppended, called once the lock is released.
+func (w *WAL) logAdd(tx *Tx) error {
+	return w.append(encodeWALAdd(tx), false)
+}
+
+// logRemove is AppendRemove for pools; see logAdd.
+func (w *WAL) logRemove(txHash string) error {
+	return w.append(encodeWALRemove(txHash), false)
+}
+
+// syncAppended fsyncs the records appended by logAdd and logRemove when the policy is
+// WALSyncAlways. Concurrent callers share an fsync, and each returns once its own records are synced.
+func (w *WAL) syncAppended() error {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+	w.mu.Lock()
+	if !w.pending || w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.pending = false
+	file := w.file
+	w.mu.Unlock()
+	// A compaction that switched files meanwhile synced and closed this one.
+	if err := file.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return errors.Wrap(err, "failed to sync WAL records")
+	}
+	return nil
+}
+
+// ShouldCompact reports whether enough records have been appended to warrant a compaction, and
+// none is running or has failed.
+func (w *WAL) ShouldCompact() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.opts.CompactEvery > 0 && w.records >= w.opts.CompactEvery && w.compacting == nil && !w.suspended
+}
+
+// SetCompactEvery changes how many appended records trigger a compaction, e.g. when it follows a
+// pool capacity that changed at runtime. 0 disables compaction.
+func (w *WAL) SetCompactEvery(n uint32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.opts.CompactEvery = n
+}
+
+// Compact atomically replaces the snapshot with txs and truncates the log, waiting for a background
+// compaction to finish first. The caller must ensure no records are appended concurrently that are
+// not reflected in txs.
+func (w *WAL) Compact(txs []*Tx) error {
+	w.waitCompaction()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWALClosed
+	}
+	if err := w.writeSnapshot(txs); err != nil {
+		return err
+	}
+
+	// Replaying the old logs on top of the new snapshot is idempotent, so a crash before this point is harmless.
+	if err := os.Remove(filepath.Join(w.dir, walCompactingFileName)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove compacted WAL")
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return errors.Wrap(err, "failed to truncate WAL after compaction")
+	}
+	if err := w.file.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync WAL after compaction")
+	}
+	w.records = 0
+	w.pending = false
+	w.suspended = false
+	return nil
+}
+
+// startCompaction moves the log aside, starts a new one and folds the old one into a snapshot of txs
+// in the background, then calls done with the outcome. Only the file switch happens before it
+// returns, so the caller may hold the lock that keeps txs in step with the appended records, as it
+// must. It does nothing while a compaction runs or after one failed.
+func (w *WAL) startCompaction(txs []*Tx, done func(error)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWALClosed
+	}
+	if w.compacting != nil || w.suspended {
+		return nil
+	}
+	// Always sync: a concurrent syncAppended may have claimed the pending records and will find the file closed.
+	if err := w.file.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync WAL before compaction")
+	}
+	w.pending = false
+	logPath := filepath.Join(w.dir, walLogFileName)
+	compactingPath := filepath.Join(w.dir, walCompactingFileName)
+	if err := os.Rename(logPath, compactingPath); err != nil {
+		return errors.Wrap(err, "failed to move WAL aside for compaction")
+	}
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		os.Rename(compactingPath, logPath) // Keep appending to the old log
+		return errors.Wrapf(err, "failed to open WAL file %s", logPath)
+	}
+	w.file.Close()
+	w.file = file
+	w.records = 0
+
+	finished := make(chan struct{})
+	w.compacting = finished
+	go func() {
+		err := w.writeSnapshot(txs)
+		if err == nil {
+			if err = os.Remove(compactingPath); err != nil {
+				err = errors.Wrap(err, "failed to remove compacted WAL")
+			}
+			syncDir(w.dir)
+		}
+		w.mu.Lock()
+		w.compacting = nil
+		w.suspended = err != nil
+		w.mu.Unlock()
+		close(finished)
+		done(err)
+	}()
+	return nil
+}
+
+// waitCompaction waits for a background compaction, if one runs, to finish.
+func (w *WAL) waitCompaction() {
+	w.mu.Lock()
+	finished := w.compacting
+	w.mu.Unlock()
+	if finished != nil {
+		<-finished
+	}
+}
+
+// writeSnapshot atomically replaces the snapshot with txs.
+func (w *WAL) writeSnapshot(txs []*Tx) error {
+	snapshotPath := filepath.Join(w.dir, walSnapshotFileName)
+	tmp, err := os.CreateTemp(w.dir, walSnapshotFileName+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create WAL snapshot temp file")
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	var buf []byte
+	for _, tx := range txs {
+		buf = appendWALRecord(buf, encodeWALAdd(tx))
+	}
+	if _, err = tmp.Write(buf); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write WAL snapshot")
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync WAL snapshot")
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close WAL snapshot")
+	}
+	if err = os.Rename(tmp.Name(), snapshotPath); err != nil {
+		return errors.Wrapf(err, "failed to move WAL snapshot into place at %s", snapshotPath)
+	}
+	syncDir(w.dir)
+	return nil
+}
+
+// Sync flushes appended records to stable storage.
+func (w *WAL) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWALClosed
+	}
+	return w.file.Sync()
+}
+
+// Close waits for a background compaction, stops the background sync loop, flushes the log and
+// closes it.
+func (w *WAL) Close() error {
+	w.waitCompaction()
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+	}
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return errors.Wrap(err, "failed to sync WAL on close")
+	}
+	return w.file.Close()
+}
+
+// append writes a record, syncing it under WALSyncAlways when sync is set and otherwise marking it
+// pending for syncAppended.
+func (w *WAL) append(payload []byte, sync bool) error {
+	record := appendWALRecord(make([]byte, 0, walHeaderSize+len(payload)), payload)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWALClosed
+	}
+	if _, err := w.file.Write(record); err != nil {
+		return errors.Wrap(err, "failed to append WAL record")
+	}
+	w.records++
+	if w.opts.Sync == WALSyncAlways && !sync {
+		w.pending = true
+	} else if w.opts.Sync == WALSyncAlways {
+		if err := w.file.Sync(); err != nil {
+			return errors.Wrap(err, "failed to sync WAL record")
+		}
+	}
+	return nil
+}
+
+func (w *WAL) syncLoop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if !w.closed {
+				w.file.Sync()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// walState applies replayed records while preserving the original arrival order.
+type walState struct {
+	order []string
+	byKey map[string]*Tx
+}
+
+func newWALState() *walState {
+	return &walState{byKey: make(map[string]*Tx)}
+}
+
+func (s *walState) add(tx *Tx) {
+	if _, exists := s.byKey[tx.TxHash]; !exists {
+		s.order = append(s.order, tx.TxHash)
+	}
+	s.byKey[tx.TxHash] = tx
+}
+
+func (s *walState) remove(txHash string) {
+	delete(s.byKey, txHash)
+}
+
+func (s *walState) txs() []*Tx {
+	txs := make([]*Tx, 0, len(s.byKey))
+	for _, txHash := range s.order {
+		if tx, ok := s.byKey[txHash]; ok {
+			txs = append(txs, tx)
+			delete(s.byKey, txHash) // Guards against a hash that was removed and re-added appearing twice in order
+		}
+	}
+	return txs
+}
+
+// replayWALFile applies every valid record in path to state. When truncateTail is set, a torn or
+// corrupt tail is cut off so that new records are appended after the last good one.
+func replayWALFile(path string, state *walState, truncateTail bool) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to read WAL file %s", path)
+	}
+
+	offset := 0
+	for offset < len(data) {
+		payload, n, err := readWALRecord(data[offset:])
+		if err != nil {
+			break
+		}
+		if err = applyWALRecord(payload, state); errors.Is(err, ErrWALIncompatible) {
+			// The record is intact, so truncating would discard transactions a newer version can read.
+			return errors.Wrapf(err, "cannot replay %s at offset %d; run a version that can read it, or move the directory aside to start empty", path, offset)
+		} else if err != nil {
+			break
+		}
+		offset += n
+	}
+
+	if offset < len(data) && truncateTail {
+		if err = os.Truncate(path, int64(offset)); err != nil {
+			return errors.Wrapf(err, "failed to truncate corrupt WAL tail in %s", path)
+		}
+	}
+	return nil
+}
+
+func readWALRecord(data []byte) (payload []byte, n int, err error) {
+	if len(data) < walHeaderSize {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	length := binary.LittleEndian.Uint32(data[0:4])
+	checksum := binary.LittleEndian.Uint32(data[4:8])
+	if length == 0 || length > walMaxRecordSize {
+		return nil, 0, ErrWALCorruptRecord
+	}
+	end := walHeaderSize + int(length)
+	if len(data) < end {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	payload = data[walHeaderSize:end]
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, 0, ErrWALCorruptRecord
+	}
+	return payload, end, nil
+}
+
+func applyWALRecord(payload []byte, state *walState) error {
+	switch payload[0] {
+	case walOpAdd:
+		if len(payload) < 2 {
+			return ErrWALCorruptRecord
+		}
+		if payload[1] != walTxVersion {
+			return errors.Wrapf(ErrWALIncompatible, "add record version %d", payload[1])
+		}
+		tx, n, err := decodeTxBinary(payload[2:])
+		if err != nil {
+			return err
+		}
+		if n != len(payload)-2 {
+			return ErrWALCorruptRecord
+		}
+		state.add(tx)
+	case walOpRemove:
+		txHash, _, err := readBinaryString(payload[1:])
+		if err != nil {
+			return err
+		}
+		state.remove(txHash)
+	default:
+		return errors.Wrapf(ErrWALIncompatible, "op code %d", payload[0])
+	}
+	return nil
+}
+
+func appendWALRecord(buf, payload []byte) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(payload))
+	return append(buf, payload...)
+}
+
+func encodeWALRemove(txHash string) []byte {
+	payload := make([]byte, 0, 1+binary.MaxVarintLen64+len(txHash))
+	payload = append(payload, walOpRemove)
+	return appendBinaryString(payload, txHash)
+}
+
+func encodeWALAdd(tx *Tx) []byte {
+	payload := make([]byte, 0, 2+2*binary.MaxVarintLen64+len(tx.TxHash)+len(tx.Signature)+32)
+	payload = append(payload, walOpAdd, walTxVersion)
+	return appendTxBinary(payload, tx)
+}
+
+// syncDir fsyncs a directory so a rename inside it is durable. Errors are ignored because not
+// every platform supports syncing directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
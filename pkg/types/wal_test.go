@@ -0,0 +1,353 @@
+package types_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mempool/pkg/logging"
+	"mempool/pkg/types"
+)
+
+func TestParseWALSyncPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		value   string
+		want    types.WALSyncPolicy
+		isError bool
+	}{
+		{name: "default", value: "", want: types.WALSyncAlways},
+		{name: "always", value: "always", want: types.WALSyncAlways},
+		{name: "interval", value: "Interval", want: types.WALSyncInterval},
+		{name: "never", value: "never", want: types.WALSyncNever},
+		{name: "failure_unknown", value: "sometimes", isError: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := types.ParseWALSyncPolicy(tc.value)
+			if tc.isError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestWAL_Replay(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := types.OpenWAL(dir, types.WALOptions{Sync: types.WALSyncNever})
+	require.NoError(t, err)
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "a", Signature: "sigA", Gas: 1, FeePerGas: 1}))
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "b", Signature: "sigB", Gas: 2, FeePerGas: 2}))
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "c", Signature: "sigC", Gas: 3, FeePerGas: 3}))
+	require.NoError(t, wal.AppendRemove("b"))
+	require.NoError(t, wal.Close())
+
+	reopened, err := types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	defer reopened.Close()
+	recovered := reopened.Recovered()
+	require.Len(t, recovered, 2)
+	assert.Equal(t, "a", recovered[0].TxHash)
+	assert.Equal(t, "c", recovered[1].TxHash)
+	assert.Equal(t, "sigC", recovered[1].Signature)
+	assert.Equal(t, 3.0, recovered[1].Gas)
+	assert.Equal(t, 3.0, recovered[1].FeePerGas)
+}
+
+func TestWAL_TruncatedTail(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		truncate func(size int64) int64
+	}{
+		{name: "mid_header", truncate: func(size int64) int64 { return size - 30 }},
+		{name: "mid_payload", truncate: func(size int64) int64 { return size - 5 }},
+		{name: "last_byte", truncate: func(size int64) int64 { return size - 1 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wal, err := types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "kept", Signature: "sig", Gas: 1, FeePerGas: 1}))
+			require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "torn", Signature: "sig", Gas: 1, FeePerGas: 1}))
+			require.NoError(t, wal.Close())
+
+			logPath := filepath.Join(dir, "mempool.wal")
+			info, err := os.Stat(logPath)
+			require.NoError(t, err)
+			require.NoError(t, os.Truncate(logPath, tc.truncate(info.Size())))
+
+			reopened, err := types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			recovered := reopened.Recovered()
+			require.Len(t, recovered, 1)
+			assert.Equal(t, "kept", recovered[0].TxHash)
+
+			// Records appended after recovery must follow the last good record, not the torn bytes.
+			require.NoError(t, reopened.AppendAdd(&types.Tx{TxHash: "after", Signature: "sig", Gas: 1, FeePerGas: 1}))
+			require.NoError(t, reopened.Close())
+			again, err := types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			defer again.Close()
+			require.Len(t, again.Recovered(), 2)
+			assert.Equal(t, "after", again.Recovered()[1].TxHash)
+		})
+	}
+}
+
+func TestWAL_CorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "kept", Signature: "sig", Gas: 1, FeePerGas: 1}))
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "flipped", Signature: "sig", Gas: 1, FeePerGas: 1}))
+	require.NoError(t, wal.Close())
+
+	logPath := filepath.Join(dir, "mempool.wal")
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xFF // Break the checksum of the final record
+	require.NoError(t, os.WriteFile(logPath, data, 0o644))
+
+	reopened, err := types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.Len(t, reopened.Recovered(), 1)
+	assert.Equal(t, "kept", reopened.Recovered()[0].TxHash)
+}
+
+// TestWAL_IncompatibleRecord checks that an intact record this version cannot read fails the open
+// without truncating the log.
+func TestWAL_IncompatibleRecord(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+	}{
+		{name: "unknown_add_version", payload: []byte{1, 99, 0, 0}},
+		{name: "unknown_op", payload: []byte{42}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wal, err := types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "kept", Signature: "sig", Gas: 1, FeePerGas: 1}))
+			require.NoError(t, wal.Close())
+
+			logPath := filepath.Join(dir, "mempool.wal")
+			data, err := os.ReadFile(logPath)
+			require.NoError(t, err)
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(tc.payload)))
+			data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(tc.payload))
+			data = append(data, tc.payload...)
+			require.NoError(t, os.WriteFile(logPath, data, 0o644))
+
+			_, err = types.OpenWAL(dir, types.WALOptions{})
+			assert.ErrorIs(t, err, types.ErrWALIncompatible)
+			kept, err := os.ReadFile(logPath)
+			require.NoError(t, err)
+			assert.Equal(t, data, kept, "an incompatible log is not truncated")
+		})
+	}
+}
+
+func TestWAL_Compact(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := types.OpenWAL(dir, types.WALOptions{CompactEvery: 2})
+	require.NoError(t, err)
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "a", Signature: "sig", Gas: 1, FeePerGas: 1}))
+	assert.False(t, wal.ShouldCompact())
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "b", Signature: "sig", Gas: 1, FeePerGas: 1}))
+	assert.True(t, wal.ShouldCompact())
+
+	require.NoError(t, wal.Compact([]*types.Tx{{TxHash: "b", Signature: "sig", Gas: 1, FeePerGas: 1}}))
+	assert.False(t, wal.ShouldCompact())
+	info, err := os.Stat(filepath.Join(dir, "mempool.wal"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "log should be empty after compaction")
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "c", Signature: "sig", Gas: 1, FeePerGas: 1}))
+	require.NoError(t, wal.Close())
+
+	reopened, err := types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.Len(t, reopened.Recovered(), 2)
+	assert.Equal(t, "b", reopened.Recovered()[0].TxHash)
+	assert.Equal(t, "c", reopened.Recovered()[1].TxHash)
+}
+
+// TestWAL_InterruptedCompaction checks that a log left aside by a compaction that never finished is
+// replayed between the snapshot and the current log.
+func TestWAL_InterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	require.NoError(t, wal.Compact([]*types.Tx{{TxHash: "a", Signature: "sig", Gas: 1, FeePerGas: 1}}))
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "b", Signature: "sig", Gas: 1, FeePerGas: 1}))
+	require.NoError(t, wal.AppendRemove("a"))
+	require.NoError(t, wal.Close())
+	require.NoError(t, os.Rename(filepath.Join(dir, "mempool.wal"), filepath.Join(dir, "mempool.wal.compacting")))
+
+	wal, err = types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	require.NoError(t, wal.AppendAdd(&types.Tx{TxHash: "c", Signature: "sig", Gas: 1, FeePerGas: 1}))
+	require.NoError(t, wal.AppendRemove("b"))
+	require.NoError(t, wal.Close())
+
+	reopened, err := types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.Len(t, reopened.Recovered(), 1)
+	assert.Equal(t, "c", reopened.Recovered()[0].TxHash)
+}
+
+// TestMempool_RestoreFromWAL_Compaction checks that admissions, evictions and removals racing with
+// background compactions all survive a restart.
+func TestMempool_RestoreFromWAL_Compaction(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	for _, tc := range []struct {
+		name string
+		new  func(wal *types.WAL) (types.Mempool, error)
+	}{
+		{name: "single", new: func(wal *types.WAL) (types.Mempool, error) { return types.NewMempool(50, logger, types.WithWAL(wal)) }},
+		{name: "sharded", new: func(wal *types.WAL) (types.Mempool, error) {
+			return types.NewShardedMempool(50, 4, logger, types.WithWAL(wal))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wal, err := types.OpenWAL(dir, types.WALOptions{CompactEvery: 8})
+			require.NoError(t, err)
+			memPool, err := tc.new(wal)
+			require.NoError(t, err)
+			wg := &sync.WaitGroup{}
+			memPool.StartProcessors(wg, 4)
+			var hashes []string
+			for i := range 200 {
+				hash := fmt.Sprintf("tx-%d", i)
+				hashes = append(hashes, hash)
+				require.NoError(t, memPool.AddTx(types.NewTx(logger, hash, "sig", 10, float64(i%70+1)), wg))
+				if i%40 == 39 {
+					batch := []*types.Tx{types.NewTx(logger, hash+"-batch", "sig", 10, float64(i))}
+					hashes = append(hashes, hash+"-batch")
+					memPool.AddTxs(batch)
+					memPool.Update([]*types.Tx{{TxHash: fmt.Sprintf("tx-%d", i-1)}})
+				}
+			}
+			wg.Wait()
+			memPool.CloseTxInsertChan()
+			require.NoError(t, wal.Close())
+			assert.FileExists(t, filepath.Join(dir, "mempool.snapshot"), "the pool should have compacted")
+			assert.NoFileExists(t, filepath.Join(dir, "mempool.wal.compacting"), "Close should wait for the compaction")
+
+			wal, err = types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			defer wal.Close()
+			restored, err := tc.new(wal)
+			require.NoError(t, err)
+			require.Equal(t, memPool.MempoolLen(), restored.MempoolLen())
+			for _, hash := range hashes {
+				_, pooled := memPool.GetTx(hash)
+				_, ok := restored.GetTx(hash)
+				assert.Equal(t, pooled, ok, "transaction %s", hash)
+			}
+		})
+	}
+}
+
+func TestMempool_RestoreFromWAL(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	dir := t.TempDir()
+
+	wal, err := types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	memPool, err := types.NewMempool(2, logger, types.WithWAL(wal))
+	require.NoError(t, err)
+	wg := &sync.WaitGroup{}
+	memPool.StartProcessors(wg, 1)
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "low", "sig", 10, 1), wg))
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "mid", "sig", 10, 2), wg))
+	require.NoError(t, memPool.AddTx(types.NewTx(logger, "high", "sig", 10, 3), wg)) // Evicts "low"
+	wg.Wait()
+	memPool.CloseTxInsertChan()
+	require.NoError(t, wal.Close())
+
+	// Simulate a restart with a smaller pool: restore must re-validate and respect capacity.
+	wal, err = types.OpenWAL(dir, types.WALOptions{})
+	require.NoError(t, err)
+	defer wal.Close()
+	restored, err := types.NewMempool(1, logger, types.WithWAL(wal))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), restored.MempoolLen())
+	tx, ok := restored.GetTx("high")
+	require.True(t, ok, "highest fee transaction should survive the restart")
+	assert.Equal(t, 30.0, tx.TotalFee)
+	_, ok = restored.GetTx("low")
+	assert.False(t, ok, "evicted transaction should not be restored")
+}
+
+// TestMempool_RestoreFromWAL_InvalidTx checks that admission applies the same validation as restore,
+// so a transaction restore would discard is never admitted and nothing is lost across a restart.
+func TestMempool_RestoreFromWAL_InvalidTx(t *testing.T) {
+	logger, err := logging.Logger()
+	require.NoError(t, err, "Failed to initialize logger for test")
+	invalid := func() []*types.Tx {
+		return []*types.Tx{
+			types.NewTx(logger, "no-signature", "", 10, 1),
+			types.NewTx(logger, "no-gas", "sig", 0, 1),
+			types.NewTx(logger, "negative-fee", "sig", 10, -1),
+			types.NewTx(logger, "", "sig", 10, 1),
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		new  func(wal *types.WAL) (types.Mempool, error)
+	}{
+		{name: "single", new: func(wal *types.WAL) (types.Mempool, error) { return types.NewMempool(10, logger, types.WithWAL(wal)) }},
+		{name: "sharded", new: func(wal *types.WAL) (types.Mempool, error) {
+			return types.NewShardedMempool(10, 4, logger, types.WithWAL(wal))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wal, err := types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			memPool, err := tc.new(wal)
+			require.NoError(t, err)
+			wg := &sync.WaitGroup{}
+			memPool.StartProcessors(wg, 1)
+			for _, tx := range invalid() {
+				assert.ErrorIs(t, memPool.AddTx(tx, wg), types.ErrInvalidTx, "AddTx %q", tx.TxHash)
+			}
+			for _, err := range memPool.AddTxs(invalid()) {
+				assert.ErrorIs(t, err, types.ErrInvalidTx)
+			}
+			for _, err := range memPool.Reinject(invalid()) {
+				assert.ErrorIs(t, err, types.ErrInvalidTx)
+			}
+			require.NoError(t, memPool.AddTx(types.NewTx(logger, "valid", "sig", 10, 1), wg))
+			wg.Wait()
+			memPool.CloseTxInsertChan()
+			require.Equal(t, uint32(1), memPool.MempoolLen())
+			require.NoError(t, wal.Close())
+
+			wal, err = types.OpenWAL(dir, types.WALOptions{})
+			require.NoError(t, err)
+			defer wal.Close()
+			restored, err := tc.new(wal)
+			require.NoError(t, err)
+			assert.Equal(t, memPool.MempoolLen(), restored.MempoolLen(), "the pool is the same after a restart")
+			_, ok := restored.GetTx("valid")
+			assert.True(t, ok)
+		})
+	}
+}